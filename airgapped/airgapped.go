@@ -1,7 +1,10 @@
 package airgapped
 
 import (
+	"crypto/ed25519"
+	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"sync"
@@ -17,6 +20,8 @@ import (
 	"github.com/lidofinance/dc4bc/fsm/state_machines/signature_proposal_fsm"
 	"github.com/lidofinance/dc4bc/fsm/state_machines/signing_proposal_fsm"
 	"github.com/lidofinance/dc4bc/fsm/types/requests"
+	"github.com/lidofinance/dc4bc/fsm/types/responses"
+	"github.com/lidofinance/dc4bc/transport"
 	"github.com/syndtr/goleveldb/leveldb"
 )
 
@@ -30,13 +35,22 @@ type Machine struct {
 	dkgInstances map[string]*dkg.DKG
 
 	encryptionKey []byte
+	deriveKey     keyDeriver
+	kdf           KDF
 	pubKey        kyber.Point
 	secKey        kyber.Scalar
+	identityKey   ed25519.PrivateKey
 	baseSuite     vss.Suite
 	baseSeed      []byte
 
 	db             *leveldb.DB
 	resultQRFolder string
+	audit          *AuditLog
+
+	// replaying is set while operations of the operations log are replayed. They were verified when they were
+	// accepted into the log, so their signatures and source messages are not checked again: operations logged
+	// before the checks were introduced have neither, and the hot node key may be rotated since then.
+	replaying bool
 }
 
 func NewMachine(dbPath string) (*Machine, error) {
@@ -46,6 +60,7 @@ func NewMachine(dbPath string) (*Machine, error) {
 
 	am := &Machine{
 		dkgInstances: make(map[string]*dkg.DKG),
+		kdf:          DefaultScryptKDF(),
 	}
 
 	if am.db, err = leveldb.OpenFile(dbPath, nil); err != nil {
@@ -74,6 +89,16 @@ func (am *Machine) SetResultQRFolder(resultQRFolder string) {
 	am.resultQRFolder = resultQRFolder
 }
 
+// SetKDF sets a KDF used to encrypt new data, data encrypted with other KDFs is still decrypted
+func (am *Machine) SetKDF(kdf KDF) {
+	am.kdf = kdf
+}
+
+// SetAuditLog sets the log of handled operations, operations are not audited if it is not set
+func (am *Machine) SetAuditLog(audit *AuditLog) {
+	am.audit = audit
+}
+
 // InitKeys load keys public and private keys for DKG from LevelDB. If keys does not exist, creates them.
 func (am *Machine) InitKeys() error {
 	err := am.LoadKeysFromDB()
@@ -82,17 +107,55 @@ func (am *Machine) InitKeys() error {
 	}
 	// if keys were not generated yet
 	if err == leveldb.ErrNotFound {
-		am.secKey = am.baseSuite.Scalar().Pick(am.baseSuite.RandomStream())
-		am.pubKey = am.baseSuite.Point().Mul(am.secKey, nil)
-		return am.SaveKeysToDB()
+		am.generateKeys()
+		if err = am.SaveKeysToDB(); err != nil {
+			return err
+		}
+	} else if am.identityKey == nil {
+		// if the machine was initialized before the identity key was introduced
+		am.identityKey = am.deriveIdentityKey()
+		if err = am.saveIdentityKey(); err != nil {
+			return err
+		}
+	}
+
+	// the operations log is created before the password is entered and is plaintext on machines
+	// initialized before it was encrypted
+	if err = am.encryptOperationsLogIfNeeded(); err != nil {
+		return fmt.Errorf("failed to encrypt operations log: %w", err)
 	}
 
+	am.restoreDKGInstances()
 	return nil
 }
 
+// generateKeys derives the DKG keypair and the identity key from the base seed.
+// The DKG keypair is the first thing picked from a freshly seeded suite,
+// so the same seed always gives the same keys.
+func (am *Machine) generateKeys() {
+	am.secKey = am.baseSuite.Scalar().Pick(am.baseSuite.RandomStream())
+	am.pubKey = am.baseSuite.Point().Mul(am.secKey, nil)
+	am.identityKey = am.deriveIdentityKey()
+}
+
+func (am *Machine) deriveIdentityKey() ed25519.PrivateKey {
+	identitySeed := sha256.Sum256(append([]byte(identityKeyDBKey), am.baseSeed...))
+	return ed25519.NewKeyFromSeed(identitySeed[:])
+}
+
+// GetIdentityPubKey returns the public key the machine signs results of operations with.
+// The key should be pinned on the hot node.
+func (am *Machine) GetIdentityPubKey() ed25519.PublicKey {
+	if am.identityKey == nil {
+		return nil
+	}
+	return am.identityKey.Public().(ed25519.PublicKey)
+}
+
 // SetEncryptionKey set a key to encrypt and decrypt a sensitive data
 func (am *Machine) SetEncryptionKey(key []byte) {
 	am.encryptionKey = key
+	am.deriveKey = cachedKeyDeriver(key)
 }
 
 // SensitiveDataRemoved indicates whether sensitive information has been cleared
@@ -108,7 +171,9 @@ func (am *Machine) DropSensitiveData() {
 	// There is no guarantee that GC actually deleted a data from memory, but that's ok at this moment
 	am.secKey = nil
 	am.pubKey = nil
+	am.identityKey = nil
 	am.encryptionKey = nil
+	am.deriveKey = nil
 }
 
 func (am *Machine) ReplayOperationsLog(dkgIdentifier string) error {
@@ -117,6 +182,12 @@ func (am *Machine) ReplayOperationsLog(dkgIdentifier string) error {
 		return fmt.Errorf("failed to getOperationsLog: %w", err)
 	}
 
+	// the round is rebuilt from scratch, so the restored instance is dropped
+	delete(am.dkgInstances, dkgIdentifier)
+
+	am.replaying = true
+	defer func() { am.replaying = false }()
+
 	for _, operation := range operationsLog {
 		if _, err := am.HandleOperation(operation); err != nil {
 			return fmt.Errorf(
@@ -171,16 +242,38 @@ func (am *Machine) decryptDataFromParticipant(data []byte) ([]byte, error) {
 	return decryptedData, nil
 }
 
+// operationResult is the operation with a signed result and the error of the handler written to the result,
+// the handler error does not fail the operation: it is passed to the FSM of the other participants
+type operationResult struct {
+	operation  client.Operation
+	handlerErr error
+}
+
 // HandleOperation handles and processes an operation
 func (am *Machine) HandleOperation(operation client.Operation) (client.Operation, error) {
+	result, err := am.verifyAndHandleOperation(operation)
+	if auditErr := am.audit.recordOperation(operation, result.handlerErr, err); auditErr != nil {
+		log.Printf("failed to write operation %s to the audit log: %v", operation.ID, auditErr)
+	}
+	return result.operation, err
+}
+
+func (am *Machine) verifyAndHandleOperation(operation client.Operation) (operationResult, error) {
+	if !am.replaying {
+		if err := am.verifyOperation(operation); err != nil {
+			return operationResult{}, fmt.Errorf("failed to verify operation %s: %w", operation.ID, err)
+		}
+	}
+
 	if err := am.storeOperation(operation); err != nil {
-		return client.Operation{}, fmt.Errorf("failed to storeOperation: %w", err)
+		return operationResult{}, fmt.Errorf("failed to storeOperation: %w", err)
 	}
 
 	return am.handleOperation(operation)
 }
 
-func (am *Machine) handleOperation(operation client.Operation) (client.Operation, error) {
+// handleOperation returns the operation with a signed result and the error of the handler written to the result
+func (am *Machine) handleOperation(operation client.Operation) (operationResult, error) {
 	var (
 		err error
 	)
@@ -208,16 +301,33 @@ func (am *Machine) handleOperation(operation client.Operation) (client.Operation
 		err = fmt.Errorf("invalid operation type: %s", operation.Type)
 	}
 
+	// the state of the DKG round is saved after every successful step, so it survives restarts
+	if err == nil && isDKGOperation(operation) {
+		if err = am.saveDKGState(operation); err != nil {
+			return operationResult{operation: operation}, fmt.Errorf("failed to save dkg state: %w", err)
+		}
+	}
+
 	// if we have error after handling the operation, we write the error to the operation, so we can feed it to a FSM
-	if err != nil {
+	result := operationResult{handlerErr: err}
+	if result.handlerErr != nil {
 		log.Println(fmt.Sprintf("failed to handle operation %s, returning response with error to client: %v",
-			operation.Type, err))
-		if e := am.writeErrorRequestToOperation(&operation, err); e != nil {
-			return operation, fmt.Errorf("failed to write error request to an operation: %w", e)
+			operation.Type, result.handlerErr))
+		if e := am.writeErrorRequestToOperation(&operation, result.handlerErr); e != nil {
+			result.operation = operation
+			return result, fmt.Errorf("failed to write error request to an operation: %w", e)
 		}
 	}
 
-	return operation, nil
+	result.operation = operation
+	if len(am.identityKey) == 0 {
+		return result, errors.New("identity key is not initialized")
+	}
+	if err = result.operation.SignResult(am.identityKey); err != nil {
+		return result, fmt.Errorf("failed to sign operation result: %w", err)
+	}
+
+	return result, nil
 }
 
 // HandleQR - gets an operation from a QR code, do necessary things for the operation and returns paths to QR-code images
@@ -247,31 +357,118 @@ func (am *Machine) HandleQR(data []byte) (string, error) {
 	return string(operationBz), nil
 }
 
+// ProcessBundles handles all pending operation bundles from the transport, sends results back
+// and acknowledges processed bundles. Bundles which can not be handled are rejected, so they do not block
+// the inbox. It returns the numbers of processed and rejected bundles.
+func (am *Machine) ProcessBundles(tr transport.Transport) (processed, rejected int, err error) {
+	bundles, err := tr.Receive()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to receive bundles: %w", err)
+	}
+
+	for _, bundle := range bundles {
+		var result string
+		if bundle.Kind != transport.OperationBundle {
+			err = fmt.Errorf("unexpected bundle kind %s", bundle.Kind)
+		} else {
+			result, err = am.HandleQR(bundle.Payload)
+		}
+		if err != nil {
+			log.Printf("Rejected bundle %s: %v\n", bundle.ID, err)
+			if err = tr.Reject(bundle, err); err != nil {
+				return processed, rejected, fmt.Errorf("failed to reject bundle %s: %w", bundle.ID, err)
+			}
+			rejected++
+			continue
+		}
+		if err = tr.Send(transport.NewBundle(transport.ResultBundle, bundle.ID, []byte(result))); err != nil {
+			return processed, rejected, fmt.Errorf("failed to send result for bundle %s: %w", bundle.ID, err)
+		}
+		if err = tr.Ack(bundle); err != nil {
+			return processed, rejected, fmt.Errorf("failed to ack bundle %s: %w", bundle.ID, err)
+		}
+		processed++
+	}
+	return processed, rejected, nil
+}
+
 // writeErrorRequestToOperation writes error to a operation if some bad things happened
 func (am *Machine) writeErrorRequestToOperation(o *client.Operation, handlerError error) error {
-	// each type of request should have a required event even error
-	// maybe should be global?
-	eventToErrorMap := map[fsm.State]fsm.Event{
-		dkg_proposal_fsm.StateDkgCommitsAwaitConfirmations:   dkg_proposal_fsm.EventDKGCommitConfirmationError,
-		dkg_proposal_fsm.StateDkgDealsAwaitConfirmations:     dkg_proposal_fsm.EventDKGDealConfirmationError,
-		dkg_proposal_fsm.StateDkgResponsesAwaitConfirmations: dkg_proposal_fsm.EventDKGResponseConfirmationError,
-		dkg_proposal_fsm.StateDkgMasterKeyAwaitConfirmations: dkg_proposal_fsm.EventDKGMasterKeyConfirmationError,
-	}
 	pid, err := am.getParticipantID(o.DKGIdentifier)
 	if err != nil {
 		return fmt.Errorf("failed to get participant id: %w", err)
 	}
-	req := requests.DKGProposalConfirmationErrorRequest{
-		Error:         handlerError,
-		ParticipantId: pid,
-		CreatedAt:     o.CreatedAt,
+	protocolError := newProtocolError(handlerError)
+
+	var req interface{}
+	switch state := fsm.State(o.Type); state {
+	case dkg_proposal_fsm.StateDkgCommitsAwaitConfirmations,
+		dkg_proposal_fsm.StateDkgDealsAwaitConfirmations,
+		dkg_proposal_fsm.StateDkgResponsesAwaitConfirmations,
+		dkg_proposal_fsm.StateDkgMasterKeyAwaitConfirmations:
+		req = requests.DKGProposalConfirmationErrorRequest{
+			Error:         protocolError,
+			ParticipantId: pid,
+			CreatedAt:     o.CreatedAt,
+		}
+	case signing_proposal_fsm.StateSigningAwaitConfirmations:
+		// there is no error event for this state, so we decline the signing and attach the error
+		var payload responses.SigningProposalParticipantInvitationsResponse
+		if err = json.Unmarshal(o.Payload, &payload); err != nil {
+			return fmt.Errorf("failed to unmarshal payload: %w", err)
+		}
+		req = requests.SigningProposalParticipantRequest{
+			SigningId:     payload.SigningId,
+			ParticipantId: pid,
+			Error:         protocolError,
+			CreatedAt:     o.CreatedAt,
+		}
+	case signing_proposal_fsm.StateSigningAwaitPartialSigns:
+		req = requests.SignatureProposalConfirmationErrorRequest{
+			Error:         protocolError,
+			ParticipantId: pid,
+			CreatedAt:     o.CreatedAt,
+		}
+	case signing_proposal_fsm.StateSigningPartialSignsCollected:
+		var payload responses.SigningProcessParticipantResponse
+		if err = json.Unmarshal(o.Payload, &payload); err != nil {
+			return fmt.Errorf("failed to unmarshal payload: %w", err)
+		}
+		req = client.SignatureReconstructionError{
+			SigningID:     payload.SigningId,
+			ParticipantId: pid,
+			Error:         protocolError,
+			CreatedAt:     o.CreatedAt,
+		}
+	default:
+		return fmt.Errorf("there is no error event for state %s: %w", state, handlerError)
 	}
-	errorEvent := eventToErrorMap[fsm.State(o.Type)]
+
 	reqBz, err := json.Marshal(req)
 	if err != nil {
 		return fmt.Errorf("failed to generate fsm request: %w", err)
 	}
-	o.Event = errorEvent
+	o.Event = stateToErrorEvent[fsm.State(o.Type)]
 	o.ResultMsgs = append(o.ResultMsgs, createMessage(*o, reqBz))
 	return nil
 }
+
+// stateToErrorEvent maps an operation type to the event used to report a failure to other participants
+var stateToErrorEvent = map[fsm.State]fsm.Event{
+	dkg_proposal_fsm.StateDkgCommitsAwaitConfirmations:     dkg_proposal_fsm.EventDKGCommitConfirmationError,
+	dkg_proposal_fsm.StateDkgDealsAwaitConfirmations:       dkg_proposal_fsm.EventDKGDealConfirmationError,
+	dkg_proposal_fsm.StateDkgResponsesAwaitConfirmations:   dkg_proposal_fsm.EventDKGResponseConfirmationError,
+	dkg_proposal_fsm.StateDkgMasterKeyAwaitConfirmations:   dkg_proposal_fsm.EventDKGMasterKeyConfirmationError,
+	signing_proposal_fsm.StateSigningAwaitConfirmations:    signing_proposal_fsm.EventDeclineSigningConfirmation,
+	signing_proposal_fsm.StateSigningAwaitPartialSigns:     signing_proposal_fsm.EventSigningPartialSignError,
+	signing_proposal_fsm.StateSigningPartialSignsCollected: client.SignatureReconstructionFailed,
+}
+
+// newProtocolError converts a handler error to the typed error sent to other participants
+func newProtocolError(handlerError error) *requests.ProtocolError {
+	var protocolError *requests.ProtocolError
+	if errors.As(handlerError, &protocolError) {
+		return requests.NewProtocolError(protocolError.Code, protocolError.Participant, handlerError.Error())
+	}
+	return requests.NewProtocolError(requests.ErrorCodeInternal, "", handlerError.Error())
+}
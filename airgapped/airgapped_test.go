@@ -1,7 +1,10 @@
 package airgapped
 
 import (
+	"bufio"
 	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
 	"encoding/json"
 	"fmt"
 	prysmBLS "github.com/prysmaticlabs/prysm/shared/bls"
@@ -11,9 +14,12 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/scrypt"
 
+	"github.com/corestario/kyber"
 	"github.com/google/uuid"
 	client "github.com/lidofinance/dc4bc/client/types"
+	"github.com/lidofinance/dc4bc/dkg"
 	"github.com/lidofinance/dc4bc/fsm/fsm"
 	"github.com/lidofinance/dc4bc/fsm/state_machines/dkg_proposal_fsm"
 	"github.com/lidofinance/dc4bc/fsm/state_machines/signature_proposal_fsm"
@@ -21,6 +27,7 @@ import (
 	"github.com/lidofinance/dc4bc/fsm/types/requests"
 	"github.com/lidofinance/dc4bc/fsm/types/responses"
 	"github.com/lidofinance/dc4bc/storage"
+	"github.com/lidofinance/dc4bc/transport"
 )
 
 const (
@@ -32,6 +39,8 @@ type Node struct {
 	ParticipantID           int
 	Participant             string
 	Machine                 *Machine
+	hotPrivKey              ed25519.PrivateKey
+	boardMessages           []storage.Message
 	commits                 []requests.DKGProposalCommitConfirmationRequest
 	deals                   []requests.DKGProposalDealConfirmationRequest
 	responses               []requests.DKGProposalResponseConfirmationRequest
@@ -41,6 +50,7 @@ type Node struct {
 }
 
 func (n *Node) storeOperation(t *testing.T, msg storage.Message) {
+	n.boardMessages = append(n.boardMessages, msg)
 	switch fsm.Event(msg.Event) {
 	case dkg_proposal_fsm.EventDKGCommitConfirmationReceived:
 		var req requests.DKGProposalCommitConfirmationRequest
@@ -83,6 +93,38 @@ func (n *Node) storeOperation(t *testing.T, msg storage.Message) {
 	}
 }
 
+// signOperation attaches received bulletin board messages to the operation and signs it with the node's hot key
+func (n *Node) signOperation(t *testing.T, o client.Operation) client.Operation {
+	o.SourceMessages = n.boardMessages
+	if err := o.Sign(n.hotPrivKey); err != nil {
+		t.Fatalf("failed to sign operation: %v", err)
+	}
+	return o
+}
+
+// signMessage signs the message with the node's hot key, as the hot node does before sending it to the bulletin board
+func (n *Node) signMessage(msg storage.Message) storage.Message {
+	msg.SenderAddr = n.Participant
+	msg.Signature = ed25519.Sign(n.hotPrivKey, msg.Bytes())
+	return msg
+}
+
+func newNode(t *testing.T, participantID int, participant string, machine *Machine) *Node {
+	hotPubKey, hotPrivKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate hot keys: %v", err)
+	}
+	if err = machine.SetHotPubKey(hotPubKey); err != nil {
+		t.Fatalf("failed to set hot pub key: %v", err)
+	}
+	return &Node{
+		ParticipantID: participantID,
+		Participant:   participant,
+		Machine:       machine,
+		hotPrivKey:    hotPrivKey,
+	}
+}
+
 type Transport struct {
 	nodes []*Node
 }
@@ -130,12 +172,7 @@ func TestAirgappedAllSteps(t *testing.T) {
 		if err = am.InitKeys(); err != nil {
 			t.Fatalf(err.Error())
 		}
-		node := Node{
-			ParticipantID: i,
-			Participant:   participants[i],
-			Machine:       am,
-		}
-		tr.nodes = append(tr.nodes, &node)
+		tr.nodes = append(tr.nodes, newNode(t, i, participants[i], am))
 	}
 	defer os.RemoveAll(testDir)
 
@@ -150,6 +187,7 @@ func TestAirgappedAllSteps(t *testing.T) {
 			Username:      n.Participant,
 			Threshold:     threshold,
 			DkgPubKey:     pubKey,
+			PubKey:        n.hotPrivKey.Public().(ed25519.PublicKey),
 		}
 		initReq = append(initReq, entry)
 	}
@@ -157,7 +195,7 @@ func TestAirgappedAllSteps(t *testing.T) {
 	runStep(tr, func(n *Node, wg *sync.WaitGroup) {
 		defer wg.Done()
 
-		_, err := n.Machine.HandleOperation(op)
+		_, err := n.Machine.HandleOperation(n.signOperation(t, op))
 		if err != nil {
 			t.Fatalf("%s: failed to handle operation %s: %v", n.Participant, op.Type, err)
 		}
@@ -181,12 +219,12 @@ func TestAirgappedAllSteps(t *testing.T) {
 	runStep(tr, func(n *Node, wg *sync.WaitGroup) {
 		defer wg.Done()
 
-		operation, err := n.Machine.HandleOperation(op)
+		operation, err := n.Machine.HandleOperation(n.signOperation(t, op))
 		if err != nil {
 			t.Fatalf("%s: failed to handle operation %s: %v", n.Participant, op.Type, err)
 		}
 		for _, msg := range operation.ResultMsgs {
-			tr.BroadcastMessage(t, msg)
+			tr.BroadcastMessage(t, n.signMessage(msg))
 		}
 	})
 
@@ -205,12 +243,12 @@ func TestAirgappedAllSteps(t *testing.T) {
 		}
 		op := createOperation(t, string(dkg_proposal_fsm.StateDkgDealsAwaitConfirmations), "", payload)
 
-		operation, err := n.Machine.HandleOperation(op)
+		operation, err := n.Machine.HandleOperation(n.signOperation(t, op))
 		if err != nil {
 			t.Fatalf("%s: failed to handle operation %s: %v", n.Participant, op.Type, err)
 		}
 		for _, msg := range operation.ResultMsgs {
-			tr.BroadcastMessage(t, msg)
+			tr.BroadcastMessage(t, n.signMessage(msg))
 		}
 	})
 
@@ -229,12 +267,12 @@ func TestAirgappedAllSteps(t *testing.T) {
 		}
 		op := createOperation(t, string(dkg_proposal_fsm.StateDkgResponsesAwaitConfirmations), "", payload)
 
-		operation, err := n.Machine.HandleOperation(op)
+		operation, err := n.Machine.HandleOperation(n.signOperation(t, op))
 		if err != nil {
 			t.Fatalf("%s: failed to handle operation %s: %v", n.Participant, op.Type, err)
 		}
 		for _, msg := range operation.ResultMsgs {
-			tr.BroadcastMessage(t, msg)
+			tr.BroadcastMessage(t, n.signMessage(msg))
 		}
 	})
 
@@ -253,12 +291,12 @@ func TestAirgappedAllSteps(t *testing.T) {
 		}
 		op := createOperation(t, string(dkg_proposal_fsm.StateDkgMasterKeyAwaitConfirmations), "", payload)
 
-		operation, err := n.Machine.HandleOperation(op)
+		operation, err := n.Machine.HandleOperation(n.signOperation(t, op))
 		if err != nil {
 			t.Fatalf("%s: failed to handle operation %s: %v", n.Participant, op.Type, err)
 		}
 		for _, msg := range operation.ResultMsgs {
-			tr.BroadcastMessage(t, msg)
+			tr.BroadcastMessage(t, n.signMessage(msg))
 		}
 	})
 
@@ -283,12 +321,12 @@ func TestAirgappedAllSteps(t *testing.T) {
 
 		op := createOperation(t, string(signing_proposal_fsm.StateSigningAwaitPartialSigns), "", payload)
 
-		operation, err := n.Machine.HandleOperation(op)
+		operation, err := n.Machine.HandleOperation(n.signOperation(t, op))
 		if err != nil {
 			t.Fatalf("%s: failed to handle operation %s: %v", n.Participant, op.Type, err)
 		}
 		for _, msg := range operation.ResultMsgs {
-			tr.BroadcastMessage(t, msg)
+			tr.BroadcastMessage(t, n.signMessage(msg))
 		}
 	})
 
@@ -308,12 +346,12 @@ func TestAirgappedAllSteps(t *testing.T) {
 		payload.SrcPayload = msgToSign
 		op := createOperation(t, string(signing_proposal_fsm.StateSigningPartialSignsCollected), "", payload)
 
-		operation, err := n.Machine.HandleOperation(op)
+		operation, err := n.Machine.HandleOperation(n.signOperation(t, op))
 		if err != nil {
 			t.Fatalf("%s: failed to handle operation %s: %v", n.Participant, op.Type, err)
 		}
 		for _, msg := range operation.ResultMsgs {
-			tr.BroadcastMessage(t, msg)
+			tr.BroadcastMessage(t, n.signMessage(msg))
 		}
 	})
 
@@ -354,12 +392,7 @@ func TestAirgappedMachine_Replay(t *testing.T) {
 		if err = am.InitKeys(); err != nil {
 			t.Fatalf(err.Error())
 		}
-		node := Node{
-			ParticipantID: i,
-			Participant:   participants[i],
-			Machine:       am,
-		}
-		tr.nodes = append(tr.nodes, &node)
+		tr.nodes = append(tr.nodes, newNode(t, i, participants[i], am))
 	}
 	defer os.RemoveAll(testDir)
 
@@ -374,6 +407,7 @@ func TestAirgappedMachine_Replay(t *testing.T) {
 			Username:      n.Participant,
 			Threshold:     threshold,
 			DkgPubKey:     pubKey,
+			PubKey:        n.hotPrivKey.Public().(ed25519.PublicKey),
 		}
 		initReq = append(initReq, entry)
 	}
@@ -381,7 +415,7 @@ func TestAirgappedMachine_Replay(t *testing.T) {
 	runStep(tr, func(n *Node, wg *sync.WaitGroup) {
 		defer wg.Done()
 
-		_, err := n.Machine.HandleOperation(op)
+		_, err := n.Machine.HandleOperation(n.signOperation(t, op))
 		if err != nil {
 			t.Fatalf("%s: failed to handle operation %s: %v", n.Participant, op.Type, err)
 		}
@@ -405,12 +439,12 @@ func TestAirgappedMachine_Replay(t *testing.T) {
 	runStep(tr, func(n *Node, wg *sync.WaitGroup) {
 		defer wg.Done()
 
-		operation, err := n.Machine.HandleOperation(op)
+		operation, err := n.Machine.HandleOperation(n.signOperation(t, op))
 		if err != nil {
 			t.Fatalf("%s: failed to handle operation %s: %v", n.Participant, op.Type, err)
 		}
 		for _, msg := range operation.ResultMsgs {
-			tr.BroadcastMessage(t, msg)
+			tr.BroadcastMessage(t, n.signMessage(msg))
 		}
 	})
 
@@ -429,15 +463,20 @@ func TestAirgappedMachine_Replay(t *testing.T) {
 		}
 		op := createOperation(t, string(dkg_proposal_fsm.StateDkgDealsAwaitConfirmations), "", payload)
 
-		operation, err := n.Machine.HandleOperation(op)
+		operation, err := n.Machine.HandleOperation(n.signOperation(t, op))
 		if err != nil {
 			t.Fatalf("%s: failed to handle operation %s: %v", n.Participant, op.Type, err)
 		}
 		for _, msg := range operation.ResultMsgs {
-			tr.BroadcastMessage(t, msg)
+			tr.BroadcastMessage(t, n.signMessage(msg))
 		}
 	})
 
+	identityPubKeys := make([]ed25519.PublicKey, 0, nodesCount)
+	for _, node := range tr.nodes {
+		identityPubKeys = append(identityPubKeys, node.Machine.GetIdentityPubKey())
+	}
+
 	// At this point something goes wrong and we have to restart the machines.
 	for _, node := range tr.nodes {
 		_ = node.Machine.db.Close()
@@ -458,10 +497,13 @@ func TestAirgappedMachine_Replay(t *testing.T) {
 		if err = am.InitKeys(); err != nil {
 			t.Fatalf(err.Error())
 		}
+		require.Equal(t, identityPubKeys[i], am.GetIdentityPubKey(), "identity key must survive a restart")
 		node := Node{
 			ParticipantID: i,
 			Participant:   participants[i],
 			Machine:       am,
+			hotPrivKey:    tr.nodes[i].hotPrivKey,
+			boardMessages: tr.nodes[i].boardMessages,
 			deals:         tr.nodes[i].deals,
 		}
 		newTr.nodes = append(newTr.nodes, &node)
@@ -469,8 +511,22 @@ func TestAirgappedMachine_Replay(t *testing.T) {
 	defer os.RemoveAll(testDir)
 
 	for _, node := range newTr.nodes {
-		err := node.Machine.ReplayOperationsLog(DKGIdentifier)
+		// operations logged before signatures and source messages were introduced are replayed as well
+		// as operations signed with a hot node key which was rotated since then
+		roundOperationsLog, err := node.Machine.getRoundOperationLog()
 		require.NoError(t, err)
+		for i := range roundOperationsLog[DKGIdentifier] {
+			roundOperationsLog[DKGIdentifier][i].Signature = nil
+			roundOperationsLog[DKGIdentifier][i].SourceMessages = nil
+		}
+		require.NoError(t, node.Machine.putRoundOperationLog(roundOperationsLog))
+		rotatedHotPubKey, _, err := ed25519.GenerateKey(nil)
+		require.NoError(t, err)
+		require.NoError(t, node.Machine.SetHotPubKey(rotatedHotPubKey))
+
+		err = node.Machine.ReplayOperationsLog(DKGIdentifier)
+		require.NoError(t, err)
+		require.NoError(t, node.Machine.SetHotPubKey(node.hotPrivKey.Public().(ed25519.PublicKey)))
 	}
 
 	//oldTr := tr
@@ -491,12 +547,12 @@ func TestAirgappedMachine_Replay(t *testing.T) {
 		}
 		op := createOperation(t, string(dkg_proposal_fsm.StateDkgResponsesAwaitConfirmations), "", payload)
 
-		operation, err := n.Machine.HandleOperation(op)
+		operation, err := n.Machine.HandleOperation(n.signOperation(t, op))
 		if err != nil {
 			t.Fatalf("%s: failed to handle operation %s: %v", n.Participant, op.Type, err)
 		}
 		for _, msg := range operation.ResultMsgs {
-			tr.BroadcastMessage(t, msg)
+			tr.BroadcastMessage(t, n.signMessage(msg))
 		}
 	})
 
@@ -515,12 +571,12 @@ func TestAirgappedMachine_Replay(t *testing.T) {
 		}
 		op := createOperation(t, string(dkg_proposal_fsm.StateDkgMasterKeyAwaitConfirmations), "", payload)
 
-		operation, err := n.Machine.HandleOperation(op)
+		operation, err := n.Machine.HandleOperation(n.signOperation(t, op))
 		if err != nil {
 			t.Fatalf("%s: failed to handle operation %s: %v", n.Participant, op.Type, err)
 		}
 		for _, msg := range operation.ResultMsgs {
-			tr.BroadcastMessage(t, msg)
+			tr.BroadcastMessage(t, n.signMessage(msg))
 		}
 	})
 
@@ -545,12 +601,12 @@ func TestAirgappedMachine_Replay(t *testing.T) {
 
 		op := createOperation(t, string(signing_proposal_fsm.StateSigningAwaitPartialSigns), "", payload)
 
-		operation, err := n.Machine.HandleOperation(op)
+		operation, err := n.Machine.HandleOperation(n.signOperation(t, op))
 		if err != nil {
 			t.Fatalf("%s: failed to handle operation %s: %v", n.Participant, op.Type, err)
 		}
 		for _, msg := range operation.ResultMsgs {
-			tr.BroadcastMessage(t, msg)
+			tr.BroadcastMessage(t, n.signMessage(msg))
 		}
 	})
 
@@ -570,12 +626,12 @@ func TestAirgappedMachine_Replay(t *testing.T) {
 		payload.SrcPayload = msgToSign
 		op := createOperation(t, string(signing_proposal_fsm.StateSigningPartialSignsCollected), "", payload)
 
-		operation, err := n.Machine.HandleOperation(op)
+		operation, err := n.Machine.HandleOperation(n.signOperation(t, op))
 		if err != nil {
 			t.Fatalf("%s: failed to handle operation %s: %v", n.Participant, op.Type, err)
 		}
 		for _, msg := range operation.ResultMsgs {
-			tr.BroadcastMessage(t, msg)
+			tr.BroadcastMessage(t, n.signMessage(msg))
 		}
 	})
 
@@ -620,3 +676,1009 @@ func runStep(transport *Transport, cb func(n *Node, wg *sync.WaitGroup)) {
 	}
 	wg.Wait()
 }
+
+func TestAirgappedMachine_ProcessBundles(t *testing.T) {
+	testDir := "/tmp/airgapped_test_bundles"
+	defer os.RemoveAll(testDir)
+
+	am, err := NewMachine(fmt.Sprintf("%s/%s", testDir, testDB))
+	if err != nil {
+		t.Fatalf("failed to create airgapped machine: %v", err)
+	}
+	am.SetEncryptionKey([]byte(testDB))
+	if err = am.InitKeys(); err != nil {
+		t.Fatalf(err.Error())
+	}
+	n := newNode(t, 0, "Participant#0", am)
+
+	hotTransport, err := transport.NewHotNodeDirTransport(testDir + "/transport")
+	if err != nil {
+		t.Fatalf("failed to create hot node transport: %v", err)
+	}
+	airgappedTransport, err := transport.NewAirgappedDirTransport(testDir + "/transport")
+	if err != nil {
+		t.Fatalf("failed to create airgapped transport: %v", err)
+	}
+
+	pubKey, err := am.pubKey.MarshalBinary()
+	if err != nil {
+		t.Fatalf("failed to marshal dkg pubkey: %v", err)
+	}
+	initReq := responses.SignatureProposalParticipantInvitationsResponse{
+		&responses.SignatureProposalParticipantInvitationEntry{
+			ParticipantId: 0,
+			Username:      "Participant#0",
+			Threshold:     1,
+			DkgPubKey:     pubKey,
+			PubKey:        n.hotPrivKey.Public().(ed25519.PublicKey),
+		},
+	}
+	op := n.signOperation(t, createOperation(t, string(signature_proposal_fsm.StateAwaitParticipantsConfirmations), "", initReq))
+	opBz, err := json.Marshal(op)
+	if err != nil {
+		t.Fatalf("failed to marshal operation: %v", err)
+	}
+	// an operation which is not signed by our hot node is rejected and does not block the next one
+	unsignedOp := createOperation(t, string(signature_proposal_fsm.StateAwaitParticipantsConfirmations), "", initReq)
+	unsignedOpBz, err := json.Marshal(unsignedOp)
+	require.NoError(t, err)
+	require.NoError(t, hotTransport.Send(transport.NewBundle(transport.OperationBundle, unsignedOp.ID, unsignedOpBz)))
+	if err = hotTransport.Send(transport.NewBundle(transport.OperationBundle, op.ID, opBz)); err != nil {
+		t.Fatalf("failed to send bundle: %v", err)
+	}
+
+	processed, rejected, err := am.ProcessBundles(airgappedTransport)
+	require.NoError(t, err)
+	require.Equal(t, 1, processed)
+	require.Equal(t, 1, rejected)
+
+	// processed and rejected bundles must not be handled twice
+	processed, rejected, err = am.ProcessBundles(airgappedTransport)
+	require.NoError(t, err)
+	require.Equal(t, 0, processed)
+	require.Equal(t, 0, rejected)
+
+	results, err := hotTransport.Receive()
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.Equal(t, transport.ResultBundle, results[0].Kind)
+	require.Equal(t, op.ID, results[0].ID)
+
+	var resultOperation client.Operation
+	require.NoError(t, json.Unmarshal(results[0].Payload, &resultOperation))
+	require.Equal(t, op.ID, resultOperation.ID)
+	require.Len(t, resultOperation.ResultMsgs, 1)
+	require.NoError(t, resultOperation.VerifyResultSignature(am.GetIdentityPubKey()))
+
+	// a tampered result must not pass the verification on the hot node
+	resultOperation.ResultMsgs[0].Data = []byte("{}")
+	require.Error(t, resultOperation.VerifyResultSignature(am.GetIdentityPubKey()))
+}
+
+func TestAirgappedMachine_RejectsUnauthenticatedOperations(t *testing.T) {
+	testDir := "/tmp/airgapped_test_auth"
+	nodesCount := 2
+	defer os.RemoveAll(testDir)
+
+	tr := &Transport{}
+	for i := 0; i < nodesCount; i++ {
+		am, err := NewMachine(fmt.Sprintf("%s/%s-%d", testDir, testDB, i))
+		if err != nil {
+			t.Fatalf("failed to create airgapped machine: %v", err)
+		}
+		am.SetEncryptionKey([]byte(fmt.Sprintf(testDB+"%d", i)))
+		if err = am.InitKeys(); err != nil {
+			t.Fatalf(err.Error())
+		}
+		tr.nodes = append(tr.nodes, newNode(t, i, fmt.Sprintf("Participant#%d", i), am))
+	}
+
+	var (
+		initReq           responses.SignatureProposalParticipantInvitationsResponse
+		getCommitsRequest responses.DKGProposalPubKeysParticipantResponse
+	)
+	for _, n := range tr.nodes {
+		pubKey, err := n.Machine.pubKey.MarshalBinary()
+		if err != nil {
+			t.Fatalf("failed to marshal dkg pubkey: %v", err)
+		}
+		initReq = append(initReq, &responses.SignatureProposalParticipantInvitationEntry{
+			ParticipantId: n.ParticipantID,
+			Username:      n.Participant,
+			Threshold:     nodesCount,
+			DkgPubKey:     pubKey,
+			PubKey:        n.hotPrivKey.Public().(ed25519.PublicKey),
+		})
+		getCommitsRequest = append(getCommitsRequest, &responses.DKGProposalPubKeysParticipantEntry{
+			ParticipantId: n.ParticipantID,
+			Username:      n.Participant,
+			DkgPubKey:     pubKey,
+		})
+	}
+	op := createOperation(t, string(signature_proposal_fsm.StateAwaitParticipantsConfirmations), "", initReq)
+
+	node := tr.nodes[0]
+
+	// an unsigned operation must be rejected
+	_, err := node.Machine.HandleOperation(op)
+	require.Error(t, err)
+
+	// an operation signed by someone else's hot key must be rejected
+	_, err = node.Machine.HandleOperation(tr.nodes[1].signOperation(t, op))
+	require.Error(t, err)
+
+	// a signed operation with a modified payload must be rejected
+	signedOp := node.signOperation(t, op)
+	signedOp.Payload = []byte("[]")
+	_, err = node.Machine.HandleOperation(signedOp)
+	require.Error(t, err)
+
+	// a signed operation with a modified event must be rejected
+	signedOp = node.signOperation(t, op)
+	signedOp.Event = "forged_event"
+	_, err = node.Machine.HandleOperation(signedOp)
+	require.Error(t, err)
+
+	// the hot node key of a participant must match the key pinned out of band
+	otherHotKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	require.NoError(t, node.Machine.PinParticipantHotKey(tr.nodes[1].Participant, otherHotKey))
+	_, err = node.Machine.HandleOperation(node.signOperation(t, op))
+	require.Error(t, err)
+	require.NoError(t, node.Machine.PinParticipantHotKey(tr.nodes[1].Participant, tr.nodes[1].hotPrivKey.Public().(ed25519.PublicKey)))
+
+	runStep(tr, func(n *Node, wg *sync.WaitGroup) {
+		defer wg.Done()
+
+		_, err := n.Machine.HandleOperation(n.signOperation(t, op))
+		require.NoError(t, err)
+	})
+
+	op = createOperation(t, string(dkg_proposal_fsm.StateDkgCommitsAwaitConfirmations), "", getCommitsRequest)
+	runStep(tr, func(n *Node, wg *sync.WaitGroup) {
+		defer wg.Done()
+
+		operation, err := n.Machine.HandleOperation(n.signOperation(t, op))
+		require.NoError(t, err)
+		for _, msg := range operation.ResultMsgs {
+			tr.BroadcastMessage(t, n.signMessage(msg))
+		}
+	})
+
+	var payload responses.DKGProposalCommitParticipantResponse
+	for _, req := range node.commits {
+		payload = append(payload, &responses.DKGProposalCommitParticipantEntry{
+			ParticipantId: req.ParticipantId,
+			Username:      fmt.Sprintf("Participant#%d", req.ParticipantId),
+			DkgCommit:     req.Commit,
+		})
+	}
+
+	// the hot node forges a commit of the second participant, the airgapped machine must not accept it
+	forgedPayload := make(responses.DKGProposalCommitParticipantResponse, len(payload))
+	copy(forgedPayload, payload)
+	forgedPayload[1] = &responses.DKGProposalCommitParticipantEntry{
+		ParticipantId: payload[1].ParticipantId,
+		Username:      payload[1].Username,
+		DkgCommit:     payload[0].DkgCommit,
+	}
+	op = createOperation(t, string(dkg_proposal_fsm.StateDkgDealsAwaitConfirmations), "", forgedPayload)
+	operation, err := node.Machine.HandleOperation(node.signOperation(t, op))
+	require.NoError(t, err)
+	require.Len(t, operation.ResultMsgs, 1)
+	require.Equal(t, string(dkg_proposal_fsm.EventDKGDealConfirmationError), operation.ResultMsgs[0].Event)
+
+	var errorReq requests.DKGProposalConfirmationErrorRequest
+	require.NoError(t, json.Unmarshal(operation.ResultMsgs[0].Data, &errorReq))
+	require.NoError(t, errorReq.Validate())
+	require.Equal(t, requests.ErrorCodeInvalidMessage, errorReq.Error.Code)
+	require.Equal(t, forgedPayload[1].Username, errorReq.Error.Participant)
+
+	// a message with a broken signature must not be accepted too
+	op = createOperation(t, string(dkg_proposal_fsm.StateDkgDealsAwaitConfirmations), "", payload)
+	op.SourceMessages = make([]storage.Message, len(node.boardMessages))
+	copy(op.SourceMessages, node.boardMessages)
+	for i, msg := range op.SourceMessages {
+		if msg.SenderAddr == payload[1].Username {
+			op.SourceMessages[i].Signature = tr.nodes[0].signMessage(msg).Signature
+		}
+	}
+	if err = op.Sign(node.hotPrivKey); err != nil {
+		t.Fatalf("failed to sign operation: %v", err)
+	}
+	operation, err = node.Machine.HandleOperation(op)
+	require.NoError(t, err)
+	require.Len(t, operation.ResultMsgs, 1)
+	require.Equal(t, string(dkg_proposal_fsm.EventDKGDealConfirmationError), operation.ResultMsgs[0].Event)
+}
+
+func TestAirgappedMachine_RestoreFromMnemonic(t *testing.T) {
+	testDir := "/tmp/airgapped_test_restore"
+	nodesCount := 2
+	threshold := 2
+	defer os.RemoveAll(testDir)
+
+	tr := &Transport{}
+	for i := 0; i < nodesCount; i++ {
+		am, err := NewMachine(fmt.Sprintf("%s/%s-%d", testDir, testDB, i))
+		if err != nil {
+			t.Fatalf("failed to create airgapped machine: %v", err)
+		}
+		am.SetEncryptionKey([]byte(fmt.Sprintf(testDB+"%d", i)))
+		if err = am.InitKeys(); err != nil {
+			t.Fatalf(err.Error())
+		}
+		tr.nodes = append(tr.nodes, newNode(t, i, fmt.Sprintf("Participant#%d", i), am))
+	}
+
+	runDKG(t, tr, threshold, nil)
+
+	original := tr.nodes[0]
+	mnemonic, err := original.Machine.GetBaseSeedMnemonic()
+	require.NoError(t, err)
+	operationsLogs, err := original.Machine.GetOperationsLogs()
+	require.NoError(t, err)
+	originalKeyring, err := original.Machine.loadBLSKeyring(DKGIdentifier)
+	require.NoError(t, err)
+
+	// restore the first machine on a clean database
+	restored, err := NewMachine(fmt.Sprintf("%s/%s-restored", testDir, testDB))
+	require.NoError(t, err)
+	restored.SetEncryptionKey([]byte("new password"))
+	require.NoError(t, restored.InitKeys())
+	require.NoError(t, restored.SetHotPubKey(original.hotPrivKey.Public().(ed25519.PublicKey)))
+
+	require.Error(t, restored.RestoreFromMnemonic("invalid mnemonic", operationsLogs))
+
+	// the machine is not changed if the replay fails
+	brokenLogs := RoundOperationLog{DKGIdentifier: append([]client.Operation{}, operationsLogs[DKGIdentifier]...)}
+	brokenLogs[DKGIdentifier][1].Type = "unknown"
+	pubKey, seed := restored.pubKey, restored.baseSeed
+	require.Error(t, restored.RestoreFromMnemonic(mnemonic, brokenLogs))
+	require.True(t, pubKey.Equal(restored.pubKey))
+	storedSeed, err := restored.getBaseSeed()
+	require.NoError(t, err)
+	require.Equal(t, seed, storedSeed)
+	restoredLogs, err := restored.GetOperationsLogs()
+	require.NoError(t, err)
+	require.Empty(t, restoredLogs[DKGIdentifier])
+
+	// the operations logs are exported encrypted
+	archive, err := original.Machine.ExportOperationsLogs([]byte("archive password"))
+	require.NoError(t, err)
+	require.True(t, IsOperationsLogsArchive(archive))
+	_, err = OpenOperationsLogsArchive(archive, []byte("wrong password"))
+	require.Error(t, err)
+	operationsLogs, err = OpenOperationsLogsArchive(archive, []byte("archive password"))
+	require.NoError(t, err)
+
+	require.NoError(t, restored.RestoreFromMnemonic(mnemonic, operationsLogs))
+
+	require.True(t, original.Machine.pubKey.Equal(restored.pubKey))
+	require.Equal(t, original.Machine.GetIdentityPubKey(), restored.GetIdentityPubKey())
+
+	restoredKeyring, err := restored.loadBLSKeyring(DKGIdentifier)
+	require.NoError(t, err)
+	require.True(t, originalKeyring.PubPoly.Commit().Equal(restoredKeyring.PubPoly.Commit()))
+	require.Equal(t, originalKeyring.Share.I, restoredKeyring.Share.I)
+	require.True(t, originalKeyring.Share.V.Equal(restoredKeyring.Share.V))
+
+	// the machine with finished DKG rounds must not be overwritten
+	require.Error(t, restored.RestoreFromMnemonic(mnemonic, operationsLogs))
+}
+
+// runDKG runs all DKG steps for the nodes of the transport, beforeStep is called before every step if set
+func runDKG(t *testing.T, tr *Transport, threshold int, beforeStep func(step fsm.State)) {
+	var (
+		initReq           responses.SignatureProposalParticipantInvitationsResponse
+		getCommitsRequest responses.DKGProposalPubKeysParticipantResponse
+	)
+	for _, n := range tr.nodes {
+		pubKey, err := n.Machine.pubKey.MarshalBinary()
+		if err != nil {
+			t.Fatalf("failed to marshal dkg pubkey: %v", err)
+		}
+		initReq = append(initReq, &responses.SignatureProposalParticipantInvitationEntry{
+			ParticipantId: n.ParticipantID,
+			Username:      n.Participant,
+			Threshold:     threshold,
+			DkgPubKey:     pubKey,
+			PubKey:        n.hotPrivKey.Public().(ed25519.PublicKey),
+		})
+		getCommitsRequest = append(getCommitsRequest, &responses.DKGProposalPubKeysParticipantEntry{
+			ParticipantId: n.ParticipantID,
+			Username:      n.Participant,
+			DkgPubKey:     pubKey,
+		})
+	}
+
+	handle := func(n *Node, op client.Operation) {
+		operation, err := n.Machine.HandleOperation(n.signOperation(t, op))
+		if err != nil {
+			t.Fatalf("%s: failed to handle operation %s: %v", n.Participant, op.Type, err)
+		}
+		for _, msg := range operation.ResultMsgs {
+			tr.BroadcastMessage(t, n.signMessage(msg))
+		}
+	}
+
+	step := func(state fsm.State, cb func(n *Node, wg *sync.WaitGroup)) {
+		if beforeStep != nil {
+			beforeStep(state)
+		}
+		runStep(tr, cb)
+	}
+
+	op := createOperation(t, string(signature_proposal_fsm.StateAwaitParticipantsConfirmations), "", initReq)
+	step(signature_proposal_fsm.StateAwaitParticipantsConfirmations, func(n *Node, wg *sync.WaitGroup) {
+		defer wg.Done()
+		if _, err := n.Machine.HandleOperation(n.signOperation(t, op)); err != nil {
+			t.Fatalf("%s: failed to handle operation %s: %v", n.Participant, op.Type, err)
+		}
+	})
+
+	op = createOperation(t, string(dkg_proposal_fsm.StateDkgCommitsAwaitConfirmations), "", getCommitsRequest)
+	step(dkg_proposal_fsm.StateDkgCommitsAwaitConfirmations, func(n *Node, wg *sync.WaitGroup) {
+		defer wg.Done()
+		handle(n, op)
+	})
+
+	step(dkg_proposal_fsm.StateDkgDealsAwaitConfirmations, func(n *Node, wg *sync.WaitGroup) {
+		defer wg.Done()
+		var payload responses.DKGProposalCommitParticipantResponse
+		for _, req := range n.commits {
+			payload = append(payload, &responses.DKGProposalCommitParticipantEntry{
+				ParticipantId: req.ParticipantId,
+				Username:      fmt.Sprintf("Participant#%d", req.ParticipantId),
+				DkgCommit:     req.Commit,
+			})
+		}
+		handle(n, createOperation(t, string(dkg_proposal_fsm.StateDkgDealsAwaitConfirmations), "", payload))
+	})
+
+	step(dkg_proposal_fsm.StateDkgResponsesAwaitConfirmations, func(n *Node, wg *sync.WaitGroup) {
+		defer wg.Done()
+		var payload responses.DKGProposalDealParticipantResponse
+		for _, req := range n.deals {
+			payload = append(payload, &responses.DKGProposalDealParticipantEntry{
+				ParticipantId: req.ParticipantId,
+				Username:      fmt.Sprintf("Participant#%d", req.ParticipantId),
+				DkgDeal:       req.Deal,
+			})
+		}
+		handle(n, createOperation(t, string(dkg_proposal_fsm.StateDkgResponsesAwaitConfirmations), "", payload))
+	})
+
+	step(dkg_proposal_fsm.StateDkgMasterKeyAwaitConfirmations, func(n *Node, wg *sync.WaitGroup) {
+		defer wg.Done()
+		var payload responses.DKGProposalResponseParticipantResponse
+		for _, req := range n.responses {
+			payload = append(payload, &responses.DKGProposalResponseParticipantEntry{
+				ParticipantId: req.ParticipantId,
+				Username:      fmt.Sprintf("Participant#%d", req.ParticipantId),
+				DkgResponse:   req.Response,
+			})
+		}
+		handle(n, createOperation(t, string(dkg_proposal_fsm.StateDkgMasterKeyAwaitConfirmations), "", payload))
+	})
+}
+
+func TestAirgappedMachine_ExportImportKeyrings(t *testing.T) {
+	testDir := "/tmp/airgapped_test_keyrings_archive"
+	nodesCount := 2
+	threshold := 2
+	defer os.RemoveAll(testDir)
+
+	tr := &Transport{}
+	for i := 0; i < nodesCount; i++ {
+		am, err := NewMachine(fmt.Sprintf("%s/%s-%d", testDir, testDB, i))
+		if err != nil {
+			t.Fatalf("failed to create airgapped machine: %v", err)
+		}
+		am.SetEncryptionKey([]byte(fmt.Sprintf(testDB+"%d", i)))
+		if err = am.InitKeys(); err != nil {
+			t.Fatalf(err.Error())
+		}
+		tr.nodes = append(tr.nodes, newNode(t, i, fmt.Sprintf("Participant#%d", i), am))
+	}
+
+	runDKG(t, tr, threshold, nil)
+
+	original := tr.nodes[0].Machine
+	password := []byte("archive password")
+	archive, err := original.ExportKeyrings(password)
+	require.NoError(t, err)
+
+	target, err := NewMachine(fmt.Sprintf("%s/%s-target", testDir, testDB))
+	require.NoError(t, err)
+	target.SetEncryptionKey([]byte("target password"))
+	require.NoError(t, target.InitKeys())
+
+	_, err = target.ImportKeyrings(archive, []byte("wrong password"))
+	require.Error(t, err)
+
+	var tampered Archive
+	require.NoError(t, json.Unmarshal(archive, &tampered))
+	tampered.Data[len(tampered.Data)-1] ^= 0xff
+	tamperedBz, err := json.Marshal(tampered)
+	require.NoError(t, err)
+	_, err = target.ImportKeyrings(tamperedBz, password)
+	require.Error(t, err)
+
+	// an archive with a share which does not match the public polynomial must be rejected
+	data, err := openKeyringsArchive(password, archive)
+	require.NoError(t, err)
+	foreignKeyring, err := tr.nodes[1].Machine.loadBLSKeyring(DKGIdentifier)
+	require.NoError(t, err)
+	ownKeyring, err := dkg.LoadBLSKeyringFromBytes(original.baseSuite, data.Keyrings[0].Keyring)
+	require.NoError(t, err)
+	ownKeyring.Share.V = foreignKeyring.Share.V
+	data.Keyrings[0].Keyring, err = ownKeyring.Bytes()
+	require.NoError(t, err)
+	invalidShareArchive, err := sealKeyringsArchive(DefaultScryptKDF(), password, *data)
+	require.NoError(t, err)
+	_, err = target.ImportKeyrings(invalidShareArchive, password)
+	require.Error(t, err)
+
+	imported, err := target.ImportKeyrings(archive, password)
+	require.NoError(t, err)
+	require.Equal(t, []string{DKGIdentifier}, imported)
+
+	originalKeyring, err := original.loadBLSKeyring(DKGIdentifier)
+	require.NoError(t, err)
+	importedKeyring, err := target.loadBLSKeyring(DKGIdentifier)
+	require.NoError(t, err)
+	require.True(t, originalKeyring.PubPoly.Commit().Equal(importedKeyring.PubPoly.Commit()))
+	require.True(t, originalKeyring.Share.V.Equal(importedKeyring.Share.V))
+
+	originalLog, err := original.getOperationsLog(DKGIdentifier)
+	require.NoError(t, err)
+	importedLog, err := target.getOperationsLog(DKGIdentifier)
+	require.NoError(t, err)
+	require.Equal(t, len(originalLog), len(importedLog))
+
+	keyrings, err := target.GetBLSKeyrings()
+	require.NoError(t, err)
+	require.Contains(t, keyrings, DKGIdentifier)
+
+	_, err = target.ImportKeyrings(archive, password)
+	require.Error(t, err)
+
+	// the imported round is ready for signing, also after a restart
+	node := tr.nodes[0]
+	require.NoError(t, target.SetHotPubKey(node.hotPrivKey.Public().(ed25519.PublicKey)))
+	require.NoError(t, target.db.Close())
+	target, err = NewMachine(fmt.Sprintf("%s/%s-target", testDir, testDB))
+	require.NoError(t, err)
+	target.SetEncryptionKey([]byte("target password"))
+	require.NoError(t, target.InitKeys())
+
+	partialSignsOp := node.signOperation(t, createOperation(t, string(signing_proposal_fsm.StateSigningAwaitPartialSigns), "",
+		responses.SigningPartialSignsParticipantInvitationsResponse{SrcPayload: []byte("i am a message")}))
+	originalOperation, err := original.HandleOperation(partialSignsOp)
+	require.NoError(t, err)
+	importedOperation, err := target.HandleOperation(partialSignsOp)
+	require.NoError(t, err)
+	require.Len(t, importedOperation.ResultMsgs, 1)
+	require.Equal(t, string(signing_proposal_fsm.EventSigningPartialSignReceived), importedOperation.ResultMsgs[0].Event)
+	require.Equal(t, originalOperation.ResultMsgs[0].Data, importedOperation.ResultMsgs[0].Data)
+}
+
+func TestAirgappedMachine_RestoreDKGState(t *testing.T) {
+	testDir := "/tmp/airgapped_test_dkg_state"
+	nodesCount := 3
+	threshold := 2
+	defer os.RemoveAll(testDir)
+
+	tr := &Transport{}
+	for i := 0; i < nodesCount; i++ {
+		am, err := NewMachine(fmt.Sprintf("%s/%s-%d", testDir, testDB, i))
+		if err != nil {
+			t.Fatalf("failed to create airgapped machine: %v", err)
+		}
+		am.SetEncryptionKey([]byte(fmt.Sprintf(testDB+"%d", i)))
+		if err = am.InitKeys(); err != nil {
+			t.Fatalf(err.Error())
+		}
+		tr.nodes = append(tr.nodes, newNode(t, i, fmt.Sprintf("Participant#%d", i), am))
+	}
+
+	restart := func(n *Node) {
+		require.NoError(t, n.Machine.db.Close())
+		am, err := NewMachine(fmt.Sprintf("%s/%s-%d", testDir, testDB, n.ParticipantID))
+		require.NoError(t, err)
+		am.SetEncryptionKey([]byte(fmt.Sprintf(testDB+"%d", n.ParticipantID)))
+		require.NoError(t, am.InitKeys())
+		n.Machine = am
+	}
+
+	// the record does not keep the seed of the machine, records saved by earlier versions do
+	legacyDKGState := func(n *Node) {
+		salt, err := n.Machine.db.Get([]byte(saltDBKey), nil)
+		require.NoError(t, err)
+		encryptedRecord, err := n.Machine.db.Get([]byte(makeDKGStateDBKey(DKGIdentifier)), nil)
+		require.NoError(t, err)
+		recordBz, err := n.Machine.decrypt(salt, encryptedRecord)
+		require.NoError(t, err)
+		var record dkgStateRecord
+		require.NoError(t, json.Unmarshal(recordBz, &record))
+
+		var state map[string]interface{}
+		require.NoError(t, json.Unmarshal(record.State, &state))
+		require.NotContains(t, state, "seed")
+		if state["initialized"] != true {
+			return
+		}
+		delete(state, "initialized")
+		state["seed"] = n.Machine.baseSeed
+		record.State, err = json.Marshal(state)
+		require.NoError(t, err)
+		encryptedRecord, err = n.Machine.encryptDKGStateRecord(salt, record)
+		require.NoError(t, err)
+		require.NoError(t, n.Machine.db.Put([]byte(makeDKGStateDBKey(DKGIdentifier)), encryptedRecord, nil))
+	}
+
+	// restart the machines between every step of the round, the state must be restored without a replay
+	runDKG(t, tr, threshold, func(state fsm.State) {
+		if state == signature_proposal_fsm.StateAwaitParticipantsConfirmations {
+			return
+		}
+		legacyDKGState(tr.nodes[0])
+		for _, n := range tr.nodes {
+			restart(n)
+			_, ok := n.Machine.dkgInstances[DKGIdentifier]
+			require.True(t, ok, "dkg instance must be restored before %s", state)
+		}
+	})
+
+	for _, n := range tr.nodes {
+		require.Len(t, n.masterKeys, nodesCount)
+		for i := range n.masterKeys {
+			require.Equal(t, n.masterKeys[0].MasterKey, n.masterKeys[i].MasterKey)
+		}
+		_, err := n.Machine.loadBLSKeyring(DKGIdentifier)
+		require.NoError(t, err)
+	}
+
+	// the state which is not consistent with the operations log must not be restored
+	n := tr.nodes[0]
+	require.NoError(t, n.Machine.DropOperationsLog(DKGIdentifier))
+	restart(n)
+	_, ok := n.Machine.dkgInstances[DKGIdentifier]
+	require.False(t, ok)
+}
+
+// legacyEncrypt encrypts the data the way it was done before the ciphertext header was introduced
+func legacyEncrypt(t *testing.T, key, salt, data []byte) []byte {
+	derivedKey, err := scrypt.Key(key, salt, N, 8, 1, 32)
+	require.NoError(t, err)
+	gcm, err := newGCM(derivedKey)
+	require.NoError(t, err)
+	nonce := make([]byte, gcm.NonceSize())
+	_, err = rand.Read(nonce)
+	require.NoError(t, err)
+	return gcm.Seal(nonce, nonce, data, nil)
+}
+
+func TestAirgappedMachine_Encryption(t *testing.T) {
+	password, salt, data := []byte("password"), []byte("salt"), []byte("data")
+
+	for _, kdf := range []KDF{DefaultScryptKDF(), &Argon2idKDF{Time: 1, Memory: 1024, Threads: 1}} {
+		encrypted, err := encrypt(kdf, password, salt, data)
+		require.NoError(t, err)
+		require.False(t, isLegacyCiphertext(encrypted))
+
+		decryptedKDF, decryptedSalt, _, err := unmarshalCiphertextHeader(encrypted)
+		require.NoError(t, err)
+		require.Equal(t, kdf, decryptedKDF)
+		require.Equal(t, salt, decryptedSalt)
+
+		// the salt is taken from the header
+		decrypted, err := decrypt(password, nil, encrypted)
+		require.NoError(t, err)
+		require.Equal(t, data, decrypted)
+
+		_, err = decrypt([]byte("wrong password"), nil, encrypted)
+		require.Error(t, err)
+
+		// the header is authenticated
+		tampered := append([]byte{}, encrypted...)
+		tampered[len(ciphertextMagic)+2+2+len(kdf.Params())-1] ^= 0x01
+		_, err = decrypt(password, nil, tampered)
+		require.Error(t, err)
+	}
+
+	decrypted, err := decrypt(password, salt, legacyEncrypt(t, password, salt, data))
+	require.NoError(t, err)
+	require.Equal(t, data, decrypted)
+
+	// parameters from a corrupted header must not make the machine run out of memory or time
+	for _, kdf := range []KDF{
+		&ScryptKDF{N: 1 << 25, R: 8, P: 1},
+		&ScryptKDF{N: 1 << 16, R: 1 << 20, P: 1},
+		&ScryptKDF{N: 1 << 16, R: 8, P: 1 << 20},
+		&ScryptKDF{N: 1 << 22, R: 32, P: 1},
+		&ScryptKDF{N: 1 << 20, R: 8, P: 16},
+		&Argon2idKDF{Time: 1, Memory: 1 << 30, Threads: 1},
+		&Argon2idKDF{Time: 1, Memory: 1024, Threads: 255},
+		&Argon2idKDF{Time: 64, Memory: 1024 * 1024, Threads: 1},
+	} {
+		_, err = kdf.DeriveKey(password, salt)
+		require.Error(t, err, "%+v", kdf)
+	}
+}
+
+func TestAirgappedMachine_ReEncrypt(t *testing.T) {
+	testDir := "/tmp/airgapped_test_reencrypt"
+	defer os.RemoveAll(testDir)
+
+	password := []byte("password")
+	am, err := NewMachine(fmt.Sprintf("%s/%s", testDir, testDB))
+	require.NoError(t, err)
+	am.SetEncryptionKey(password)
+	require.NoError(t, am.InitKeys())
+	pubKey := am.pubKey
+
+	// downgrade the stored keys to the legacy format
+	salt, err := am.db.Get([]byte(saltDBKey), nil)
+	require.NoError(t, err)
+	for _, key := range encryptedEntries {
+		value, err := am.db.Get([]byte(key), nil)
+		require.NoError(t, err)
+		decrypted, err := decrypt(password, salt, value)
+		require.NoError(t, err)
+		require.NoError(t, am.db.Put([]byte(key), legacyEncrypt(t, password, salt, decrypted), nil))
+	}
+	require.NoError(t, am.LoadKeysFromDB())
+
+	am.SetKDF(&Argon2idKDF{Time: 1, Memory: 1024, Threads: 1})
+	reEncrypted, err := am.ReEncrypt()
+	require.NoError(t, err)
+	require.Equal(t, len(encryptedEntries), reEncrypted)
+
+	for _, key := range encryptedEntries {
+		value, err := am.db.Get([]byte(key), nil)
+		require.NoError(t, err)
+		kdf, _, _, err := unmarshalCiphertextHeader(value)
+		require.NoError(t, err)
+		require.Equal(t, KDFArgon2id, kdf.Type())
+	}
+
+	am.DropSensitiveData()
+	am.SetEncryptionKey(password)
+	require.NoError(t, am.LoadKeysFromDB())
+	require.True(t, pubKey.Equal(am.pubKey))
+}
+
+func TestAirgappedMachine_ChangePassword(t *testing.T) {
+	testDir := "/tmp/airgapped_test_change_password"
+	nodesCount := 2
+	threshold := 2
+	defer os.RemoveAll(testDir)
+
+	tr := &Transport{}
+	for i := 0; i < nodesCount; i++ {
+		am, err := NewMachine(fmt.Sprintf("%s/%s-%d", testDir, testDB, i))
+		if err != nil {
+			t.Fatalf("failed to create airgapped machine: %v", err)
+		}
+		am.SetEncryptionKey([]byte(fmt.Sprintf(testDB+"%d", i)))
+		if err = am.InitKeys(); err != nil {
+			t.Fatalf(err.Error())
+		}
+		tr.nodes = append(tr.nodes, newNode(t, i, fmt.Sprintf("Participant#%d", i), am))
+	}
+
+	runDKG(t, tr, threshold, nil)
+
+	am := tr.nodes[0].Machine
+	oldPassword, newPassword := []byte(testDB+"0"), []byte("new password")
+	keyring, err := am.loadBLSKeyring(DKGIdentifier)
+	require.NoError(t, err)
+
+	require.Error(t, am.ChangePassword([]byte("wrong password"), newPassword))
+
+	// the change must fail atomically if any entry can not be decrypted
+	keyringDBKey := []byte(makeBLSKeyKeyringDBKey(DKGIdentifier))
+	validKeyringBz, err := am.db.Get(keyringDBKey, nil)
+	require.NoError(t, err)
+	corruptedKeyringBz := append([]byte{}, validKeyringBz...)
+	corruptedKeyringBz[len(corruptedKeyringBz)-1] ^= 0xff
+	require.NoError(t, am.db.Put(keyringDBKey, corruptedKeyringBz, nil))
+	require.Error(t, am.ChangePassword(oldPassword, newPassword))
+	require.NoError(t, am.db.Put(keyringDBKey, validKeyringBz, nil))
+	am.SetEncryptionKey(oldPassword)
+	require.NoError(t, am.LoadKeysFromDB())
+
+	require.NoError(t, am.ChangePassword(oldPassword, newPassword))
+
+	am.SetEncryptionKey(oldPassword)
+	require.Error(t, am.LoadKeysFromDB())
+	_, err = am.loadBLSKeyring(DKGIdentifier)
+	require.Error(t, err)
+
+	am.SetEncryptionKey(newPassword)
+	require.NoError(t, am.LoadKeysFromDB())
+	changedKeyring, err := am.loadBLSKeyring(DKGIdentifier)
+	require.NoError(t, err)
+	require.True(t, keyring.Share.V.Equal(changedKeyring.Share.V))
+}
+
+func TestAirgappedMachine_EncryptedOperationsLog(t *testing.T) {
+	testDir := "/tmp/airgapped_test_operations_log"
+	defer os.RemoveAll(testDir)
+
+	dbPath := fmt.Sprintf("%s/%s", testDir, testDB)
+	password := []byte("password")
+	am, err := NewMachine(dbPath)
+	require.NoError(t, err)
+	am.SetEncryptionKey(password)
+	require.NoError(t, am.InitKeys())
+
+	// the log stored by an older version of the machine is plaintext
+	operation := client.Operation{ID: "plaintext_operation", DKGIdentifier: DKGIdentifier}
+	plaintextLogBz, err := json.Marshal(RoundOperationLog{DKGIdentifier: {operation}})
+	require.NoError(t, err)
+	require.NoError(t, am.db.Put([]byte(operationsLogDBKey), plaintextLogBz, nil))
+	require.NoError(t, am.db.Close())
+
+	am, err = NewMachine(dbPath)
+	require.NoError(t, err)
+	am.SetEncryptionKey(password)
+	require.NoError(t, am.InitKeys())
+
+	operationsLogBz, err := am.db.Get([]byte(operationsLogDBKey), nil)
+	require.NoError(t, err)
+	require.False(t, isPlaintextOperationsLog(operationsLogBz))
+	require.False(t, bytes.Contains(operationsLogBz, []byte(operation.ID)))
+
+	operationsLog, err := am.getOperationsLog(DKGIdentifier)
+	require.NoError(t, err)
+	require.Len(t, operationsLog, 1)
+	require.Equal(t, operation.ID, operationsLog[0].ID)
+
+	require.NoError(t, am.DropOperationsLog(DKGIdentifier))
+	operationsLog, err = am.getOperationsLog(DKGIdentifier)
+	require.NoError(t, err)
+	require.Empty(t, operationsLog)
+
+	am.SetEncryptionKey([]byte("wrong password"))
+	_, err = am.getRoundOperationLog()
+	require.Error(t, err)
+}
+
+func TestAirgappedMachine_VerifyShare(t *testing.T) {
+	testDir := "/tmp/airgapped_test_verify_share"
+	nodesCount := 3
+	threshold := 2
+	defer os.RemoveAll(testDir)
+
+	tr := &Transport{}
+	for i := 0; i < nodesCount; i++ {
+		am, err := NewMachine(fmt.Sprintf("%s/%s-%d", testDir, testDB, i))
+		if err != nil {
+			t.Fatalf("failed to create airgapped machine: %v", err)
+		}
+		am.SetEncryptionKey([]byte(fmt.Sprintf(testDB+"%d", i)))
+		if err = am.InitKeys(); err != nil {
+			t.Fatalf(err.Error())
+		}
+		tr.nodes = append(tr.nodes, newNode(t, i, fmt.Sprintf("Participant#%d", i), am))
+	}
+
+	runDKG(t, tr, threshold, nil)
+
+	indexes := make(map[int]bool)
+	for _, n := range tr.nodes {
+		publicShare, err := n.Machine.VerifyShare(DKGIdentifier)
+		require.NoError(t, err)
+		require.Equal(t, DKGIdentifier, publicShare.DKGIdentifier)
+		require.False(t, indexes[publicShare.Index], "share indexes must be unique")
+		indexes[publicShare.Index] = true
+
+		// other participants get the same public share from their public polynomials
+		for _, other := range tr.nodes {
+			keyring, err := other.Machine.loadBLSKeyring(DKGIdentifier)
+			require.NoError(t, err)
+			pubShare, err := keyring.PubPoly.Eval(publicShare.Index).V.MarshalBinary()
+			require.NoError(t, err)
+			require.Equal(t, publicShare.PubShare, pubShare)
+			masterPubKey, err := keyring.PubPoly.Commit().MarshalBinary()
+			require.NoError(t, err)
+			require.Equal(t, publicShare.MasterPubKey, masterPubKey)
+		}
+	}
+
+	_, err := tr.nodes[0].Machine.VerifyShare("unknown")
+	require.Error(t, err)
+
+	// a corrupted share must not pass the verification
+	am := tr.nodes[0].Machine
+	keyring, err := am.loadBLSKeyring(DKGIdentifier)
+	require.NoError(t, err)
+	foreignKeyring, err := tr.nodes[1].Machine.loadBLSKeyring(DKGIdentifier)
+	require.NoError(t, err)
+	keyring.Share.V = foreignKeyring.Share.V
+	require.NoError(t, am.saveBLSKeyring(DKGIdentifier, keyring))
+	_, err = am.VerifyShare(DKGIdentifier)
+	require.Error(t, err)
+}
+
+func TestAirgappedMachine_InvalidPartialSignExcluded(t *testing.T) {
+	testDir := "/tmp/airgapped_test_invalid_partial_sign"
+	nodesCount := 3
+	threshold := 2
+	defer os.RemoveAll(testDir)
+
+	tr := &Transport{}
+	for i := 0; i < nodesCount; i++ {
+		am, err := NewMachine(fmt.Sprintf("%s/%s-%d", testDir, testDB, i))
+		if err != nil {
+			t.Fatalf("failed to create airgapped machine: %v", err)
+		}
+		am.SetEncryptionKey([]byte(fmt.Sprintf(testDB+"%d", i)))
+		if err = am.InitKeys(); err != nil {
+			t.Fatalf(err.Error())
+		}
+		tr.nodes = append(tr.nodes, newNode(t, i, fmt.Sprintf("Participant#%d", i), am))
+	}
+
+	runDKG(t, tr, threshold, nil)
+
+	// the hot node verifies partial signs with the public polynomial built from the broadcasted commits
+	commits := make([][]kyber.Point, len(tr.nodes))
+	for _, req := range tr.nodes[0].commits {
+		participantCommits, err := dkg.UnmarshalCommits(tr.nodes[0].Machine.baseSuite, req.Commit)
+		require.NoError(t, err)
+		commits[req.ParticipantId] = participantCommits
+	}
+	pubPoly, err := dkg.PubPolyFromCommits(tr.nodes[0].Machine.baseSuite, commits)
+	require.NoError(t, err)
+	keyring, err := tr.nodes[0].Machine.loadBLSKeyring(DKGIdentifier)
+	require.NoError(t, err)
+	require.True(t, pubPoly.Equal(keyring.PubPoly))
+
+	msgToSign := []byte("i am a message")
+	partialSignsOp := createOperation(t, string(signing_proposal_fsm.StateSigningAwaitPartialSigns), "",
+		responses.SigningPartialSignsParticipantInvitationsResponse{SrcPayload: msgToSign})
+
+	// every node makes a partial sign, but the first one broadcasts a partial sign of the second node
+	partialSigns := make(map[int][]byte)
+	for _, n := range tr.nodes {
+		operation, err := n.Machine.HandleOperation(n.signOperation(t, partialSignsOp))
+		require.NoError(t, err)
+		require.Len(t, operation.ResultMsgs, 1)
+		var req requests.SigningProposalPartialSignRequest
+		require.NoError(t, json.Unmarshal(operation.ResultMsgs[0].Data, &req))
+		partialSigns[n.ParticipantID] = req.PartialSign
+	}
+
+	signAndBroadcast := func(invalid map[int][]byte) []storage.Message {
+		var messages []storage.Message
+		for _, n := range tr.nodes {
+			partialSign := partialSigns[n.ParticipantID]
+			if invalidSign, ok := invalid[n.ParticipantID]; ok {
+				partialSign = invalidSign
+			}
+			reqBz, err := json.Marshal(requests.SigningProposalPartialSignRequest{
+				ParticipantId: n.ParticipantID,
+				PartialSign:   partialSign,
+				CreatedAt:     time.Now(),
+			})
+			require.NoError(t, err)
+			messages = append(messages, n.signMessage(storage.Message{
+				Event:      string(signing_proposal_fsm.EventSigningPartialSignReceived),
+				Data:       reqBz,
+				DkgRoundID: DKGIdentifier,
+			}))
+		}
+		return messages
+	}
+
+	reconstruct := func(n *Node, messages []storage.Message) (client.Operation, error) {
+		var payload responses.SigningProcessParticipantResponse
+		for _, msg := range messages {
+			var req requests.SigningProposalPartialSignRequest
+			require.NoError(t, json.Unmarshal(msg.Data, &req))
+			payload.Participants = append(payload.Participants, &responses.SigningProcessParticipantEntry{
+				ParticipantId: req.ParticipantId,
+				Username:      fmt.Sprintf("Participant#%d", req.ParticipantId),
+				PartialSign:   req.PartialSign,
+			})
+		}
+		payload.SrcPayload = msgToSign
+		o := createOperation(t, string(signing_proposal_fsm.StateSigningPartialSignsCollected), "", payload)
+		o.SourceMessages = append(append([]storage.Message{}, n.boardMessages...), messages...)
+		require.NoError(t, o.Sign(n.hotPrivKey))
+		return n.Machine.HandleOperation(o)
+	}
+
+	// one invalid partial sign is excluded and the signature is reconstructed from the valid ones
+	messages := signAndBroadcast(map[int][]byte{0: partialSigns[1]})
+	for _, n := range tr.nodes {
+		operation, err := reconstruct(n, messages)
+		require.NoError(t, err)
+		require.Len(t, operation.ResultMsgs, 1)
+		require.Equal(t, string(client.SignatureReconstructed), operation.ResultMsgs[0].Event)
+		var signature client.ReconstructedSignature
+		require.NoError(t, json.Unmarshal(operation.ResultMsgs[0].Data, &signature))
+		require.NoError(t, n.Machine.VerifySign(msgToSign, signature.Signature, DKGIdentifier))
+	}
+
+	// there is no threshold of valid partial signs, the failure is reported to other participants
+	messages = signAndBroadcast(map[int][]byte{0: partialSigns[1], 2: partialSigns[1]})
+	operation, err := reconstruct(tr.nodes[1], messages)
+	require.NoError(t, err)
+	require.Len(t, operation.ResultMsgs, 1)
+	require.Equal(t, string(client.SignatureReconstructionFailed), operation.ResultMsgs[0].Event)
+	var reconstructionErr client.SignatureReconstructionError
+	require.NoError(t, json.Unmarshal(operation.ResultMsgs[0].Data, &reconstructionErr))
+	require.Equal(t, requests.ErrorCodeVerificationFailed, reconstructionErr.Error.Code)
+	require.NoError(t, operation.VerifyResultSignature(tr.nodes[1].Machine.GetIdentityPubKey()))
+}
+
+func TestAirgappedMachine_AuditLog(t *testing.T) {
+	testDir := "/tmp/airgapped_test_audit"
+	defer os.RemoveAll(testDir)
+
+	am, err := NewMachine(fmt.Sprintf("%s/%s", testDir, testDB))
+	require.NoError(t, err)
+	am.SetEncryptionKey([]byte(testDB))
+	require.NoError(t, am.InitKeys())
+	auditLog, err := OpenAuditLog(testDir + "/audit.log")
+	require.NoError(t, err)
+	am.SetAuditLog(auditLog)
+	n := newNode(t, 0, "Participant#0", am)
+
+	pubKey, err := am.pubKey.MarshalBinary()
+	require.NoError(t, err)
+	initOp := createOperation(t, string(signature_proposal_fsm.StateAwaitParticipantsConfirmations), "",
+		responses.SignatureProposalParticipantInvitationsResponse{{
+			ParticipantId: n.ParticipantID,
+			Username:      n.Participant,
+			Threshold:     1,
+			DkgPubKey:     pubKey,
+			PubKey:        n.hotPrivKey.Public().(ed25519.PublicKey),
+		}})
+
+	// an unsigned operation is rejected
+	_, err = am.HandleOperation(initOp)
+	require.Error(t, err)
+	_, err = am.HandleOperation(n.signOperation(t, initOp))
+	require.NoError(t, err)
+	// an invalid payload is handled with an error sent to the FSM
+	commitsOp := createOperation(t, string(dkg_proposal_fsm.StateDkgCommitsAwaitConfirmations), "", nil)
+	commitsOp.Payload = []byte("{}")
+	_, err = am.HandleOperation(n.signOperation(t, commitsOp))
+	require.NoError(t, err)
+	require.NoError(t, auditLog.RecordCommand("show_dkg_pubkey", nil))
+	require.NoError(t, auditLog.Close())
+
+	f, err := os.Open(testDir + "/audit.log")
+	require.NoError(t, err)
+	defer f.Close()
+	var entries []AuditEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var entry AuditEntry
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &entry))
+		entries = append(entries, entry)
+	}
+	require.NoError(t, scanner.Err())
+	require.Len(t, entries, 4)
+
+	require.Equal(t, AuditKindOperation, entries[0].Kind)
+	require.Equal(t, initOp.ID, entries[0].OperationID)
+	require.Equal(t, AuditResultFailed, entries[0].Result)
+	require.NotEmpty(t, entries[0].Error)
+
+	require.Equal(t, initOp.ID, entries[1].OperationID)
+	require.Equal(t, DKGIdentifier, entries[1].DKGRoundID)
+	require.Equal(t, string(initOp.Type), entries[1].OperationType)
+	require.Equal(t, AuditResultOK, entries[1].Result)
+
+	require.Equal(t, commitsOp.ID, entries[2].OperationID)
+	require.Equal(t, AuditResultHandlerError, entries[2].Result)
+
+	require.Equal(t, AuditEntry{Kind: AuditKindCommand, Command: "show_dkg_pubkey", Result: AuditResultOK, Time: entries[3].Time}, entries[3])
+}
@@ -0,0 +1,107 @@
+package airgapped
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	client "github.com/lidofinance/dc4bc/client/types"
+)
+
+const (
+	AuditKindCommand   = "command"
+	AuditKindOperation = "operation"
+
+	// AuditResultOK means that the command or the operation succeeded
+	AuditResultOK = "ok"
+	// AuditResultHandlerError means that the operation was handled with an error, the error is sent to the FSM in the result
+	AuditResultHandlerError = "handler_error"
+	// AuditResultFailed means that the command failed or the operation was rejected without a result
+	AuditResultFailed = "failed"
+)
+
+// AuditEntry is a line of the audit log
+type AuditEntry struct {
+	Time          time.Time `json:"time"`
+	Kind          string    `json:"kind"`
+	Command       string    `json:"command,omitempty"`
+	OperationID   string    `json:"operation_id,omitempty"`
+	OperationType string    `json:"operation_type,omitempty"`
+	DKGRoundID    string    `json:"dkg_round_id,omitempty"`
+	Result        string    `json:"result"`
+	Error         string    `json:"error,omitempty"`
+}
+
+// AuditLog appends a JSON line for every command and operation handled by the machine. Arguments of commands
+// are not written, so passwords and mnemonics never get into the log. A nil AuditLog writes nothing.
+type AuditLog struct {
+	sync.Mutex
+	f *os.File
+}
+
+// OpenAuditLog opens the audit log file for appending, the file is created if it does not exist
+func OpenAuditLog(path string) (*AuditLog, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	return &AuditLog{f: f}, nil
+}
+
+func (a *AuditLog) Close() error {
+	if a == nil {
+		return nil
+	}
+	return a.f.Close()
+}
+
+// RecordCommand writes an entry of a prompt command
+func (a *AuditLog) RecordCommand(command string, err error) error {
+	entry := AuditEntry{Kind: AuditKindCommand, Command: command, Result: AuditResultOK}
+	if err != nil {
+		entry.Result = AuditResultFailed
+		entry.Error = err.Error()
+	}
+	return a.write(entry)
+}
+
+// recordOperation writes an entry of an operation, handlerErr is the error written to the operation result
+func (a *AuditLog) recordOperation(operation client.Operation, handlerErr, err error) error {
+	entry := AuditEntry{
+		Kind:          AuditKindOperation,
+		OperationID:   operation.ID,
+		OperationType: string(operation.Type),
+		DKGRoundID:    operation.DKGIdentifier,
+		Result:        AuditResultOK,
+	}
+	switch {
+	case err != nil:
+		entry.Result = AuditResultFailed
+		entry.Error = err.Error()
+	case handlerErr != nil:
+		entry.Result = AuditResultHandlerError
+		entry.Error = handlerErr.Error()
+	}
+	return a.write(entry)
+}
+
+func (a *AuditLog) write(entry AuditEntry) error {
+	if a == nil {
+		return nil
+	}
+	entry.Time = time.Now().UTC()
+	bz, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit entry: %w", err)
+	}
+
+	a.Lock()
+	defer a.Unlock()
+	if _, err = a.f.Write(append(bz, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit entry: %w", err)
+	}
+	// the entry must survive the machine being switched off right after the operation
+	return a.f.Sync()
+}
@@ -0,0 +1,177 @@
+package airgapped
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	client "github.com/lidofinance/dc4bc/client/types"
+	"github.com/lidofinance/dc4bc/fsm/fsm"
+	"github.com/lidofinance/dc4bc/fsm/types/requests"
+	"github.com/lidofinance/dc4bc/fsm/types/responses"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+const (
+	hotPubKeyDBKey           = "hot_public_key"
+	participantsHotKeysDBKey = "participants_hot_keys"
+	pinnedHotKeysDBKey       = "pinned_hot_keys"
+)
+
+var ErrHotPubKeyNotSet = errors.New("hot node public key is not set")
+
+func makeParticipantsHotKeysDBKey(dkgID string) string {
+	return fmt.Sprintf("%s_%s", participantsHotKeysDBKey, dkgID)
+}
+
+// SetHotPubKey pins the public key of our hot node. Only operations signed with the corresponding
+// private key will be handled by the machine.
+func (am *Machine) SetHotPubKey(pubKey ed25519.PublicKey) error {
+	if len(pubKey) != ed25519.PublicKeySize {
+		return fmt.Errorf("invalid hot node public key size: %d", len(pubKey))
+	}
+	if err := am.db.Put([]byte(hotPubKeyDBKey), pubKey, nil); err != nil {
+		return fmt.Errorf("failed to put hot node public key: %w", err)
+	}
+	return nil
+}
+
+// GetHotPubKey returns the pinned public key of our hot node
+func (am *Machine) GetHotPubKey() (ed25519.PublicKey, error) {
+	pubKey, err := am.db.Get([]byte(hotPubKeyDBKey), nil)
+	if err != nil {
+		if err == leveldb.ErrNotFound {
+			return nil, ErrHotPubKeyNotSet
+		}
+		return nil, fmt.Errorf("failed to get hot node public key: %w", err)
+	}
+	return pubKey, nil
+}
+
+// verifyOperation checks that the operation was signed by our hot node
+func (am *Machine) verifyOperation(o client.Operation) error {
+	hotPubKey, err := am.GetHotPubKey()
+	if err != nil {
+		return err
+	}
+	return o.VerifySignature(hotPubKey)
+}
+
+// PinParticipantHotKey pins the hot node public key of another participant, the key is exchanged out of band.
+// A DKG round which lists the participant with a different key is refused.
+func (am *Machine) PinParticipantHotKey(username string, pubKey ed25519.PublicKey) error {
+	if len(pubKey) != ed25519.PublicKeySize {
+		return fmt.Errorf("invalid hot node public key size: %d", len(pubKey))
+	}
+	pinnedKeys, err := am.loadPinnedHotKeys()
+	if err != nil {
+		return err
+	}
+	pinnedKeys[username] = pubKey
+	pinnedKeysBz, err := json.Marshal(pinnedKeys)
+	if err != nil {
+		return fmt.Errorf("failed to marshal pinned hot keys: %w", err)
+	}
+	if err = am.db.Put([]byte(pinnedHotKeysDBKey), pinnedKeysBz, nil); err != nil {
+		return fmt.Errorf("failed to put pinned hot keys: %w", err)
+	}
+	return nil
+}
+
+func (am *Machine) loadPinnedHotKeys() (map[string]ed25519.PublicKey, error) {
+	pinnedKeys := make(map[string]ed25519.PublicKey)
+	pinnedKeysBz, err := am.db.Get([]byte(pinnedHotKeysDBKey), nil)
+	if err != nil {
+		if err == leveldb.ErrNotFound {
+			return pinnedKeys, nil
+		}
+		return nil, fmt.Errorf("failed to get pinned hot keys: %w", err)
+	}
+	if err = json.Unmarshal(pinnedKeysBz, &pinnedKeys); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal pinned hot keys: %w", err)
+	}
+	return pinnedKeys, nil
+}
+
+// storeParticipantsHotKeys saves hot node public keys of the DKG round participants,
+// they are used to check bulletin board messages on the next steps.
+// The keys come with the operation signed by our hot node, so a compromised hot node can substitute
+// the keys of other participants. Only the keys pinned with PinParticipantHotKey are checked.
+func (am *Machine) storeParticipantsHotKeys(dkgID string, payload responses.SignatureProposalParticipantInvitationsResponse) error {
+	pinnedKeys, err := am.loadPinnedHotKeys()
+	if err != nil {
+		return err
+	}
+	hotKeys := make(map[string]ed25519.PublicKey, len(payload))
+	for _, entry := range payload {
+		if len(entry.PubKey) != ed25519.PublicKeySize {
+			return fmt.Errorf("invalid hot node public key of participant %s", entry.Username)
+		}
+		if pinnedKey, ok := pinnedKeys[entry.Username]; ok && !bytes.Equal(pinnedKey, entry.PubKey) {
+			return fmt.Errorf("hot node public key of participant %s does not match the pinned key", entry.Username)
+		}
+		hotKeys[entry.Username] = entry.PubKey
+	}
+	hotKeysBz, err := json.Marshal(hotKeys)
+	if err != nil {
+		return fmt.Errorf("failed to marshal participants hot keys: %w", err)
+	}
+	if err = am.db.Put([]byte(makeParticipantsHotKeysDBKey(dkgID)), hotKeysBz, nil); err != nil {
+		return fmt.Errorf("failed to put participants hot keys: %w", err)
+	}
+	return nil
+}
+
+func (am *Machine) loadParticipantsHotKeys(dkgID string) (map[string]ed25519.PublicKey, error) {
+	hotKeysBz, err := am.db.Get([]byte(makeParticipantsHotKeysDBKey(dkgID)), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get participants hot keys for dkg %s: %w", dkgID, err)
+	}
+	var hotKeys map[string]ed25519.PublicKey
+	if err = json.Unmarshal(hotKeysBz, &hotKeys); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal participants hot keys: %w", err)
+	}
+	return hotKeys, nil
+}
+
+// checkSourceMessage checks that the operation carries a bulletin board message with the given event
+// from the given participant, which is signed with the participant's hot key and matches the payload entry.
+// Operations of the operations log are not checked on replay, see Machine.replaying
+func (am *Machine) checkSourceMessage(o *client.Operation, username string, event fsm.Event,
+	match func(data []byte) (bool, error)) error {
+	if am.replaying {
+		return nil
+	}
+	hotKeys, err := am.loadParticipantsHotKeys(o.DKGIdentifier)
+	if err != nil {
+		return err
+	}
+	hotKey, ok := hotKeys[username]
+	if !ok {
+		return requests.NewProtocolError(requests.ErrorCodeInvalidMessage, username,
+			fmt.Sprintf("unknown participant %s", username))
+	}
+
+	for _, message := range o.SourceMessages {
+		if message.SenderAddr != username || message.DkgRoundID != o.DKGIdentifier || fsm.Event(message.Event) != event {
+			continue
+		}
+		matched, err := match(message.Data)
+		if err != nil {
+			return requests.NewProtocolError(requests.ErrorCodeInvalidMessage, username,
+				fmt.Sprintf("failed to match message from %s: %v", username, err))
+		}
+		if !matched {
+			continue
+		}
+		if !message.Verify(hotKey) {
+			return requests.NewProtocolError(requests.ErrorCodeInvalidMessage, username,
+				fmt.Sprintf("signature of message %s from %s is corrupt", event, username))
+		}
+		return nil
+	}
+	return requests.NewProtocolError(requests.ErrorCodeInvalidMessage, username,
+		fmt.Sprintf("signed message %s from %s not found", event, username))
+}
@@ -0,0 +1,519 @@
+package airgapped
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	bls12381 "github.com/corestario/kyber/pairing/bls12381"
+	"github.com/lidofinance/dc4bc/dkg"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/storage"
+	"github.com/syndtr/goleveldb/leveldb/util"
+	"github.com/tyler-smith/go-bip39"
+)
+
+// GetBaseSeedMnemonic returns the base seed encoded as a BIP-39 mnemonic. The mnemonic together with
+// the operations logs is enough to restore all keys of the machine.
+func (am *Machine) GetBaseSeedMnemonic() (string, error) {
+	mnemonic, err := bip39.NewMnemonic(am.baseSeed)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode base seed: %w", err)
+	}
+	return mnemonic, nil
+}
+
+// GetOperationsLogs returns operations logs of all DKG rounds
+func (am *Machine) GetOperationsLogs() (RoundOperationLog, error) {
+	roundOperationsLog, err := am.getRoundOperationLog()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get operations log: %w", err)
+	}
+	return roundOperationsLog, nil
+}
+
+// RestoreFromMnemonic restores the base seed from a BIP-39 mnemonic, regenerates the keys and rebuilds
+// BLS keyrings by replaying the given operations logs. Since the outbound messages are deterministic for
+// the same seed and the same inbound messages, the machine ends up in the same state as the original one.
+// If operationsLogs is nil, the operations logs stored in the machine are replayed. The logs are replayed
+// into a scratch in-memory state which is saved in one transaction, so the machine is not changed if the replay fails.
+func (am *Machine) RestoreFromMnemonic(mnemonic string, operationsLogs RoundOperationLog) error {
+	seed, err := bip39.EntropyFromMnemonic(mnemonic)
+	if err != nil {
+		return fmt.Errorf("failed to decode mnemonic: %w", err)
+	}
+	if len(seed) != seedSize {
+		return fmt.Errorf("invalid seed size: %d", len(seed))
+	}
+
+	iter := am.db.NewIterator(util.BytesPrefix([]byte(blsKeyringPrefix)), nil)
+	hasKeyrings := iter.Next()
+	iter.Release()
+	if hasKeyrings {
+		return errors.New("the machine already has finished DKG rounds, restore must be done on a clean machine")
+	}
+
+	if operationsLogs == nil {
+		if operationsLogs, err = am.getRoundOperationLog(); err != nil {
+			return fmt.Errorf("failed to get operations log: %w", err)
+		}
+	}
+
+	scratch, err := am.newScratchMachine(seed)
+	if err != nil {
+		return fmt.Errorf("failed to init scratch state: %w", err)
+	}
+	defer scratch.db.Close()
+	scratch.replaying = true
+
+	dkgIdentifiers := make([]string, 0, len(operationsLogs))
+	for dkgIdentifier := range operationsLogs {
+		dkgIdentifiers = append(dkgIdentifiers, dkgIdentifier)
+	}
+	sort.Strings(dkgIdentifiers)
+
+	for _, dkgIdentifier := range dkgIdentifiers {
+		for _, operation := range operationsLogs[dkgIdentifier] {
+			if _, err := scratch.HandleOperation(operation); err != nil {
+				return fmt.Errorf("failed to HandleOperation %s of DKG round %s: %w", operation.ID, dkgIdentifier, err)
+			}
+		}
+		log.Printf("Successfully replayed operations log of DKG round %s\n", dkgIdentifier)
+	}
+
+	if err = am.commitScratchMachine(scratch); err != nil {
+		return fmt.Errorf("failed to save restored state: %w", err)
+	}
+	return nil
+}
+
+// newScratchMachine creates a machine with the given seed, the password and the hot node key of the machine,
+// which keeps its data in memory
+func (am *Machine) newScratchMachine(seed []byte) (*Machine, error) {
+	db, err := leveldb.Open(storage.NewMemStorage(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open in-memory db: %w", err)
+	}
+	scratch := &Machine{
+		dkgInstances: make(map[string]*dkg.DKG),
+		kdf:          am.kdf,
+		baseSeed:     seed,
+		baseSuite:    bls12381.NewBLS12381Suite(seed),
+		db:           db,
+	}
+	scratch.SetEncryptionKey(am.encryptionKey)
+
+	if err = scratch.storeBaseSeed(seed); err != nil {
+		db.Close()
+		return nil, err
+	}
+	hotPubKey, err := am.GetHotPubKey()
+	if err == nil {
+		err = scratch.SetHotPubKey(hotPubKey)
+	} else if errors.Is(err, ErrHotPubKeyNotSet) {
+		err = nil
+	}
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	pinnedKeys, err := am.loadPinnedHotKeys()
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	for username, pubKey := range pinnedKeys {
+		if err = scratch.PinParticipantHotKey(username, pubKey); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	scratch.generateKeys()
+	if err = scratch.SaveKeysToDB(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to save keys: %w", err)
+	}
+	if err = scratch.putRoundOperationLog(RoundOperationLog{}); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to init operations log: %w", err)
+	}
+	return scratch, nil
+}
+
+// commitScratchMachine replaces the seed, the keys, the operations log and the state of DKG rounds of the machine
+// with the ones of the scratch machine in one transaction
+func (am *Machine) commitScratchMachine(scratch *Machine) error {
+	tx, err := am.db.OpenTransaction()
+	if err != nil {
+		return fmt.Errorf("failed to open transcation for db: %w", err)
+	}
+	defer tx.Discard()
+
+	iter := tx.NewIterator(util.BytesPrefix([]byte(dkgStatePrefix+"_")), nil)
+	for iter.Next() {
+		if err = tx.Delete(append([]byte{}, iter.Key()...), nil); err != nil {
+			iter.Release()
+			return fmt.Errorf("failed to delete dkg state: %w", err)
+		}
+	}
+	iter.Release()
+	if err = iter.Error(); err != nil {
+		return fmt.Errorf("failed to iterate over dkg states: %w", err)
+	}
+
+	// all encrypted entries of the machine are replaced, so the salt of the scratch machine is taken as well
+	iter = scratch.db.NewIterator(nil, nil)
+	for iter.Next() {
+		if err = tx.Put(append([]byte{}, iter.Key()...), append([]byte{}, iter.Value()...), nil); err != nil {
+			iter.Release()
+			return fmt.Errorf("failed to put %s into db: %w", iter.Key(), err)
+		}
+	}
+	iter.Release()
+	if err = iter.Error(); err != nil {
+		return fmt.Errorf("failed to iterate over restored state: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit tx for restored state: %w", err)
+	}
+
+	am.baseSeed = scratch.baseSeed
+	am.baseSuite = scratch.baseSuite
+	am.pubKey = scratch.pubKey
+	am.secKey = scratch.secKey
+	am.identityKey = scratch.identityKey
+	am.dkgInstances = scratch.dkgInstances
+	return nil
+}
+
+// keyringsArchiveVersion 2 archives carry the participants and the hot keys of the rounds, which are required to sign
+// with the keyrings, archives of version 1 do not have them and can not be imported
+const keyringsArchiveVersion = 2
+
+const (
+	archiveKindKeyrings       = "keyrings"
+	archiveKindOperationsLogs = "operations_logs"
+
+	operationsLogsArchiveVersion = 1
+)
+
+// Archive is a password-encrypted archive with data of the machine, e.g. finished DKG rounds.
+// The data is sealed with AES-GCM, so any modification of the archive is detected on decryption.
+type Archive struct {
+	Kind    string
+	Version int
+	Salt    []byte
+	Data    []byte
+}
+
+// ArchivedKeyring contains a BLS keyring of a finished DKG round along with its public metadata
+type ArchivedKeyring struct {
+	DKGIdentifier string
+	Threshold     int
+	Participants  []string
+	MasterPubKey  []byte
+	ShareIndex    int
+	Keyring       []byte
+	// DKGParticipants are the participants of the round encoded by dkg.MarshalParticipants
+	DKGParticipants []byte
+	// HotKeys are the hot node public keys of the participants, they are used to check bulletin board messages
+	HotKeys map[string]ed25519.PublicKey
+}
+
+type keyringsArchiveData struct {
+	CreatedAt      time.Time
+	Keyrings       []ArchivedKeyring
+	OperationsLogs RoundOperationLog
+}
+
+// ExportKeyrings returns an archive with all BLS keyrings, operations logs of the machine and
+// metadata of the DKG rounds encrypted with the given password
+func (am *Machine) ExportKeyrings(password []byte) ([]byte, error) {
+	keyrings, err := am.GetBLSKeyrings()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get BLS keyrings: %w", err)
+	}
+
+	operationsLogs, err := am.getRoundOperationLog()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get operations log: %w", err)
+	}
+
+	data := keyringsArchiveData{
+		CreatedAt:      time.Now().UTC(),
+		OperationsLogs: RoundOperationLog{},
+	}
+	for dkgID, keyring := range keyrings {
+		keyringBz, err := keyring.Bytes()
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode BLS keyring %s: %w", dkgID, err)
+		}
+		masterPubKey, err := keyring.PubPoly.Commit().MarshalBinary()
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal master public key %s: %w", dkgID, err)
+		}
+		dkgInstance, ok := am.dkgInstances[dkgID]
+		if !ok {
+			return nil, fmt.Errorf("dkg instance %s is not restored, replay its operations log first", dkgID)
+		}
+		dkgParticipants, err := dkgInstance.MarshalParticipants()
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal participants of %s: %w", dkgID, err)
+		}
+		// rounds finished before hot keys were introduced do not have them
+		var participants []string
+		hotKeys, err := am.loadParticipantsHotKeys(dkgID)
+		if err == nil {
+			for username := range hotKeys {
+				participants = append(participants, username)
+			}
+			sort.Strings(participants)
+		}
+		data.Keyrings = append(data.Keyrings, ArchivedKeyring{
+			DKGIdentifier:   dkgID,
+			Threshold:       keyring.PubPoly.Threshold(),
+			Participants:    participants,
+			MasterPubKey:    masterPubKey,
+			ShareIndex:      keyring.Share.I,
+			Keyring:         keyringBz,
+			DKGParticipants: dkgParticipants,
+			HotKeys:         hotKeys,
+		})
+		data.OperationsLogs[dkgID] = operationsLogs[dkgID]
+	}
+	sort.Slice(data.Keyrings, func(i, j int) bool {
+		return data.Keyrings[i].DKGIdentifier < data.Keyrings[j].DKGIdentifier
+	})
+
+	return sealKeyringsArchive(am.kdf, password, data)
+}
+
+// ImportKeyrings decrypts the archive made by ExportKeyrings, verifies every share against its public
+// polynomial and saves the keyrings, the participants and the hot keys of the rounds and the operations logs
+// in one transaction, so the imported rounds are ready for signing. Returns identifiers of the imported DKG rounds.
+func (am *Machine) ImportKeyrings(archiveBz, password []byte) ([]string, error) {
+	data, err := openKeyringsArchive(password, archiveBz)
+	if err != nil {
+		return nil, err
+	}
+
+	salt, err := am.db.Get([]byte(saltDBKey), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read salt from db: %w", err)
+	}
+	operationsLogs, err := am.getRoundOperationLog()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get operations log: %w", err)
+	}
+
+	tx, err := am.db.OpenTransaction()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open transcation for db: %w", err)
+	}
+	defer tx.Discard()
+
+	dkgInstances := make(map[string]*dkg.DKG, len(data.Keyrings))
+	imported := make([]string, 0, len(data.Keyrings))
+	for _, archived := range data.Keyrings {
+		dkgID := archived.DKGIdentifier
+		if _, err := tx.Get([]byte(makeBLSKeyKeyringDBKey(dkgID)), nil); err == nil {
+			return nil, fmt.Errorf("keyring %s already exists", dkgID)
+		}
+		keyring, err := am.verifyArchivedKeyring(archived)
+		if err != nil {
+			return nil, fmt.Errorf("invalid keyring %s: %w", dkgID, err)
+		}
+		dkgInstance, err := dkg.RestoreFromState(am.dkgSuite(dkgID), am.pubKey, am.secKey, nil, archived.DKGParticipants)
+		if err != nil {
+			return nil, fmt.Errorf("failed to restore participants of %s: %w", dkgID, err)
+		}
+		if dkgInstance.ParticipantID != archived.ShareIndex {
+			return nil, fmt.Errorf("participant id %d of %s does not match the share index %d",
+				dkgInstance.ParticipantID, dkgID, archived.ShareIndex)
+		}
+		operationsLog := data.OperationsLogs[dkgID]
+		if len(operationsLog) == 0 {
+			return nil, fmt.Errorf("operations log of %s is empty", dkgID)
+		}
+
+		keyringBz, err := keyring.Bytes()
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode bls keyring: %w", err)
+		}
+		encryptedKeyring, err := am.encrypt(salt, keyringBz)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt BLS keyring: %w", err)
+		}
+		if err = tx.Put([]byte(makeBLSKeyKeyringDBKey(dkgID)), encryptedKeyring, nil); err != nil {
+			return nil, fmt.Errorf("failed to put BLS keyring %s: %w", dkgID, err)
+		}
+
+		// the state is restored on startup only if the operation it was saved after is in the operations log
+		lastOperation := operationsLog[len(operationsLog)-1]
+		encryptedRecord, err := am.encryptDKGStateRecord(salt, dkgStateRecord{
+			LastOperationID:   lastOperation.ID,
+			LastOperationType: lastOperation.Type,
+			State:             archived.DKGParticipants,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err = tx.Put([]byte(makeDKGStateDBKey(dkgID)), encryptedRecord, nil); err != nil {
+			return nil, fmt.Errorf("failed to put dkg state %s: %w", dkgID, err)
+		}
+
+		if archived.HotKeys != nil {
+			hotKeysBz, err := json.Marshal(archived.HotKeys)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal participants hot keys: %w", err)
+			}
+			if err = tx.Put([]byte(makeParticipantsHotKeysDBKey(dkgID)), hotKeysBz, nil); err != nil {
+				return nil, fmt.Errorf("failed to put participants hot keys of %s: %w", dkgID, err)
+			}
+		}
+
+		operationsLogs[dkgID] = operationsLog
+		dkgInstances[dkgID] = dkgInstance
+		imported = append(imported, dkgID)
+	}
+
+	encryptedOperationsLog, err := am.encryptRoundOperationLog(salt, operationsLogs)
+	if err != nil {
+		return nil, err
+	}
+	if err = tx.Put([]byte(operationsLogDBKey), encryptedOperationsLog, nil); err != nil {
+		return nil, fmt.Errorf("failed to put operations log: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit tx for importing keyrings: %w", err)
+	}
+
+	for dkgID, dkgInstance := range dkgInstances {
+		am.dkgInstances[dkgID] = dkgInstance
+	}
+	return imported, nil
+}
+
+// verifyArchivedKeyring decodes the keyring and checks it against the archived metadata. The share
+// is accepted only if it matches the evaluation of the public polynomial at the share index.
+func (am *Machine) verifyArchivedKeyring(archived ArchivedKeyring) (*dkg.BLSKeyring, error) {
+	keyring, err := dkg.LoadBLSKeyringFromBytes(am.baseSuite, archived.Keyring)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode BLS keyring: %w", err)
+	}
+
+	masterPubKey := am.baseSuite.Point()
+	if err = masterPubKey.UnmarshalBinary(archived.MasterPubKey); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal master public key: %w", err)
+	}
+	if !keyring.PubPoly.Commit().Equal(masterPubKey) {
+		return nil, errors.New("master public key does not match the public polynomial")
+	}
+	if keyring.PubPoly.Threshold() != archived.Threshold {
+		return nil, fmt.Errorf("threshold %d does not match the public polynomial", archived.Threshold)
+	}
+	if keyring.Share.I != archived.ShareIndex {
+		return nil, fmt.Errorf("share index %d does not match the share", archived.ShareIndex)
+	}
+
+	if err = keyring.VerifyShare(); err != nil {
+		return nil, err
+	}
+	return keyring, nil
+}
+
+func sealKeyringsArchive(kdf KDF, password []byte, data keyringsArchiveData) ([]byte, error) {
+	return sealArchive(kdf, password, archiveKindKeyrings, keyringsArchiveVersion, data)
+}
+
+func openKeyringsArchive(password, archiveBz []byte) (*keyringsArchiveData, error) {
+	var data keyringsArchiveData
+	if err := openArchive(password, archiveBz, archiveKindKeyrings, keyringsArchiveVersion, &data); err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
+// ExportOperationsLogs returns the operations logs of all DKG rounds encrypted with the given password.
+// The logs contain the secret deals the machine received, so they must not be stored in plaintext.
+func (am *Machine) ExportOperationsLogs(password []byte) ([]byte, error) {
+	operationsLogs, err := am.getRoundOperationLog()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get operations log: %w", err)
+	}
+	return sealArchive(am.kdf, password, archiveKindOperationsLogs, operationsLogsArchiveVersion, operationsLogs)
+}
+
+// OpenOperationsLogsArchive decrypts the archive made by ExportOperationsLogs
+func OpenOperationsLogsArchive(archiveBz, password []byte) (RoundOperationLog, error) {
+	var operationsLogs RoundOperationLog
+	if err := openArchive(password, archiveBz, archiveKindOperationsLogs, operationsLogsArchiveVersion,
+		&operationsLogs); err != nil {
+		return nil, err
+	}
+	return operationsLogs, nil
+}
+
+// IsOperationsLogsArchive returns true if the data is an archive made by ExportOperationsLogs,
+// and not the plaintext operations logs exported by earlier versions
+func IsOperationsLogsArchive(data []byte) bool {
+	var archive Archive
+	return json.Unmarshal(data, &archive) == nil && archive.Kind == archiveKindOperationsLogs
+}
+
+func sealArchive(kdf KDF, password []byte, kind string, version int, data interface{}) ([]byte, error) {
+	dataBz, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal %s archive: %w", kind, err)
+	}
+
+	salt := make([]byte, 32)
+	if _, err = rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	encryptedData, err := encrypt(kdf, password, salt, dataBz)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt %s archive: %w", kind, err)
+	}
+
+	archiveBz, err := json.Marshal(Archive{
+		Kind:    kind,
+		Version: version,
+		Salt:    salt,
+		Data:    encryptedData,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal %s archive: %w", kind, err)
+	}
+	return archiveBz, nil
+}
+
+func openArchive(password, archiveBz []byte, kind string, version int, data interface{}) error {
+	var archive Archive
+	if err := json.Unmarshal(archiveBz, &archive); err != nil {
+		return fmt.Errorf("failed to unmarshal %s archive: %w", kind, err)
+	}
+	if archive.Kind != kind {
+		return fmt.Errorf("archive of kind %q is not a %s archive", archive.Kind, kind)
+	}
+	if archive.Version != version {
+		return fmt.Errorf("unsupported %s archive version: %d", kind, archive.Version)
+	}
+
+	dataBz, err := decrypt(password, archive.Salt, archive.Data)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt %s archive, wrong password or corrupted archive: %w", kind, err)
+	}
+
+	if err = json.Unmarshal(dataBz, data); err != nil {
+		return fmt.Errorf("failed to unmarshal %s archive data: %w", kind, err)
+	}
+	return nil
+}
@@ -1,13 +1,18 @@
 package airgapped
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"log"
+	"strings"
+
 	"github.com/corestario/kyber/pairing"
 
 	"github.com/corestario/kyber/sign/bls"
 	"github.com/corestario/kyber/sign/tbls"
 	client "github.com/lidofinance/dc4bc/client/types"
+	"github.com/lidofinance/dc4bc/dkg"
 	"github.com/lidofinance/dc4bc/fsm/state_machines/signing_proposal_fsm"
 	"github.com/lidofinance/dc4bc/fsm/types/requests"
 	"github.com/lidofinance/dc4bc/fsm/types/responses"
@@ -90,14 +95,49 @@ func (am *Machine) reconstructThresholdSignature(o *client.Operation) error {
 		return fmt.Errorf("failed to unmarshal payload: %w", err)
 	}
 
+	dkgInstance, ok := am.dkgInstances[o.DKGIdentifier]
+	if !ok {
+		return fmt.Errorf("dkg instance with identifier %s does not exist", o.DKGIdentifier)
+	}
+
+	blsKeyring, err := am.loadBLSKeyring(o.DKGIdentifier)
+	if err != nil {
+		return fmt.Errorf("failed to load blsKeyring: %w", err)
+	}
+
+	var culprits []string
 	partialSignatures := make([][]byte, 0, len(payload.Participants))
 	for _, participant := range payload.Participants {
+		err = am.checkSourceMessage(o, participant.Username, signing_proposal_fsm.EventSigningPartialSignReceived,
+			func(data []byte) (bool, error) {
+				var req requests.SigningProposalPartialSignRequest
+				if err := json.Unmarshal(data, &req); err != nil {
+					return false, err
+				}
+				return req.SigningId == payload.SigningId && req.ParticipantId == participant.ParticipantId &&
+					bytes.Equal(req.PartialSign, participant.PartialSign), nil
+			})
+		if err != nil {
+			return fmt.Errorf("failed to check partial signature: %w", err)
+		}
+
+		// invalid partial signatures are excluded, the signature is reconstructed from any threshold of valid ones
+		shareIndex, err := dkgInstance.GetIndexByParticipant(participant.Username)
+		if err != nil {
+			return fmt.Errorf("failed to get share index: %w", err)
+		}
+		if err = dkg.VerifyPartialSign(am.baseSuite.(pairing.Suite), blsKeyring.PubPoly, shareIndex, payload.SrcPayload,
+			participant.PartialSign); err != nil {
+			log.Printf("Invalid partial signature from %s in DKG round %s: %v\n", participant.Username, o.DKGIdentifier, err)
+			culprits = append(culprits, participant.Username)
+			continue
+		}
 		partialSignatures = append(partialSignatures, participant.PartialSign)
 	}
 
-	dkgInstance, ok := am.dkgInstances[o.DKGIdentifier]
-	if !ok {
-		return fmt.Errorf("dkg instance with identifier %s does not exist", o.DKGIdentifier)
+	if len(partialSignatures) < dkgInstance.Threshold {
+		return requests.NewProtocolError(requests.ErrorCodeVerificationFailed, strings.Join(culprits, ","),
+			fmt.Sprintf("not enough valid partial signatures: %d of %d", len(partialSignatures), dkgInstance.Threshold))
 	}
 
 	reconstructedSignature, err := am.recoverFullSign(payload.SrcPayload, partialSignatures, dkgInstance.Threshold,
@@ -1,6 +1,7 @@
 package airgapped
 
 import (
+	"bytes"
 	"crypto/sha256"
 	"encoding/json"
 	"fmt"
@@ -9,6 +10,7 @@ import (
 
 	"github.com/corestario/kyber"
 	dkgPedersen "github.com/corestario/kyber/share/dkg/pedersen"
+	vss "github.com/corestario/kyber/share/vss/rabin"
 	client "github.com/lidofinance/dc4bc/client/types"
 	"github.com/lidofinance/dc4bc/dkg"
 	"github.com/lidofinance/dc4bc/fsm/state_machines/dkg_proposal_fsm"
@@ -58,18 +60,15 @@ func (am *Machine) handleStateAwaitParticipantsConfirmations(o *client.Operation
 		return fmt.Errorf("failed to determine participant id for DKG #%s", o.DKGIdentifier)
 	}
 
+	if err = am.storeParticipantsHotKeys(o.DKGIdentifier, payload); err != nil {
+		return fmt.Errorf("failed to store participants hot keys: %w", err)
+	}
+
 	if _, ok := am.dkgInstances[o.DKGIdentifier]; ok {
 		return fmt.Errorf("dkg instance %s already exists", o.DKGIdentifier)
 	}
 
-	// Here we create a new seeded suite for the new DKG round with seed =
-	// sha256.Sum256(baseSeed + DKGIdentifier). We need this to avoid identical
-	// DKG rounds.
-	var (
-		dkgSeed = sha256.Sum256(append([]byte(o.DKGIdentifier), am.baseSeed...))
-		suite   = bls.NewBLS12381Suite(dkgSeed[:])
-	)
-	dkgInstance := dkg.Init(suite, am.pubKey, am.secKey)
+	dkgInstance := dkg.Init(am.dkgSuite(o.DKGIdentifier), am.pubKey, am.secKey)
 	dkgInstance.Threshold = payload[0].Threshold //same for everyone
 	dkgInstance.N = len(payload)
 	am.dkgInstances[o.DKGIdentifier] = dkgInstance
@@ -87,6 +86,13 @@ func (am *Machine) handleStateAwaitParticipantsConfirmations(o *client.Operation
 	return nil
 }
 
+// dkgSuite creates a new seeded suite for the DKG round with seed = sha256.Sum256(baseSeed + DKGIdentifier).
+// We need this to avoid identical DKG rounds.
+func (am *Machine) dkgSuite(dkgIdentifier string) vss.Suite {
+	dkgSeed := sha256.Sum256(append([]byte(dkgIdentifier), am.baseSeed...))
+	return bls.NewBLS12381Suite(dkgSeed[:])
+}
+
 func (am *Machine) GetPubKey() kyber.Point {
 	return am.pubKey
 }
@@ -170,6 +176,18 @@ func (am *Machine) handleStateDkgDealsAwaitConfirmations(o *client.Operation) er
 	}
 
 	for _, entry := range payload {
+		err = am.checkSourceMessage(o, entry.Username, dkg_proposal_fsm.EventDKGCommitConfirmationReceived,
+			func(data []byte) (bool, error) {
+				var req requests.DKGProposalCommitConfirmationRequest
+				if err := json.Unmarshal(data, &req); err != nil {
+					return false, err
+				}
+				return req.ParticipantId == entry.ParticipantId && bytes.Equal(req.Commit, entry.DkgCommit), nil
+			})
+		if err != nil {
+			return fmt.Errorf("failed to check commit: %w", err)
+		}
+
 		var commitsBz [][]byte
 		if err = json.Unmarshal(entry.DkgCommit, &commitsBz); err != nil {
 			return fmt.Errorf("failed to unmarshal commits: %w", err)
@@ -237,6 +255,18 @@ func (am *Machine) handleStateDkgResponsesAwaitConfirmations(o *client.Operation
 	}
 
 	for _, entry := range payload {
+		err = am.checkSourceMessage(o, entry.Username, dkg_proposal_fsm.EventDKGDealConfirmationReceived,
+			func(data []byte) (bool, error) {
+				var req requests.DKGProposalDealConfirmationRequest
+				if err := json.Unmarshal(data, &req); err != nil {
+					return false, err
+				}
+				return req.ParticipantId == entry.ParticipantId && bytes.Equal(req.Deal, entry.DkgDeal), nil
+			})
+		if err != nil {
+			return fmt.Errorf("failed to check deal: %w", err)
+		}
+
 		decryptedDealBz, err := am.decryptDataFromParticipant(entry.DkgDeal)
 		if err != nil {
 			return fmt.Errorf("failed to decrypt deal: %w", err)
@@ -250,7 +280,8 @@ func (am *Machine) handleStateDkgResponsesAwaitConfirmations(o *client.Operation
 
 	processedResponses, err := dkgInstance.ProcessDeals()
 	if err != nil {
-		return fmt.Errorf("failed to process deals: %w", err)
+		return requests.NewProtocolError(requests.ErrorCodeVerificationFailed, "",
+			fmt.Sprintf("failed to process deals: %v", err))
 	}
 
 	am.dkgInstances[o.DKGIdentifier] = dkgInstance
@@ -294,6 +325,18 @@ func (am *Machine) handleStateDkgMasterKeyAwaitConfirmations(o *client.Operation
 	}
 
 	for _, entry := range payload {
+		err = am.checkSourceMessage(o, entry.Username, dkg_proposal_fsm.EventDKGResponseConfirmationReceived,
+			func(data []byte) (bool, error) {
+				var req requests.DKGProposalResponseConfirmationRequest
+				if err := json.Unmarshal(data, &req); err != nil {
+					return false, err
+				}
+				return req.ParticipantId == entry.ParticipantId && bytes.Equal(req.Response, entry.DkgResponse), nil
+			})
+		if err != nil {
+			return fmt.Errorf("failed to check response: %w", err)
+		}
+
 		var entryResponses []*dkgPedersen.Response
 		if err = json.Unmarshal(entry.DkgResponse, &entryResponses); err != nil {
 			return fmt.Errorf("failed to unmarshal responses: %w", err)
@@ -302,7 +345,8 @@ func (am *Machine) handleStateDkgMasterKeyAwaitConfirmations(o *client.Operation
 	}
 
 	if err = dkgInstance.ProcessResponses(); err != nil {
-		return fmt.Errorf("failed to process responses: %w", err)
+		return requests.NewProtocolError(requests.ErrorCodeVerificationFailed, "",
+			fmt.Sprintf("failed to process responses: %v", err))
 	}
 
 	pubKey, err := dkgInstance.GetDistributedPublicKey()
@@ -0,0 +1,153 @@
+package airgapped
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	client "github.com/lidofinance/dc4bc/client/types"
+	"github.com/lidofinance/dc4bc/dkg"
+	"github.com/lidofinance/dc4bc/fsm/fsm"
+	"github.com/lidofinance/dc4bc/fsm/state_machines/dkg_proposal_fsm"
+	"github.com/lidofinance/dc4bc/fsm/state_machines/signature_proposal_fsm"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+const (
+	dkgStatePrefix = "dkg_state"
+)
+
+// dkgStateRecord is the state of a DKG round along with the operation after which the state was saved
+type dkgStateRecord struct {
+	LastOperationID   string
+	LastOperationType client.OperationType
+	State             []byte
+}
+
+func makeDKGStateDBKey(dkgID string) string {
+	return fmt.Sprintf("%s_%s", dkgStatePrefix, dkgID)
+}
+
+func isDKGOperation(o client.Operation) bool {
+	switch fsm.State(o.Type) {
+	case signature_proposal_fsm.StateAwaitParticipantsConfirmations,
+		dkg_proposal_fsm.StateDkgCommitsAwaitConfirmations,
+		dkg_proposal_fsm.StateDkgDealsAwaitConfirmations,
+		dkg_proposal_fsm.StateDkgResponsesAwaitConfirmations,
+		dkg_proposal_fsm.StateDkgMasterKeyAwaitConfirmations:
+		return true
+	}
+	return false
+}
+
+// saveDKGState saves the encrypted state of the operation's DKG round
+func (am *Machine) saveDKGState(o client.Operation) error {
+	dkgInstance, ok := am.dkgInstances[o.DKGIdentifier]
+	if !ok {
+		return fmt.Errorf("dkg instance with identifier %s does not exist", o.DKGIdentifier)
+	}
+
+	state, err := dkgInstance.MarshalState()
+	if err != nil {
+		return fmt.Errorf("failed to marshal dkg state: %w", err)
+	}
+
+	salt, err := am.db.Get([]byte(saltDBKey), nil)
+	if err != nil {
+		return fmt.Errorf("failed to read salt from db: %w", err)
+	}
+	encryptedRecord, err := am.encryptDKGStateRecord(salt, dkgStateRecord{
+		LastOperationID:   o.ID,
+		LastOperationType: o.Type,
+		State:             state,
+	})
+	if err != nil {
+		return err
+	}
+	if err = am.db.Put([]byte(makeDKGStateDBKey(o.DKGIdentifier)), encryptedRecord, nil); err != nil {
+		return fmt.Errorf("failed to put dkg state into db: %w", err)
+	}
+	return nil
+}
+
+func (am *Machine) encryptDKGStateRecord(salt []byte, record dkgStateRecord) ([]byte, error) {
+	recordBz, err := json.Marshal(record)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal dkg state record: %w", err)
+	}
+	encryptedRecord, err := am.encrypt(salt, recordBz)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt dkg state: %w", err)
+	}
+	return encryptedRecord, nil
+}
+
+// restoreDKGInstances restores DKG instances from the saved states. A round is restored only if its state
+// is consistent with the operations log, otherwise the round should be recovered with replay_operations_log
+func (am *Machine) restoreDKGInstances() {
+	salt, err := am.db.Get([]byte(saltDBKey), nil)
+	if err != nil {
+		log.Printf("failed to read salt from db: %v\n", err)
+		return
+	}
+
+	iter := am.db.NewIterator(util.BytesPrefix([]byte(dkgStatePrefix+"_")), nil)
+	defer iter.Release()
+
+	for iter.Next() {
+		dkgID := strings.TrimPrefix(string(iter.Key()), dkgStatePrefix+"_")
+		if _, ok := am.dkgInstances[dkgID]; ok {
+			continue
+		}
+		if err = am.restoreDKGInstance(dkgID, salt, iter.Value()); err != nil {
+			log.Printf("failed to restore DKG round %s: %v\n", dkgID, err)
+			continue
+		}
+		log.Printf("Successfully restored DKG round %s\n", dkgID)
+	}
+	if err = iter.Error(); err != nil {
+		log.Printf("failed to iterate over dkg states: %v\n", err)
+	}
+}
+
+func (am *Machine) restoreDKGInstance(dkgID string, salt, encryptedRecord []byte) error {
+	recordBz, err := am.decrypt(salt, encryptedRecord)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt dkg state: %w", err)
+	}
+	var record dkgStateRecord
+	if err = json.Unmarshal(recordBz, &record); err != nil {
+		return fmt.Errorf("failed to unmarshal dkg state record: %w", err)
+	}
+
+	if err = am.checkDKGStateConsistency(dkgID, record); err != nil {
+		return err
+	}
+
+	dkgInstance, err := dkg.RestoreFromState(am.dkgSuite(dkgID), am.pubKey, am.secKey, am.baseSeed, record.State)
+	if err != nil {
+		return fmt.Errorf("failed to restore dkg instance: %w", err)
+	}
+	am.dkgInstances[dkgID] = dkgInstance
+	return nil
+}
+
+// checkDKGStateConsistency checks that the operation the state was saved after is present in the operations log
+func (am *Machine) checkDKGStateConsistency(dkgID string, record dkgStateRecord) error {
+	operationsLog, err := am.getOperationsLog(dkgID)
+	if err != nil {
+		return fmt.Errorf("failed to get operations log: %w", err)
+	}
+	for _, operation := range operationsLog {
+		if operation.ID != record.LastOperationID {
+			continue
+		}
+		if operation.Type != record.LastOperationType {
+			return fmt.Errorf("operation %s in the operations log has type %s, but the state was saved after %s",
+				operation.ID, operation.Type, record.LastOperationType)
+		}
+		return nil
+	}
+	return fmt.Errorf("operation %s the state was saved after is not found in the operations log", record.LastOperationID)
+}
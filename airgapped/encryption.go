@@ -1,30 +1,241 @@
 package airgapped
 
 import (
+	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
+	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
 	"math"
 
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/scrypt"
 )
 
+// N is the scrypt cost parameter of ciphertexts without a header, they were produced before the KDF was stored
 var N = int(math.Pow(2, 16))
 
-func encrypt(key, salt, data []byte) ([]byte, error) {
-	derivedKey, err := scrypt.Key(key, salt, N, 8, 1, 32)
+const (
+	derivedKeyLen = 32
+
+	// ciphertextVersion is the version of the ciphertext header:
+	// magic | version | kdf type | params length (uint16) | params | salt length | salt | nonce | sealed data
+	ciphertextVersion byte = 1
+
+	// upper bounds of KDF parameters to not run out of memory or time on a corrupted header,
+	// scrypt uses 128*N*r bytes of memory and its work is proportional to N*r*p
+	maxScryptN         = 1 << 24
+	maxScryptR         = 32
+	maxScryptP         = 16
+	maxScryptMemory    = 1 << 30
+	maxScryptWork      = 1 << 26
+	maxArgon2idTime    = 64
+	maxArgon2idMemory  = 1024 * 1024 // in KiB
+	maxArgon2idThreads = 16
+	maxArgon2idWork    = 16 * 1024 * 1024 // time * memory
+)
+
+var ciphertextMagic = []byte("DC4E")
+
+type KDFType byte
+
+const (
+	KDFScrypt   KDFType = 1
+	KDFArgon2id KDFType = 2
+)
+
+// KDF derives an encryption key from the password. Parameters of the KDF are stored in the
+// header of every ciphertext, so they can be changed without breaking existing data.
+type KDF interface {
+	Type() KDFType
+	Params() []byte
+	DeriveKey(password, salt []byte) ([]byte, error)
+}
+
+type ScryptKDF struct {
+	N, R, P uint32
+}
+
+func DefaultScryptKDF() *ScryptKDF {
+	return &ScryptKDF{N: uint32(N), R: 8, P: 1}
+}
+
+func (k *ScryptKDF) Type() KDFType {
+	return KDFScrypt
+}
+
+func (k *ScryptKDF) Params() []byte {
+	params := make([]byte, 12)
+	binary.BigEndian.PutUint32(params[0:], k.N)
+	binary.BigEndian.PutUint32(params[4:], k.R)
+	binary.BigEndian.PutUint32(params[8:], k.P)
+	return params
+}
+
+func (k *ScryptKDF) DeriveKey(password, salt []byte) ([]byte, error) {
+	// parameters are read from a header which is not authenticated yet, so they are checked before use
+	n, r, p := uint64(k.N), uint64(k.R), uint64(k.P)
+	if r == 0 || r > maxScryptR || p == 0 || p > maxScryptP || n > maxScryptN ||
+		128*n*r > maxScryptMemory || n*r*p > maxScryptWork {
+		return nil, errors.New("invalid scrypt parameters")
+	}
+	return scrypt.Key(password, salt, int(k.N), int(k.R), int(k.P), derivedKeyLen)
+}
+
+type Argon2idKDF struct {
+	Time    uint32
+	Memory  uint32 // in KiB
+	Threads uint8
+}
+
+func DefaultArgon2idKDF() *Argon2idKDF {
+	return &Argon2idKDF{Time: 1, Memory: 64 * 1024, Threads: 4}
+}
+
+func (k *Argon2idKDF) Type() KDFType {
+	return KDFArgon2id
+}
+
+func (k *Argon2idKDF) Params() []byte {
+	params := make([]byte, 9)
+	binary.BigEndian.PutUint32(params[0:], k.Time)
+	binary.BigEndian.PutUint32(params[4:], k.Memory)
+	params[8] = k.Threads
+	return params
+}
+
+func (k *Argon2idKDF) DeriveKey(password, salt []byte) ([]byte, error) {
+	if k.Time == 0 || k.Time > maxArgon2idTime || k.Threads == 0 || k.Threads > maxArgon2idThreads ||
+		k.Memory > maxArgon2idMemory || uint64(k.Time)*uint64(k.Memory) > maxArgon2idWork {
+		return nil, errors.New("invalid argon2id parameters")
+	}
+	return argon2.IDKey(password, salt, k.Time, k.Memory, k.Threads, derivedKeyLen), nil
+}
+
+// NewKDF returns a KDF with default parameters by its name
+func NewKDF(name string) (KDF, error) {
+	switch name {
+	case "scrypt":
+		return DefaultScryptKDF(), nil
+	case "argon2id":
+		return DefaultArgon2idKDF(), nil
+	default:
+		return nil, fmt.Errorf("unknown kdf: %s", name)
+	}
+}
+
+func kdfFromParams(kdfType KDFType, params []byte) (KDF, error) {
+	switch kdfType {
+	case KDFScrypt:
+		if len(params) != 12 {
+			return nil, errors.New("invalid scrypt parameters")
+		}
+		return &ScryptKDF{
+			N: binary.BigEndian.Uint32(params[0:]),
+			R: binary.BigEndian.Uint32(params[4:]),
+			P: binary.BigEndian.Uint32(params[8:]),
+		}, nil
+	case KDFArgon2id:
+		if len(params) != 9 {
+			return nil, errors.New("invalid argon2id parameters")
+		}
+		return &Argon2idKDF{
+			Time:    binary.BigEndian.Uint32(params[0:]),
+			Memory:  binary.BigEndian.Uint32(params[4:]),
+			Threads: params[8],
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown kdf type: %d", kdfType)
+	}
+}
+
+func marshalCiphertextHeader(kdf KDF, salt []byte) ([]byte, error) {
+	params := kdf.Params()
+	if len(params) > math.MaxUint16 || len(salt) > math.MaxUint8 {
+		return nil, errors.New("kdf parameters or salt are too long")
+	}
+
+	header := bytes.NewBuffer(nil)
+	header.Write(ciphertextMagic)
+	header.WriteByte(ciphertextVersion)
+	header.WriteByte(byte(kdf.Type()))
+	paramsLen := make([]byte, 2)
+	binary.BigEndian.PutUint16(paramsLen, uint16(len(params)))
+	header.Write(paramsLen)
+	header.Write(params)
+	header.WriteByte(byte(len(salt)))
+	header.Write(salt)
+	return header.Bytes(), nil
+}
+
+// unmarshalCiphertextHeader parses the header and returns the KDF, the salt and the header length
+func unmarshalCiphertextHeader(data []byte) (KDF, []byte, int, error) {
+	offset := len(ciphertextMagic)
+	if len(data) < offset+4 {
+		return nil, nil, 0, errors.New("invalid ciphertext header")
+	}
+	if data[offset] != ciphertextVersion {
+		return nil, nil, 0, fmt.Errorf("unsupported ciphertext version: %d", data[offset])
+	}
+	kdfType := KDFType(data[offset+1])
+	paramsLen := int(binary.BigEndian.Uint16(data[offset+2:]))
+	offset += 4
+	if len(data) < offset+paramsLen+1 {
+		return nil, nil, 0, errors.New("invalid ciphertext header")
+	}
+	kdf, err := kdfFromParams(kdfType, data[offset:offset+paramsLen])
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	offset += paramsLen
+	saltLen := int(data[offset])
+	offset++
+	if len(data) < offset+saltLen {
+		return nil, nil, 0, errors.New("invalid ciphertext header")
+	}
+	salt := data[offset : offset+saltLen]
+	return kdf, salt, offset + saltLen, nil
+}
+
+// isLegacyCiphertext returns true for ciphertexts made before the header was introduced
+func isLegacyCiphertext(data []byte) bool {
+	return !bytes.HasPrefix(data, ciphertextMagic)
+}
+
+// keyDeriver derives an encryption key with the given KDF and salt
+type keyDeriver func(kdf KDF, salt []byte) ([]byte, error)
+
+func passwordKeyDeriver(password []byte) keyDeriver {
+	return func(kdf KDF, salt []byte) ([]byte, error) {
+		return kdf.DeriveKey(password, salt)
+	}
+}
+
+func encrypt(kdf KDF, key, salt, data []byte) ([]byte, error) {
+	return encryptWithDeriver(kdf, passwordKeyDeriver(key), salt, data)
+}
+
+// decrypt decrypts the data using the KDF and the salt from the ciphertext header,
+// the salt argument is used only for legacy ciphertexts without a header
+func decrypt(key, salt, data []byte) ([]byte, error) {
+	return decryptWithDeriver(passwordKeyDeriver(key), salt, data)
+}
+
+func encryptWithDeriver(kdf KDF, deriveKey keyDeriver, salt, data []byte) ([]byte, error) {
+	header, err := marshalCiphertextHeader(kdf, salt)
 	if err != nil {
 		return nil, err
 	}
 
-	c, err := aes.NewCipher(derivedKey)
+	derivedKey, err := deriveKey(kdf, salt)
 	if err != nil {
 		return nil, err
 	}
 
-	gcm, err := cipher.NewGCM(c)
+	gcm, err := newGCM(derivedKey)
 	if err != nil {
 		return nil, err
 	}
@@ -34,21 +245,33 @@ func encrypt(key, salt, data []byte) ([]byte, error) {
 		return nil, err
 	}
 
-	return gcm.Seal(nonce, nonce, data, nil), nil
+	// the header is authenticated, so the KDF parameters can not be changed
+	sealed := gcm.Seal(nonce, nonce, data, header)
+	return append(header, sealed...), nil
 }
 
-func decrypt(key, salt, data []byte) ([]byte, error) {
-	derivedKey, err := scrypt.Key(key, salt, N, 8, 1, 32)
-	if err != nil {
-		return nil, err
+func decryptWithDeriver(deriveKey keyDeriver, salt, data []byte) ([]byte, error) {
+	var (
+		kdf    KDF = DefaultScryptKDF()
+		header []byte
+	)
+	if !isLegacyCiphertext(data) {
+		var (
+			headerLen int
+			err       error
+		)
+		if kdf, salt, headerLen, err = unmarshalCiphertextHeader(data); err != nil {
+			return nil, err
+		}
+		header, data = data[:headerLen], data[headerLen:]
 	}
 
-	c, err := aes.NewCipher(derivedKey)
+	derivedKey, err := deriveKey(kdf, salt)
 	if err != nil {
 		return nil, err
 	}
 
-	gcm, err := cipher.NewGCM(c)
+	gcm, err := newGCM(derivedKey)
 	if err != nil {
 		return nil, err
 	}
@@ -59,10 +282,52 @@ func decrypt(key, salt, data []byte) ([]byte, error) {
 	}
 
 	nonce, ciphertext := data[:nonceSize], data[nonceSize:]
-	decryptedData, err := gcm.Open(nil, nonce, ciphertext, nil)
+	decryptedData, err := gcm.Open(nil, nonce, ciphertext, header)
 	if err != nil {
 		return nil, err
 	}
 
 	return decryptedData, nil
 }
+
+// cachedKeyDeriver derives keys from the password. The KDF is slow by design, so derived keys are cached
+// for every KDF and salt.
+func cachedKeyDeriver(password []byte) keyDeriver {
+	cache := make(map[string][]byte)
+	return func(kdf KDF, salt []byte) ([]byte, error) {
+		cacheKey := fmt.Sprintf("%d_%x_%x", kdf.Type(), kdf.Params(), salt)
+		if derivedKey, ok := cache[cacheKey]; ok {
+			return derivedKey, nil
+		}
+		derivedKey, err := kdf.DeriveKey(password, salt)
+		if err != nil {
+			return nil, err
+		}
+		cache[cacheKey] = derivedKey
+		return derivedKey, nil
+	}
+}
+
+// encrypt encrypts the data with the machine password
+func (am *Machine) encrypt(salt, data []byte) ([]byte, error) {
+	if am.deriveKey == nil {
+		return nil, errors.New("encryption key is not set")
+	}
+	return encryptWithDeriver(am.kdf, am.deriveKey, salt, data)
+}
+
+// decrypt decrypts the data with the machine password
+func (am *Machine) decrypt(salt, data []byte) ([]byte, error) {
+	if am.deriveKey == nil {
+		return nil, errors.New("encryption key is not set")
+	}
+	return decryptWithDeriver(am.deriveKey, salt, data)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	c, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(c)
+}
@@ -1,6 +1,7 @@
 package airgapped
 
 import (
+	"crypto/ed25519"
 	"crypto/rand"
 	"encoding/json"
 	"errors"
@@ -11,11 +12,13 @@ import (
 
 	client "github.com/lidofinance/dc4bc/client/types"
 	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
 )
 
 const (
 	pubKeyDBKey        = "public_key"
 	privateKeyDBKey    = "private_key"
+	identityKeyDBKey   = "identity_private_key"
 	saltDBKey          = "salt_key"
 	baseSeedKey        = "base_seed_key"
 	operationsLogDBKey = "operations_log"
@@ -23,6 +26,12 @@ const (
 
 type RoundOperationLog map[string][]client.Operation
 
+// encryptedEntries and encryptedEntryPrefixes are the db keys of all data encrypted with the machine password
+var (
+	encryptedEntries       = []string{pubKeyDBKey, privateKeyDBKey, identityKeyDBKey, operationsLogDBKey}
+	encryptedEntryPrefixes = []string{blsKeyringPrefix, dkgStatePrefix}
+)
+
 func (am *Machine) loadBaseSeed() error {
 	seed, err := am.getBaseSeed()
 	if errors.Is(err, leveldb.ErrNotFound) {
@@ -78,16 +87,7 @@ func (am *Machine) storeOperation(o client.Operation) error {
 	operationsLog = append(operationsLog, o)
 	roundOperationsLog[o.DKGIdentifier] = operationsLog
 
-	roundOperationsLogBz, err := json.Marshal(roundOperationsLog)
-	if err != nil {
-		return fmt.Errorf("failed to marshal operationsLog: %w", err)
-	}
-
-	if err := am.db.Put([]byte(operationsLogDBKey), roundOperationsLogBz, nil); err != nil {
-		return fmt.Errorf("failed to put updated operationsLog: %w", err)
-	}
-
-	return nil
+	return am.putRoundOperationLog(roundOperationsLog)
 }
 
 func (am *Machine) getOperationsLog(dkgIdentifier string) ([]client.Operation, error) {
@@ -117,24 +117,27 @@ func (am *Machine) dropRoundOperationLog(dkgIdentifier string) error {
 	}
 
 	roundOperationsLog[dkgIdentifier] = []client.Operation{}
-	roundOperationsLogBz, err := json.Marshal(roundOperationsLog)
-	if err != nil {
-		return fmt.Errorf("failed to marshal operationsLog: %w", err)
-	}
-
-	if err := am.db.Put([]byte(operationsLogDBKey), roundOperationsLogBz, nil); err != nil {
-		return fmt.Errorf("failed to put updated operationsLog: %w", err)
-	}
-
-	return nil
+	return am.putRoundOperationLog(roundOperationsLog)
 }
 
+// getRoundOperationLog reads and decrypts the operations log, logs stored before the encryption was
+// introduced are read as plaintext until they are migrated by encryptOperationsLogIfNeeded
 func (am *Machine) getRoundOperationLog() (RoundOperationLog, error) {
 	operationsLogBz, err := am.db.Get([]byte(operationsLogDBKey), nil)
 	if err != nil {
 		return nil, err
 	}
 
+	if !isPlaintextOperationsLog(operationsLogBz) {
+		salt, err := am.db.Get([]byte(saltDBKey), nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read salt from db: %w", err)
+		}
+		if operationsLogBz, err = am.decrypt(salt, operationsLogBz); err != nil {
+			return nil, fmt.Errorf("failed to decrypt operationsLog: %w", err)
+		}
+	}
+
 	var roundOperationsLog RoundOperationLog
 	if err := json.Unmarshal(operationsLogBz, &roundOperationsLog); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal stored operationsLog: %w", err)
@@ -143,6 +146,58 @@ func (am *Machine) getRoundOperationLog() (RoundOperationLog, error) {
 	return roundOperationsLog, nil
 }
 
+// putRoundOperationLog encrypts and saves the operations log
+func (am *Machine) putRoundOperationLog(roundOperationsLog RoundOperationLog) error {
+	salt, err := am.db.Get([]byte(saltDBKey), nil)
+	if err != nil {
+		return fmt.Errorf("failed to read salt from db: %w", err)
+	}
+	encryptedOperationsLog, err := am.encryptRoundOperationLog(salt, roundOperationsLog)
+	if err != nil {
+		return err
+	}
+
+	if err := am.db.Put([]byte(operationsLogDBKey), encryptedOperationsLog, nil); err != nil {
+		return fmt.Errorf("failed to put updated operationsLog: %w", err)
+	}
+	return nil
+}
+
+func (am *Machine) encryptRoundOperationLog(salt []byte, roundOperationsLog RoundOperationLog) ([]byte, error) {
+	roundOperationsLogBz, err := json.Marshal(roundOperationsLog)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal operationsLog: %w", err)
+	}
+	encryptedOperationsLog, err := am.encrypt(salt, roundOperationsLogBz)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt operationsLog: %w", err)
+	}
+	return encryptedOperationsLog, nil
+}
+
+// isPlaintextOperationsLog returns true for the operations log stored as a plain JSON,
+// as it is initialized before the password is entered and as it was stored before the encryption was introduced
+func isPlaintextOperationsLog(operationsLogBz []byte) bool {
+	return isLegacyCiphertext(operationsLogBz) && json.Valid(operationsLogBz)
+}
+
+// encryptOperationsLogIfNeeded encrypts the plaintext operations log
+func (am *Machine) encryptOperationsLogIfNeeded() error {
+	operationsLogBz, err := am.db.Get([]byte(operationsLogDBKey), nil)
+	if err != nil {
+		return fmt.Errorf("failed to get operationsLog: %w", err)
+	}
+	if !isPlaintextOperationsLog(operationsLogBz) {
+		return nil
+	}
+
+	roundOperationsLog, err := am.getRoundOperationLog()
+	if err != nil {
+		return err
+	}
+	return am.putRoundOperationLog(roundOperationsLog)
+}
+
 // LoadKeysFromDB load DKG keys from LevelDB
 func (am *Machine) LoadKeysFromDB() error {
 	pubKeyBz, err := am.db.Get([]byte(pubKeyDBKey), nil)
@@ -169,12 +224,12 @@ func (am *Machine) LoadKeysFromDB() error {
 		return fmt.Errorf("failed to read salt from db: %w", err)
 	}
 
-	decryptedPubKey, err := decrypt(am.encryptionKey, salt, pubKeyBz)
+	decryptedPubKey, err := am.decrypt(salt, pubKeyBz)
 	if err != nil {
 		return err
 	}
 
-	decryptedPrivateKey, err := decrypt(am.encryptionKey, salt, privateKeyBz)
+	decryptedPrivateKey, err := am.decrypt(salt, privateKeyBz)
 	if err != nil {
 		return err
 	}
@@ -188,6 +243,43 @@ func (am *Machine) LoadKeysFromDB() error {
 	if err = am.secKey.UnmarshalBinary(decryptedPrivateKey); err != nil {
 		return fmt.Errorf("failed to unmarshal private key: %w", err)
 	}
+
+	// machines initialized before the identity key was introduced do not have it yet
+	identityKeyBz, err := am.db.Get([]byte(identityKeyDBKey), nil)
+	if err != nil {
+		if err == leveldb.ErrNotFound {
+			am.identityKey = nil
+			return nil
+		}
+		return fmt.Errorf("failed to get identity key from db: %w", err)
+	}
+
+	decryptedIdentityKey, err := am.decrypt(salt, identityKeyBz)
+	if err != nil {
+		return err
+	}
+	if len(decryptedIdentityKey) != ed25519.PrivateKeySize {
+		return fmt.Errorf("invalid identity key size: %d", len(decryptedIdentityKey))
+	}
+	am.identityKey = decryptedIdentityKey
+	return nil
+}
+
+// saveIdentityKey saves the identity key to LevelDB using the already existing salt
+func (am *Machine) saveIdentityKey() error {
+	salt, err := am.db.Get([]byte(saltDBKey), nil)
+	if err != nil {
+		return fmt.Errorf("failed to read salt from db: %w", err)
+	}
+
+	encryptedIdentityKey, err := am.encrypt(salt, am.identityKey)
+	if err != nil {
+		return err
+	}
+
+	if err = am.db.Put([]byte(identityKeyDBKey), encryptedIdentityKey, nil); err != nil {
+		return fmt.Errorf("failed to put identity key into db: %w", err)
+	}
 	return nil
 }
 
@@ -207,11 +299,15 @@ func (am *Machine) SaveKeysToDB() error {
 		return fmt.Errorf("failed to generate salt: %w", err)
 	}
 
-	encryptedPubKey, err := encrypt(am.encryptionKey, salt, pubKeyBz)
+	encryptedPubKey, err := am.encrypt(salt, pubKeyBz)
+	if err != nil {
+		return err
+	}
+	encryptedPrivateKey, err := am.encrypt(salt, privateKeyBz)
 	if err != nil {
 		return err
 	}
-	encryptedPrivateKey, err := encrypt(am.encryptionKey, salt, privateKeyBz)
+	encryptedIdentityKey, err := am.encrypt(salt, am.identityKey)
 	if err != nil {
 		return err
 	}
@@ -230,6 +326,10 @@ func (am *Machine) SaveKeysToDB() error {
 		return fmt.Errorf("failed to put private key into db: %w", err)
 	}
 
+	if err = tx.Put([]byte(identityKeyDBKey), encryptedIdentityKey, nil); err != nil {
+		return fmt.Errorf("failed to put identity key into db: %w", err)
+	}
+
 	if err = tx.Put([]byte(saltDBKey), salt, nil); err != nil {
 		return fmt.Errorf("failed to put salt into db: %w", err)
 	}
@@ -240,3 +340,105 @@ func (am *Machine) SaveKeysToDB() error {
 
 	return nil
 }
+
+// ChangePassword checks the old password and re-encrypts all the data of the machine with the new password
+// and a new salt. If any entry can not be re-encrypted, nothing is changed.
+func (am *Machine) ChangePassword(oldPassword, newPassword []byte) error {
+	if len(newPassword) == 0 {
+		return errors.New("new password cannot be empty")
+	}
+
+	salt, err := am.db.Get([]byte(saltDBKey), nil)
+	if err != nil {
+		return fmt.Errorf("failed to read salt from db: %w", err)
+	}
+	privateKeyBz, err := am.db.Get([]byte(privateKeyDBKey), nil)
+	if err != nil {
+		return fmt.Errorf("failed to get private key from db: %w", err)
+	}
+	if _, err = decrypt(oldPassword, salt, privateKeyBz); err != nil {
+		return errors.New("old password is incorrect")
+	}
+
+	currentEncryptionKey := am.encryptionKey
+	am.SetEncryptionKey(oldPassword)
+	if _, err = am.reEncrypt(newPassword); err != nil {
+		am.SetEncryptionKey(currentEncryptionKey)
+		return err
+	}
+	return nil
+}
+
+// ReEncrypt re-encrypts all the data of the machine with the current KDF and a new salt.
+// Returns the number of re-encrypted entries.
+func (am *Machine) ReEncrypt() (int, error) {
+	return am.reEncrypt(am.encryptionKey)
+}
+
+// reEncrypt decrypts all the encrypted entries with the current password and encrypts them with the new one
+// and a new salt. Entries are written in a single transaction, so nothing is changed if any entry fails.
+func (am *Machine) reEncrypt(newEncryptionKey []byte) (int, error) {
+	salt, err := am.db.Get([]byte(saltDBKey), nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read salt from db: %w", err)
+	}
+
+	newSalt := make([]byte, 32)
+	if _, err := rand.Read(newSalt); err != nil {
+		return 0, fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	tx, err := am.db.OpenTransaction()
+	if err != nil {
+		return 0, fmt.Errorf("failed to open transcation for db: %w", err)
+	}
+	defer tx.Discard()
+
+	entries := make(map[string][]byte)
+	for _, key := range encryptedEntries {
+		value, err := tx.Get([]byte(key), nil)
+		if err != nil {
+			if err == leveldb.ErrNotFound {
+				continue
+			}
+			return 0, fmt.Errorf("failed to get %s from db: %w", key, err)
+		}
+		entries[key] = value
+	}
+	for _, prefix := range encryptedEntryPrefixes {
+		iter := tx.NewIterator(util.BytesPrefix([]byte(prefix+"_")), nil)
+		for iter.Next() {
+			entries[string(iter.Key())] = append([]byte{}, iter.Value()...)
+		}
+		iter.Release()
+		if err = iter.Error(); err != nil {
+			return 0, fmt.Errorf("failed to iterate over %s entries: %w", prefix, err)
+		}
+	}
+
+	newKeyDeriver := cachedKeyDeriver(newEncryptionKey)
+	for key, value := range entries {
+		decryptedValue, err := am.decrypt(salt, value)
+		if err != nil {
+			return 0, fmt.Errorf("failed to decrypt %s: %w", key, err)
+		}
+		encryptedValue, err := encryptWithDeriver(am.kdf, newKeyDeriver, newSalt, decryptedValue)
+		if err != nil {
+			return 0, fmt.Errorf("failed to encrypt %s: %w", key, err)
+		}
+		if err = tx.Put([]byte(key), encryptedValue, nil); err != nil {
+			return 0, fmt.Errorf("failed to put %s into db: %w", key, err)
+		}
+	}
+
+	if err = tx.Put([]byte(saltDBKey), newSalt, nil); err != nil {
+		return 0, fmt.Errorf("failed to put salt into db: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit tx for re-encryption: %w", err)
+	}
+
+	am.SetEncryptionKey(newEncryptionKey)
+	return len(entries), nil
+}
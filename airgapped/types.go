@@ -27,7 +27,7 @@ func (am *Machine) saveBLSKeyring(dkgID string, blsKeyring *dkg.BLSKeyring) erro
 		return fmt.Errorf("failed to encode bls keyring: %w", err)
 	}
 
-	encryptedKeyring, err := encrypt(am.encryptionKey, salt, blsKeyringBz)
+	encryptedKeyring, err := am.encrypt(salt, blsKeyringBz)
 	if err != nil {
 		return fmt.Errorf("failed to encrypt BLS keyring: %w", err)
 	}
@@ -53,7 +53,7 @@ func (am *Machine) loadBLSKeyring(dkgID string) (*dkg.BLSKeyring, error) {
 		return nil, fmt.Errorf("failed to get bls keyring with dkg id %s: %w", dkgID, err)
 	}
 
-	decryptedKeyring, err := decrypt(am.encryptionKey, salt, blsKeyringBz)
+	decryptedKeyring, err := am.decrypt(salt, blsKeyringBz)
 	if err != nil {
 		return nil, fmt.Errorf("failed to decrypt BLS keyring: %w", err)
 	}
@@ -82,14 +82,49 @@ func (am *Machine) GetBLSKeyrings() (map[string]*dkg.BLSKeyring, error) {
 	for iter.Next() {
 		key := iter.Key()
 		value := iter.Value()
-		decryptedKeyring, err := decrypt(am.encryptionKey, salt, value)
+		decryptedKeyring, err := am.decrypt(salt, value)
 		if err != nil {
 			return nil, fmt.Errorf("failed to decrypt BLS keyring: %w", err)
 		}
 		if blsKeyring, err = dkg.LoadBLSKeyringFromBytes(am.baseSuite, decryptedKeyring); err != nil {
 			return nil, fmt.Errorf("failed to decode bls keyring: %w", err)
 		}
-		keyrings[strings.TrimLeft(string(key), blsKeyringPrefix)] = blsKeyring
+		keyrings[strings.TrimPrefix(string(key), blsKeyringPrefix+"_")] = blsKeyring
 	}
 	return keyrings, iter.Error()
 }
+
+// PublicShare is the public key share of the participant in a finished DKG round.
+// It is used to verify partial signatures of the participant.
+type PublicShare struct {
+	DKGIdentifier string
+	Index         int
+	PubShare      []byte
+	MasterPubKey  []byte
+}
+
+// VerifyShare checks the stored share of the DKG round against the public polynomial and returns the public share
+func (am *Machine) VerifyShare(dkgID string) (*PublicShare, error) {
+	keyring, err := am.loadBLSKeyring(dkgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load BLS keyring: %w", err)
+	}
+	if err = keyring.VerifyShare(); err != nil {
+		return nil, err
+	}
+
+	pubShare, err := keyring.PubShare().V.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal public share: %w", err)
+	}
+	masterPubKey, err := keyring.PubPoly.Commit().MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal master public key: %w", err)
+	}
+	return &PublicShare{
+		DKGIdentifier: dkgID,
+		Index:         keyring.Share.I,
+		PubShare:      pubShare,
+		MasterPubKey:  masterPubKey,
+	}, nil
+}
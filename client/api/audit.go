@@ -0,0 +1,140 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// AuditAction is a security-relevant action recorded in the audit journal of the node
+type AuditAction string
+
+const (
+	// AuditDKGStarted is recorded when the node proposes a DKG round
+	AuditDKGStarted AuditAction = "dkg_started"
+	// AuditSigningProposed is recorded when the node proposes to sign data
+	AuditSigningProposed AuditAction = "signing_proposed"
+	// AuditOperationCreated is recorded when an operation for the airgapped machine is created
+	AuditOperationCreated AuditAction = "operation_created"
+	// AuditOperationHandled is recorded when the result of an operation is broadcast to the append-only log
+	AuditOperationHandled AuditAction = "operation_handled"
+	// AuditSignatureBroadcast is recorded when a signature reconstructed by the node is broadcast
+	AuditSignatureBroadcast AuditAction = "signature_broadcast"
+	// AuditOffsetChanged is recorded when the offset of the append-only log is set through the API
+	AuditOffsetChanged AuditAction = "offset_changed"
+	// AuditSigningDeclined is recorded when the signing policy of the node declines a signing request
+	AuditSigningDeclined AuditAction = "signing_declined"
+	// AuditOperationApproved is recorded when an operation held by the signing policy is approved
+	AuditOperationApproved AuditAction = "operation_approved"
+)
+
+// AuditRecord is an entry of the audit journal. Every record contains the hash of the previous one and
+// is signed with the communication key of the node, so changed, removed or reordered records are detected.
+type AuditRecord struct {
+	Index     uint64      `json:"index"`
+	CreatedAt time.Time   `json:"created_at"`
+	Action    AuditAction `json:"action"`
+
+	DKGRoundID     string   `json:"dkg_round_id,omitempty"`
+	SigningID      string   `json:"signing_id,omitempty"`
+	OperationID    string   `json:"operation_id,omitempty"`
+	OperationType  string   `json:"operation_type,omitempty"`
+	MessageEvents  []string `json:"message_events,omitempty"`
+	PreviousOffset *uint64  `json:"previous_offset,omitempty"`
+	Offset         *uint64  `json:"offset,omitempty"`
+	Approver       string   `json:"approver,omitempty"`
+
+	PrevHash  []byte `json:"prev_hash"`
+	Hash      []byte `json:"hash"`
+	Signature []byte `json:"signature"`
+}
+
+// ComputeHash returns the SHA-256 hash of the JSON encoding of the record without the hash and the signature
+func (r AuditRecord) ComputeHash() ([]byte, error) {
+	r.Hash, r.Signature = nil, nil
+	bz, err := json.Marshal(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal audit record: %w", err)
+	}
+	hash := sha256.Sum256(bz)
+	return hash[:], nil
+}
+
+// VerifyAuditRecords checks hashes, signatures and the order of consecutive records of the journal.
+// The chain is checked from the first record of the journal, or from the first given record if the journal
+// is exported from the middle.
+func VerifyAuditRecords(records []AuditRecord, pubKey ed25519.PublicKey) error {
+	if len(pubKey) != ed25519.PublicKeySize {
+		return fmt.Errorf("invalid public key size: %d", len(pubKey))
+	}
+	for i, record := range records {
+		if i == 0 {
+			if record.Index == 0 && len(record.PrevHash) != 0 {
+				return errors.New("the first record of the journal refers to a previous record")
+			}
+		} else {
+			if record.Index != records[i-1].Index+1 {
+				return fmt.Errorf("record %d follows record %d", record.Index, records[i-1].Index)
+			}
+			if !bytes.Equal(record.PrevHash, records[i-1].Hash) {
+				return fmt.Errorf("record %d does not refer to the hash of the previous record", record.Index)
+			}
+		}
+		hash, err := record.ComputeHash()
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(hash, record.Hash) {
+			return fmt.Errorf("hash of record %d does not match its content", record.Index)
+		}
+		if !ed25519.Verify(pubKey, record.Hash, record.Signature) {
+			return fmt.Errorf("invalid signature of record %d", record.Index)
+		}
+	}
+	return nil
+}
+
+type AuditRecordsPage struct {
+	Items []AuditRecord `json:"items"`
+	PageInfo
+}
+
+// ListAuditRecords returns a page of the audit journal, the offset of the page is the index of its first record
+func (c *Client) ListAuditRecords(ctx context.Context, opts ListOptions) (*AuditRecordsPage, error) {
+	var page AuditRecordsPage
+	if err := c.do(ctx, http.MethodGet, "/v1/audit", opts.query(), nil, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// AuditRecords returns records of the audit journal with indices from the range [from, to), zero to
+// returns records till the end of the journal
+func (c *Client) AuditRecords(ctx context.Context, from, to uint64) ([]AuditRecord, error) {
+	var records []AuditRecord
+	for {
+		offset := from + uint64(len(records))
+		if to != 0 && offset >= to {
+			return records, nil
+		}
+		page, err := c.ListAuditRecords(ctx, ListOptions{Limit: MaxPageLimit, Offset: int(offset)})
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range page.Items {
+			if to != 0 && record.Index >= to {
+				return records, nil
+			}
+			records = append(records, record)
+		}
+		if len(page.Items) == 0 || int(from)+len(records) >= page.Total {
+			return records, nil
+		}
+	}
+}
@@ -0,0 +1,323 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/lidofinance/dc4bc/client/types"
+	"github.com/lidofinance/dc4bc/fsm/state_machines"
+	"github.com/lidofinance/dc4bc/storage"
+)
+
+// Client is a client of the node HTTP API
+type Client struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+// NewClient creates a client of the node listening on the base URL, e.g. http://localhost:8080
+func NewClient(baseURL string) *Client {
+	return &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: http.DefaultClient,
+	}
+}
+
+// SetToken sets the bearer token sent with every request
+func (c *Client) SetToken(token string) {
+	c.token = token
+}
+
+// SetHTTPClient sets the HTTP client used to send requests, e.g. to configure TLS
+func (c *Client) SetHTTPClient(httpClient *http.Client) {
+	c.httpClient = httpClient
+}
+
+// IsNotFound returns true if the node responded that the requested resource does not exist
+func IsNotFound(err error) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.Code == ErrorNotFound
+}
+
+// IsInvalidRequest returns true if the node refused to handle the request, so sending it again gives the same error
+func IsInvalidRequest(err error) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.Code == ErrorInvalidRequest
+}
+
+// do sends the request and decodes the result into the result argument, errors returned by the node are *Error
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, result interface{}) error {
+	var bodyReader io.Reader
+	if body != nil {
+		bodyBz, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		bodyReader = bytes.NewReader(bodyBz)
+	}
+
+	requestURL := c.baseURL + path
+	if len(query) > 0 {
+		requestURL += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, requestURL, bodyReader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	response := struct {
+		Result json.RawMessage `json:"result"`
+		Error  *Error          `json:"error"`
+	}{}
+	if err = json.Unmarshal(respBody, &response); err != nil {
+		return fmt.Errorf("failed to unmarshal response with status %d: %w", resp.StatusCode, err)
+	}
+	if response.Error != nil {
+		return response.Error
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return &Error{Code: ErrorInternal, Message: fmt.Sprintf("unexpected status %d", resp.StatusCode)}
+	}
+	if result == nil || len(response.Result) == 0 {
+		return nil
+	}
+	if err = json.Unmarshal(response.Result, result); err != nil {
+		return fmt.Errorf("failed to unmarshal result: %w", err)
+	}
+	return nil
+}
+
+func (opts ListOptions) query() url.Values {
+	query := url.Values{}
+	if opts.Limit > 0 {
+		query.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if opts.Offset > 0 {
+		query.Set("offset", strconv.Itoa(opts.Offset))
+	}
+	return query
+}
+
+// GetNode returns the username and the communication public key of the node
+func (c *Client) GetNode(ctx context.Context) (*NodeInfo, error) {
+	var node NodeInfo
+	if err := c.do(ctx, http.MethodGet, "/v1/node", nil, nil, &node); err != nil {
+		return nil, err
+	}
+	return &node, nil
+}
+
+// ListRounds returns a page of DKG rounds
+func (c *Client) ListRounds(ctx context.Context, opts ListOptions) (*RoundsPage, error) {
+	var page RoundsPage
+	if err := c.do(ctx, http.MethodGet, "/v1/rounds", opts.query(), nil, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// AllRounds returns all DKG rounds
+func (c *Client) AllRounds(ctx context.Context) ([]Round, error) {
+	var rounds []Round
+	for {
+		page, err := c.ListRounds(ctx, ListOptions{Limit: MaxPageLimit, Offset: len(rounds)})
+		if err != nil {
+			return nil, err
+		}
+		rounds = append(rounds, page.Items...)
+		if len(page.Items) == 0 || len(rounds) >= page.Total {
+			return rounds, nil
+		}
+	}
+}
+
+// StartRound sends the proposal to start a DKG round and returns the round ID
+func (c *Client) StartRound(ctx context.Context, proposal json.RawMessage) (string, error) {
+	var round Round
+	if err := c.do(ctx, http.MethodPost, "/v1/rounds", nil, proposal, &round); err != nil {
+		return "", err
+	}
+	return round.ID, nil
+}
+
+// GetRound returns the FSM dump of the DKG round
+func (c *Client) GetRound(ctx context.Context, roundID string) (*state_machines.FSMDump, error) {
+	var dump state_machines.FSMDump
+	if err := c.do(ctx, http.MethodGet, "/v1/rounds/"+url.PathEscape(roundID), nil, nil, &dump); err != nil {
+		return nil, err
+	}
+	return &dump, nil
+}
+
+// ListSignatures returns a page of signatures reconstructed in the DKG round
+func (c *Client) ListSignatures(ctx context.Context, roundID string, opts ListOptions) (*SigningsPage, error) {
+	var page SigningsPage
+	path := "/v1/rounds/" + url.PathEscape(roundID) + "/signatures"
+	if err := c.do(ctx, http.MethodGet, path, opts.query(), nil, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// AllSignatures returns all signatures reconstructed in the DKG round
+func (c *Client) AllSignatures(ctx context.Context, roundID string) ([]Signing, error) {
+	var signings []Signing
+	for {
+		page, err := c.ListSignatures(ctx, roundID, ListOptions{Limit: MaxPageLimit, Offset: len(signings)})
+		if err != nil {
+			return nil, err
+		}
+		signings = append(signings, page.Items...)
+		if len(page.Items) == 0 || len(signings) >= page.Total {
+			return signings, nil
+		}
+	}
+}
+
+// ProposeSigning sends the proposal to sign the data with the key of the DKG round and returns the signing ID
+func (c *Client) ProposeSigning(ctx context.Context, roundID string, data []byte) (string, error) {
+	var signing Signing
+	path := "/v1/rounds/" + url.PathEscape(roundID) + "/signatures"
+	if err := c.do(ctx, http.MethodPost, path, nil, ProposeSigningRequest{Data: data}, &signing); err != nil {
+		return "", err
+	}
+	return signing.SigningID, nil
+}
+
+// GetSignatures returns signatures reconstructed for the signing proposal
+func (c *Client) GetSignatures(ctx context.Context, roundID, signingID string) (*Signing, error) {
+	var signing Signing
+	path := "/v1/rounds/" + url.PathEscape(roundID) + "/signatures/" + url.PathEscape(signingID)
+	if err := c.do(ctx, http.MethodGet, path, nil, nil, &signing); err != nil {
+		return nil, err
+	}
+	return &signing, nil
+}
+
+// ListOperations returns a page of operations waiting for the airgapped machine
+func (c *Client) ListOperations(ctx context.Context, opts ListOptions) (*OperationsPage, error) {
+	var page OperationsPage
+	if err := c.do(ctx, http.MethodGet, "/v1/operations", opts.query(), nil, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// AllOperations returns all operations waiting for the airgapped machine
+func (c *Client) AllOperations(ctx context.Context) ([]*types.Operation, error) {
+	var operations []*types.Operation
+	for {
+		page, err := c.ListOperations(ctx, ListOptions{Limit: MaxPageLimit, Offset: len(operations)})
+		if err != nil {
+			return nil, err
+		}
+		operations = append(operations, page.Items...)
+		if len(page.Items) == 0 || len(operations) >= page.Total {
+			return operations, nil
+		}
+	}
+}
+
+// GetOperation returns the operation waiting for the airgapped machine
+func (c *Client) GetOperation(ctx context.Context, operationID string) (*types.Operation, error) {
+	var operation types.Operation
+	if err := c.do(ctx, http.MethodGet, "/v1/operations/"+url.PathEscape(operationID), nil, nil, &operation); err != nil {
+		return nil, err
+	}
+	return &operation, nil
+}
+
+// HandleOperationResult passes the operation processed by the airgapped machine to the node
+func (c *Client) HandleOperationResult(ctx context.Context, operation *types.Operation) error {
+	path := "/v1/operations/" + url.PathEscape(operation.ID) + "/result"
+	return c.do(ctx, http.MethodPost, path, nil, operation, nil)
+}
+
+// ApproveOperation approves the operation held by the signing policy of the node, the token of the client
+// must have the approver scope and the approval is recorded under the name of the token
+func (c *Client) ApproveOperation(ctx context.Context, operationID string) (*types.Operation, error) {
+	var operation types.Operation
+	path := "/v1/operations/" + url.PathEscape(operationID) + "/approve"
+	if err := c.do(ctx, http.MethodPost, path, nil, nil, &operation); err != nil {
+		return nil, err
+	}
+	return &operation, nil
+}
+
+// ListKeys returns a page of public keys of finished DKG rounds
+func (c *Client) ListKeys(ctx context.Context, opts ListOptions) (*KeysPage, error) {
+	var page KeysPage
+	if err := c.do(ctx, http.MethodGet, "/v1/keys", opts.query(), nil, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// AllKeys returns public keys of all finished DKG rounds
+func (c *Client) AllKeys(ctx context.Context) ([]*types.DKGKey, error) {
+	var keys []*types.DKGKey
+	for {
+		page, err := c.ListKeys(ctx, ListOptions{Limit: MaxPageLimit, Offset: len(keys)})
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, page.Items...)
+		if len(page.Items) == 0 || len(keys) >= page.Total {
+			return keys, nil
+		}
+	}
+}
+
+// GetKey returns the public key of the finished DKG round
+func (c *Client) GetKey(ctx context.Context, roundID string) (*types.DKGKey, error) {
+	var key types.DKGKey
+	if err := c.do(ctx, http.MethodGet, "/v1/keys/"+url.PathEscape(roundID), nil, nil, &key); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// SendMessage sends the message to the message board as is
+func (c *Client) SendMessage(ctx context.Context, message storage.Message) error {
+	return c.do(ctx, http.MethodPost, "/v1/messages", nil, message, nil)
+}
+
+// GetOffset returns the offset of the next message to be processed by the node
+func (c *Client) GetOffset(ctx context.Context) (uint64, error) {
+	var offset Offset
+	if err := c.do(ctx, http.MethodGet, "/v1/offset", nil, nil, &offset); err != nil {
+		return 0, err
+	}
+	return offset.Offset, nil
+}
+
+// SaveOffset sets the offset of the next message to be processed by the node
+func (c *Client) SaveOffset(ctx context.Context, offset uint64) error {
+	return c.do(ctx, http.MethodPut, "/v1/offset", nil, Offset{Offset: offset}, nil)
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func writeResponse(w http.ResponseWriter, status int, response Response) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+func TestClient_AllRounds(t *testing.T) {
+	const total = MaxPageLimit + 5
+
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/v1/rounds" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			t.Errorf("unexpected authorization header %q", r.Header.Get("Authorization"))
+		}
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+
+		var items []Round
+		for i := offset; i < total && i < offset+limit; i++ {
+			items = append(items, Round{ID: fmt.Sprintf("round_%d", i), State: "state"})
+		}
+		writeResponse(w, http.StatusOK, Response{Result: Page{
+			Items:    items,
+			PageInfo: PageInfo{Total: total, Offset: offset, Limit: limit},
+		}})
+	}))
+	defer server.Close()
+
+	c := NewClient(server.URL + "/")
+	c.SetToken("secret")
+	rounds, err := c.AllRounds(context.Background())
+	if err != nil {
+		t.Fatalf("failed to get rounds: %v", err)
+	}
+	if len(rounds) != total {
+		t.Fatalf("expected %d rounds, got %d", total, len(rounds))
+	}
+	if rounds[total-1].ID != fmt.Sprintf("round_%d", total-1) {
+		t.Errorf("unexpected last round %s", rounds[total-1].ID)
+	}
+	if requests != 2 {
+		t.Errorf("expected 2 requests, got %d", requests)
+	}
+}
+
+func TestClient_Errors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/rounds/missing":
+			writeResponse(w, http.StatusNotFound, Response{Error: &Error{Code: ErrorNotFound, Message: "round not found"}})
+		case "/v1/offset":
+			writeResponse(w, http.StatusForbidden, Response{Error: &Error{Code: ErrorForbidden, Message: "forbidden"}})
+		default:
+			w.WriteHeader(http.StatusBadGateway)
+			_, _ = w.Write([]byte("bad gateway"))
+		}
+	}))
+	defer server.Close()
+
+	c := NewClient(server.URL)
+	ctx := context.Background()
+
+	if _, err := c.GetRound(ctx, "missing"); !IsNotFound(err) {
+		t.Errorf("expected not found error, got %v", err)
+	}
+
+	err := c.SaveOffset(ctx, 10)
+	apiErr, ok := err.(*Error)
+	if !ok || apiErr.Code != ErrorForbidden || apiErr.Code.StatusCode() != http.StatusForbidden {
+		t.Errorf("expected forbidden error, got %v", err)
+	}
+
+	if _, err = c.GetNode(ctx); err == nil || IsNotFound(err) {
+		t.Errorf("expected an error for a malformed response, got %v", err)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err = c.GetNode(cancelled); err == nil {
+		t.Error("expected an error for a cancelled context")
+	}
+}
@@ -0,0 +1,121 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EventType is a type of an event of the node event stream
+type EventType string
+
+const (
+	// EventMessageProcessed is published when a message of the append-only log is processed
+	EventMessageProcessed EventType = "message_processed"
+	// EventStateChanged is published when the FSM of a DKG round moves to another state
+	EventStateChanged EventType = "state_changed"
+	// EventOperationCreated is published when a new operation waits for the airgapped machine
+	EventOperationCreated EventType = "operation_created"
+	// EventOperationHandled is published when the result of an operation is sent to the append-only log
+	EventOperationHandled EventType = "operation_handled"
+	// EventSignatureReconstructed is published when a verified reconstructed signature is saved
+	EventSignatureReconstructed EventType = "signature_reconstructed"
+	// EventSignatureReconstructionFailed is published when the airgapped machine of a participant fails to
+	// reconstruct a signature
+	EventSignatureReconstructionFailed EventType = "signature_reconstruction_failed"
+	// EventTimeout is published when a step of a DKG round or a signing is canceled by timeout
+	EventTimeout EventType = "timeout"
+	// EventRoundCompleted is published when the master public key of a DKG round is collected
+	EventRoundCompleted EventType = "round_completed"
+	// EventRoundFailed is published when a DKG round or a signing is canceled by a participant, an error or timeout
+	EventRoundFailed EventType = "round_failed"
+)
+
+// Event is an event of the node event stream. Offsets of events grow by one, a subscriber resumes the stream
+// from the offset of the last received event. Offsets keep growing after the node restarts, but only events
+// published since the start are kept, so older events are not replayed.
+type Event struct {
+	Offset     uint64    `json:"offset"`
+	Type       EventType `json:"type"`
+	DKGRoundID string    `json:"dkg_round_id,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+
+	// MessageOffset, MessageEvent and Sender describe the processed message
+	MessageOffset uint64 `json:"message_offset,omitempty"`
+	MessageEvent  string `json:"message_event,omitempty"`
+	Sender        string `json:"sender,omitempty"`
+
+	// PreviousState and State describe the FSM state change
+	PreviousState string `json:"previous_state,omitempty"`
+	State         string `json:"state,omitempty"`
+
+	OperationID   string `json:"operation_id,omitempty"`
+	OperationType string `json:"operation_type,omitempty"`
+	SigningID     string `json:"signing_id,omitempty"`
+}
+
+// Watch subscribes to the node event stream and calls the handler for every event until the context is done,
+// the handler returns an error or the connection is closed. Events with offsets greater than fromOffset are
+// replayed if the node still keeps them, zero fromOffset subscribes to new events only.
+func (c *Client) Watch(ctx context.Context, fromOffset uint64, handler func(Event) error) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/v1/events", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	if fromOffset > 0 {
+		req.Header.Set("Last-Event-ID", strconv.FormatUint(fromOffset, 10))
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		var response struct {
+			Error *Error `json:"error"`
+		}
+		if json.Unmarshal(body, &response) == nil && response.Error != nil {
+			return response.Error
+		}
+		return &Error{Code: ErrorInternal, Message: fmt.Sprintf("unexpected status %d", resp.StatusCode)}
+	}
+
+	// every event is a block of "field: value" lines ended by an empty line, lines starting with ":" are comments
+	var data strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if data.Len() == 0 {
+				continue
+			}
+			var event Event
+			if err = json.Unmarshal([]byte(data.String()), &event); err != nil {
+				return fmt.Errorf("failed to unmarshal event: %w", err)
+			}
+			data.Reset()
+			if err = handler(event); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "data:"):
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	if err = scanner.Err(); err != nil && ctx.Err() == nil {
+		return fmt.Errorf("failed to read event stream: %w", err)
+	}
+	return ctx.Err()
+}
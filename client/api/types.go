@@ -0,0 +1,140 @@
+// Package api is the typed Go client of the v1 HTTP API of the dc4bc node. The wire types of the API are
+// defined here and are shared by the node and the client.
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/lidofinance/dc4bc/client/types"
+)
+
+const (
+	// ParticipantsPathPrefix is the prefix of the API of a participant when the node hosts several participants
+	ParticipantsPathPrefix = "/participants/"
+
+	// DefaultPageLimit is the size of a page when the limit is not set
+	DefaultPageLimit = 100
+	// MaxPageLimit is the maximal size of a page
+	MaxPageLimit = 1000
+)
+
+// ParticipantPath returns the path of the API of the participant hosted by a node with several participants,
+// the API client of the participant is created with the node URL followed by the path
+func ParticipantPath(username string) string {
+	return ParticipantsPathPrefix + url.PathEscape(username)
+}
+
+// ErrorCode is a machine-readable code of an API error
+type ErrorCode string
+
+const (
+	ErrorInvalidRequest   ErrorCode = "invalid_request"
+	ErrorUnauthorized     ErrorCode = "unauthorized"
+	ErrorForbidden        ErrorCode = "forbidden"
+	ErrorNotFound         ErrorCode = "not_found"
+	ErrorMethodNotAllowed ErrorCode = "method_not_allowed"
+	ErrorInternal         ErrorCode = "internal"
+)
+
+// StatusCode returns the HTTP status code of the error code
+func (c ErrorCode) StatusCode() int {
+	switch c {
+	case ErrorInvalidRequest:
+		return http.StatusBadRequest
+	case ErrorUnauthorized:
+		return http.StatusUnauthorized
+	case ErrorForbidden:
+		return http.StatusForbidden
+	case ErrorNotFound:
+		return http.StatusNotFound
+	case ErrorMethodNotAllowed:
+		return http.StatusMethodNotAllowed
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// Error is an error returned by the node
+type Error struct {
+	Code    ErrorCode `json:"code"`
+	Message string    `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+// Response is the body of every API response, exactly one of the fields is set
+type Response struct {
+	Result interface{} `json:"result,omitempty"`
+	Error  *Error      `json:"error,omitempty"`
+}
+
+// PageInfo describes a page of a list
+type PageInfo struct {
+	Total  int `json:"total"`
+	Offset int `json:"offset"`
+	Limit  int `json:"limit"`
+}
+
+// Page is a page of a list
+type Page struct {
+	Items interface{} `json:"items"`
+	PageInfo
+}
+
+// ListOptions selects a page of a list, zero values select the first page of the default size
+type ListOptions struct {
+	Limit  int
+	Offset int
+}
+
+// Round is a DKG round
+type Round struct {
+	ID    string `json:"id"`
+	State string `json:"state"`
+}
+
+// Signing is a list of signatures reconstructed for a signing proposal
+type Signing struct {
+	SigningID  string                         `json:"signing_id"`
+	Signatures []types.ReconstructedSignature `json:"signatures"`
+}
+
+// NodeInfo describes the node
+type NodeInfo struct {
+	Username string `json:"username"`
+	PubKey   []byte `json:"pub_key"`
+}
+
+// Offset is the offset of the next message of the append-only log to be processed
+type Offset struct {
+	Offset uint64 `json:"offset"`
+}
+
+// ProposeSigningRequest is the body of a request to propose signing of the data
+type ProposeSigningRequest struct {
+	Data []byte `json:"data"`
+}
+
+type RoundsPage struct {
+	Items []Round `json:"items"`
+	PageInfo
+}
+
+type SigningsPage struct {
+	Items []Signing `json:"items"`
+	PageInfo
+}
+
+type OperationsPage struct {
+	Items []*types.Operation `json:"items"`
+	PageInfo
+}
+
+type KeysPage struct {
+	Items []*types.DKGKey `json:"items"`
+	PageInfo
+}
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+const (
+	// WebhookSignatureHeader is the header with the HMAC-SHA256 signature of the webhook body
+	WebhookSignatureHeader = "X-Dc4bc-Signature"
+	// WebhookEventHeader is the header with the type of the event of the webhook
+	WebhookEventHeader = "X-Dc4bc-Event"
+	// WebhookDeliveryHeader is the header with the offset of the event, retries of a webhook have the same offset
+	WebhookDeliveryHeader = "X-Dc4bc-Delivery"
+
+	webhookSignaturePrefix = "sha256="
+)
+
+// WebhookPayload is the JSON body of a webhook sent by the node
+type WebhookPayload struct {
+	Username string `json:"username"`
+	Event    Event  `json:"event"`
+}
+
+// SignWebhook returns the value of the signature header of the webhook body
+func SignWebhook(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return webhookSignaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhook checks the value of the signature header of the webhook body
+func VerifyWebhook(secret, body []byte, signature string) error {
+	if !strings.HasPrefix(signature, webhookSignaturePrefix) {
+		return errors.New("unknown webhook signature scheme")
+	}
+	if !hmac.Equal([]byte(SignWebhook(secret, body)), []byte(signature)) {
+		return errors.New("invalid webhook signature")
+	}
+	return nil
+}
@@ -0,0 +1,446 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lidofinance/dc4bc/client/api"
+	"github.com/lidofinance/dc4bc/client/types"
+	"github.com/lidofinance/dc4bc/storage"
+)
+
+const apiV1Prefix = "/v1"
+
+func apiErrorResponse(w http.ResponseWriter, code api.ErrorCode, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code.StatusCode())
+	writeAPIResponse(w, api.Response{Error: &api.Error{Code: code, Message: message}})
+}
+
+func apiSuccessResponse(w http.ResponseWriter, statusCode int, result interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	writeAPIResponse(w, api.Response{Result: result})
+}
+
+func writeAPIResponse(w http.ResponseWriter, resp api.Response) {
+	respBz, err := json.Marshal(resp)
+	if err != nil {
+		log.Printf("Failed to marshal response: %v\n", err)
+		return
+	}
+	if _, err := w.Write(respBz); err != nil {
+		panic(fmt.Sprintf("failed to write response: %v", err))
+	}
+}
+
+// paginate returns a page of the items, the items must be sorted. limit and offset are read from the query.
+func paginate(r *http.Request, total int, slice func(from, to int) interface{}) (*api.Page, error) {
+	limit, offset := api.DefaultPageLimit, 0
+	var err error
+	if value := r.URL.Query().Get("limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil || limit <= 0 || limit > api.MaxPageLimit {
+			return nil, fmt.Errorf("limit must be a number from 1 to %d", api.MaxPageLimit)
+		}
+	}
+	if value := r.URL.Query().Get("offset"); value != "" {
+		if offset, err = strconv.Atoi(value); err != nil || offset < 0 {
+			return nil, fmt.Errorf("offset must be a non-negative number")
+		}
+	}
+
+	// the offset is clamped before the limit is added, so a huge offset does not overflow
+	from := offset
+	if from > total {
+		from = total
+	}
+	to := from + limit
+	if to > total {
+		to = total
+	}
+	return &api.Page{Items: slice(from, to), PageInfo: api.PageInfo{Total: total, Offset: offset, Limit: limit}}, nil
+}
+
+func readJSONBody(r *http.Request, v interface{}) error {
+	defer r.Body.Close()
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read request body: %w", err)
+	}
+	if err = json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("failed to unmarshal request body: %w", err)
+	}
+	return nil
+}
+
+type apiV1HandlerFunc func(w http.ResponseWriter, r *http.Request, params map[string]string)
+
+type apiV1Route struct {
+	method  string
+	pattern string
+	handler apiV1HandlerFunc
+}
+
+// match matches the path against the pattern, segments of the pattern in braces are returned as parameters
+func (route apiV1Route) match(path string) (map[string]string, bool) {
+	patternSegments := strings.Split(strings.Trim(route.pattern, "/"), "/")
+	pathSegments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(patternSegments) != len(pathSegments) {
+		return nil, false
+	}
+
+	params := make(map[string]string)
+	for i, segment := range patternSegments {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			if pathSegments[i] == "" {
+				return nil, false
+			}
+			params[strings.Trim(segment, "{}")] = pathSegments[i]
+			continue
+		}
+		if segment != pathSegments[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+func (c *BaseClient) apiV1Routes() []apiV1Route {
+	return []apiV1Route{
+		{http.MethodGet, "/v1/openapi.json", c.getOpenAPISpecV1},
+		{http.MethodGet, "/v1/node", c.getNodeV1},
+
+		{http.MethodGet, "/v1/rounds", c.listRoundsV1},
+		{http.MethodPost, "/v1/rounds", c.startRoundV1},
+		{http.MethodGet, "/v1/rounds/{roundID}", c.getRoundV1},
+		{http.MethodGet, "/v1/rounds/{roundID}/signatures", c.listSignaturesV1},
+		{http.MethodPost, "/v1/rounds/{roundID}/signatures", c.proposeSigningV1},
+		{http.MethodGet, "/v1/rounds/{roundID}/signatures/{signingID}", c.getSignaturesV1},
+
+		{http.MethodGet, "/v1/operations", c.listOperationsV1},
+		{http.MethodGet, "/v1/operations/{operationID}", c.getOperationV1},
+		{http.MethodPost, "/v1/operations/{operationID}/result", c.handleOperationResultV1},
+		{http.MethodPost, "/v1/operations/{operationID}/approve", c.approveOperationV1},
+
+		{http.MethodGet, "/v1/keys", c.listKeysV1},
+		{http.MethodGet, "/v1/keys/{roundID}", c.getKeyV1},
+
+		{http.MethodPost, "/v1/messages", c.sendMessageV1},
+
+		{http.MethodGet, "/v1/events", c.watchEventsV1},
+
+		{http.MethodGet, "/v1/audit", c.listAuditRecordsV1},
+
+		{http.MethodGet, "/v1/offset", c.getOffsetV1},
+		{http.MethodPut, "/v1/offset", c.saveOffsetV1},
+	}
+}
+
+// apiV1Handler routes requests of the v1 API
+func (c *BaseClient) apiV1Handler() http.Handler {
+	routes := c.apiV1Routes()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var allowed []string
+		for _, route := range routes {
+			params, ok := route.match(r.URL.Path)
+			if !ok {
+				continue
+			}
+			if route.method != r.Method {
+				allowed = append(allowed, route.method)
+				continue
+			}
+			route.handler(w, r, params)
+			return
+		}
+		if len(allowed) > 0 {
+			w.Header().Set("Allow", strings.Join(allowed, ", "))
+			apiErrorResponse(w, api.ErrorMethodNotAllowed, fmt.Sprintf("method %s is not allowed", r.Method))
+			return
+		}
+		apiErrorResponse(w, api.ErrorNotFound, fmt.Sprintf("%s not found", r.URL.Path))
+	})
+}
+
+func (c *BaseClient) getOpenAPISpecV1(w http.ResponseWriter, _ *http.Request, _ map[string]string) {
+	w.Header().Set("Content-Type", "application/json")
+	rawResponse(w, []byte(openAPISpec))
+}
+
+func (c *BaseClient) getNodeV1(w http.ResponseWriter, _ *http.Request, _ map[string]string) {
+	apiSuccessResponse(w, http.StatusOK, api.NodeInfo{Username: c.GetUsername(), PubKey: c.GetPubKey()})
+}
+
+func (c *BaseClient) listRoundsV1(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	fsmInstances, err := c.state.GetAllFSM()
+	if err != nil {
+		apiErrorResponse(w, api.ErrorInternal, fmt.Sprintf("failed to get all FSM instances: %v", err))
+		return
+	}
+	rounds := make([]api.Round, 0, len(fsmInstances))
+	for id, fsmInstance := range fsmInstances {
+		state, err := fsmInstance.State()
+		if err != nil {
+			apiErrorResponse(w, api.ErrorInternal, fmt.Sprintf("failed to get FSM state: %v", err))
+			return
+		}
+		rounds = append(rounds, api.Round{ID: id, State: state.String()})
+	}
+	sort.Slice(rounds, func(i, j int) bool {
+		return rounds[i].ID < rounds[j].ID
+	})
+
+	page, err := paginate(r, len(rounds), func(from, to int) interface{} { return rounds[from:to] })
+	if err != nil {
+		apiErrorResponse(w, api.ErrorInvalidRequest, err.Error())
+		return
+	}
+	apiSuccessResponse(w, http.StatusOK, page)
+}
+
+func (c *BaseClient) startRoundV1(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	defer r.Body.Close()
+	reqBody, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		apiErrorResponse(w, api.ErrorInvalidRequest, fmt.Sprintf("failed to read request body: %v", err))
+		return
+	}
+	if !json.Valid(reqBody) {
+		apiErrorResponse(w, api.ErrorInvalidRequest, "request body is not a valid JSON")
+		return
+	}
+
+	dkgRoundID, err := c.startDKG(reqBody)
+	if err != nil {
+		apiErrorResponse(w, api.ErrorInternal, err.Error())
+		return
+	}
+	apiSuccessResponse(w, http.StatusAccepted, api.Round{ID: dkgRoundID})
+}
+
+func (c *BaseClient) getRoundV1(w http.ResponseWriter, _ *http.Request, params map[string]string) {
+	fsmInstance, ok, err := c.state.LoadFSM(params["roundID"])
+	if err != nil {
+		apiErrorResponse(w, api.ErrorInternal, fmt.Sprintf("failed to load FSM: %v", err))
+		return
+	}
+	if !ok {
+		apiErrorResponse(w, api.ErrorNotFound, fmt.Sprintf("round %s not found", params["roundID"]))
+		return
+	}
+	apiSuccessResponse(w, http.StatusOK, fsmInstance.FSMDump())
+}
+
+func (c *BaseClient) listSignaturesV1(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	signatures, err := c.GetSignatures(params["roundID"])
+	if err != nil {
+		apiErrorResponse(w, api.ErrorInternal, fmt.Sprintf("failed to get signatures: %v", err))
+		return
+	}
+	signings := make([]api.Signing, 0, len(signatures))
+	for signingID, signingSignatures := range signatures {
+		signings = append(signings, api.Signing{SigningID: signingID, Signatures: signingSignatures})
+	}
+	sort.Slice(signings, func(i, j int) bool {
+		return signings[i].SigningID < signings[j].SigningID
+	})
+
+	page, err := paginate(r, len(signings), func(from, to int) interface{} { return signings[from:to] })
+	if err != nil {
+		apiErrorResponse(w, api.ErrorInvalidRequest, err.Error())
+		return
+	}
+	apiSuccessResponse(w, http.StatusOK, page)
+}
+
+func (c *BaseClient) proposeSigningV1(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	var req api.ProposeSigningRequest
+	if err := readJSONBody(r, &req); err != nil {
+		apiErrorResponse(w, api.ErrorInvalidRequest, err.Error())
+		return
+	}
+	if len(req.Data) == 0 {
+		apiErrorResponse(w, api.ErrorInvalidRequest, "data to sign is empty")
+		return
+	}
+	_, ok, err := c.state.LoadFSM(params["roundID"])
+	if err != nil {
+		apiErrorResponse(w, api.ErrorInternal, fmt.Sprintf("failed to load FSM: %v", err))
+		return
+	}
+	if !ok {
+		apiErrorResponse(w, api.ErrorNotFound, fmt.Sprintf("round %s not found", params["roundID"]))
+		return
+	}
+
+	signingID, err := c.proposeSignData(params["roundID"], req.Data)
+	if err != nil {
+		apiErrorResponse(w, api.ErrorInternal, err.Error())
+		return
+	}
+	apiSuccessResponse(w, http.StatusAccepted, api.Signing{SigningID: signingID})
+}
+
+func (c *BaseClient) getSignaturesV1(w http.ResponseWriter, _ *http.Request, params map[string]string) {
+	signatures, err := c.GetSignatures(params["roundID"])
+	if err != nil {
+		apiErrorResponse(w, api.ErrorInternal, fmt.Sprintf("failed to get signatures: %v", err))
+		return
+	}
+	signingSignatures, ok := signatures[params["signingID"]]
+	if !ok {
+		apiErrorResponse(w, api.ErrorNotFound, fmt.Sprintf("signing %s not found", params["signingID"]))
+		return
+	}
+	apiSuccessResponse(w, http.StatusOK, api.Signing{SigningID: params["signingID"], Signatures: signingSignatures})
+}
+
+func (c *BaseClient) listOperationsV1(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	operationsMap, err := c.GetOperations()
+	if err != nil {
+		apiErrorResponse(w, api.ErrorInternal, fmt.Sprintf("failed to get operations: %v", err))
+		return
+	}
+	operations := make([]*types.Operation, 0, len(operationsMap))
+	for _, operation := range operationsMap {
+		operations = append(operations, operation)
+	}
+	sort.Slice(operations, func(i, j int) bool {
+		return operations[i].CreatedAt.Before(operations[j].CreatedAt)
+	})
+
+	page, err := paginate(r, len(operations), func(from, to int) interface{} { return operations[from:to] })
+	if err != nil {
+		apiErrorResponse(w, api.ErrorInvalidRequest, err.Error())
+		return
+	}
+	apiSuccessResponse(w, http.StatusOK, page)
+}
+
+func (c *BaseClient) getOperationV1(w http.ResponseWriter, _ *http.Request, params map[string]string) {
+	operation, err := c.state.GetOperationByID(params["operationID"])
+	if err != nil {
+		apiErrorResponse(w, api.ErrorNotFound, fmt.Sprintf("operation %s not found", params["operationID"]))
+		return
+	}
+	apiSuccessResponse(w, http.StatusOK, operation)
+}
+
+func (c *BaseClient) handleOperationResultV1(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	var operation types.Operation
+	if err := readJSONBody(r, &operation); err != nil {
+		apiErrorResponse(w, api.ErrorInvalidRequest, err.Error())
+		return
+	}
+	if operation.ID != params["operationID"] {
+		apiErrorResponse(w, api.ErrorInvalidRequest, "operation ID does not match the path")
+		return
+	}
+	if _, err := c.state.GetOperationByID(operation.ID); err != nil {
+		apiErrorResponse(w, api.ErrorNotFound, fmt.Sprintf("operation %s not found", operation.ID))
+		return
+	}
+
+	if err := c.handleProcessedOperation(operation); err != nil {
+		apiErrorResponse(w, api.ErrorInvalidRequest, fmt.Sprintf("failed to handle processed operation: %v", err))
+		return
+	}
+	apiSuccessResponse(w, http.StatusOK, operation.ID)
+}
+
+func (c *BaseClient) approveOperationV1(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	// the approver is the holder of the token, so approvals are not possible without authentication
+	token, ok := apiTokenFromContext(r.Context())
+	if !ok {
+		apiErrorResponse(w, api.ErrorForbidden, "approvals require bearer tokens, set API tokens of the node")
+		return
+	}
+	if _, err := c.state.GetOperationByID(params["operationID"]); err != nil {
+		apiErrorResponse(w, api.ErrorNotFound, fmt.Sprintf("operation %s not found", params["operationID"]))
+		return
+	}
+
+	operation, err := c.approveOperation(params["operationID"], token.Name)
+	if err != nil {
+		apiErrorResponse(w, api.ErrorInvalidRequest, fmt.Sprintf("failed to approve operation: %v", err))
+		return
+	}
+	apiSuccessResponse(w, http.StatusOK, operation)
+}
+
+func (c *BaseClient) listKeysV1(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	keys, err := c.GetDKGKeys()
+	if err != nil {
+		apiErrorResponse(w, api.ErrorInternal, fmt.Sprintf("failed to get keys: %v", err))
+		return
+	}
+
+	page, err := paginate(r, len(keys), func(from, to int) interface{} { return keys[from:to] })
+	if err != nil {
+		apiErrorResponse(w, api.ErrorInvalidRequest, err.Error())
+		return
+	}
+	apiSuccessResponse(w, http.StatusOK, page)
+}
+
+func (c *BaseClient) getKeyV1(w http.ResponseWriter, _ *http.Request, params map[string]string) {
+	keys, err := c.GetDKGKeys()
+	if err != nil {
+		apiErrorResponse(w, api.ErrorInternal, fmt.Sprintf("failed to get keys: %v", err))
+		return
+	}
+	for _, key := range keys {
+		if key.DKGRoundID == params["roundID"] {
+			apiSuccessResponse(w, http.StatusOK, key)
+			return
+		}
+	}
+	apiErrorResponse(w, api.ErrorNotFound, fmt.Sprintf("key of round %s not found", params["roundID"]))
+}
+
+func (c *BaseClient) sendMessageV1(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	var message storage.Message
+	if err := readJSONBody(r, &message); err != nil {
+		apiErrorResponse(w, api.ErrorInvalidRequest, err.Error())
+		return
+	}
+	if err := c.SendMessage(message); err != nil {
+		apiErrorResponse(w, api.ErrorInternal, fmt.Sprintf("failed to send message to the storage: %v", err))
+		return
+	}
+	apiSuccessResponse(w, http.StatusAccepted, message.ID)
+}
+
+func (c *BaseClient) getOffsetV1(w http.ResponseWriter, _ *http.Request, _ map[string]string) {
+	offset, err := c.state.LoadOffset()
+	if err != nil {
+		apiErrorResponse(w, api.ErrorInternal, fmt.Sprintf("failed to load offset: %v", err))
+		return
+	}
+	apiSuccessResponse(w, http.StatusOK, api.Offset{Offset: offset})
+}
+
+func (c *BaseClient) saveOffsetV1(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	var req struct {
+		Offset *uint64 `json:"offset"`
+	}
+	if err := readJSONBody(r, &req); err != nil {
+		apiErrorResponse(w, api.ErrorInvalidRequest, err.Error())
+		return
+	}
+	if req.Offset == nil {
+		apiErrorResponse(w, api.ErrorInvalidRequest, "offset is not set")
+		return
+	}
+	if err := c.saveOffset(*req.Offset); err != nil {
+		apiErrorResponse(w, api.ErrorInternal, fmt.Sprintf("failed to save offset: %v", err))
+		return
+	}
+	apiSuccessResponse(w, http.StatusOK, api.Offset{Offset: *req.Offset})
+}
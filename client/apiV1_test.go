@@ -0,0 +1,160 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lidofinance/dc4bc/client/api"
+	"github.com/lidofinance/dc4bc/client/types"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestAPIClient(t *testing.T) (*BaseClient, func()) {
+	dbPath, err := ioutil.TempDir("", "dc4bc_test_api_v1")
+	require.NoError(t, err)
+	state, err := NewLevelDBState(filepath.Join(dbPath, "state"))
+	require.NoError(t, err)
+
+	c := newTestClient(t, context.Background(), dbPath, "test", state, nil)
+	return c, func() { os.RemoveAll(dbPath) }
+}
+
+func doAPIRequest(t *testing.T, handler http.Handler, method, path string, body []byte) (*httptest.ResponseRecorder, api.Response) {
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(method, path, bytes.NewReader(body)))
+
+	var resp api.Response
+	if strings.HasPrefix(path, apiV1Prefix) {
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	}
+	return w, resp
+}
+
+func TestAPIV1_OpenAPISpecCoversRoutes(t *testing.T) {
+	req := require.New(t)
+
+	var spec struct {
+		Paths map[string]map[string]interface{} `json:"paths"`
+	}
+	req.NoError(json.Unmarshal([]byte(openAPISpec), &spec))
+
+	c := &BaseClient{}
+	for _, route := range c.apiV1Routes() {
+		methods, ok := spec.Paths[route.pattern]
+		req.True(ok, "path %s is not documented", route.pattern)
+		_, ok = methods[strings.ToLower(route.method)]
+		req.True(ok, "%s %s is not documented", route.method, route.pattern)
+	}
+}
+
+func TestAPIV1_Routing(t *testing.T) {
+	req := require.New(t)
+	c, cleanup := newTestAPIClient(t)
+	defer cleanup()
+	handler := c.httpHandler()
+
+	w, resp := doAPIRequest(t, handler, http.MethodGet, "/v1/unknown", nil)
+	req.Equal(http.StatusNotFound, w.Code)
+	req.Equal(api.ErrorNotFound, resp.Error.Code)
+
+	w, resp = doAPIRequest(t, handler, http.MethodDelete, "/v1/offset", nil)
+	req.Equal(http.StatusMethodNotAllowed, w.Code)
+	req.Equal(api.ErrorMethodNotAllowed, resp.Error.Code)
+	req.Equal("GET, PUT", w.Header().Get("Allow"))
+
+	w, resp = doAPIRequest(t, handler, http.MethodGet, "/v1/rounds/unknown_round", nil)
+	req.Equal(http.StatusNotFound, w.Code)
+	req.Equal(api.ErrorNotFound, resp.Error.Code)
+
+	w, resp = doAPIRequest(t, handler, http.MethodPut, "/v1/offset", []byte(`{}`))
+	req.Equal(http.StatusBadRequest, w.Code)
+	req.Equal(api.ErrorInvalidRequest, resp.Error.Code)
+
+	w, _ = doAPIRequest(t, handler, http.MethodPut, "/v1/offset", []byte(`{"offset": 5}`))
+	req.Equal(http.StatusOK, w.Code)
+	w, resp = doAPIRequest(t, handler, http.MethodGet, "/v1/offset", nil)
+	req.Equal(http.StatusOK, w.Code)
+	req.Nil(resp.Error)
+	req.Equal(map[string]interface{}{"offset": float64(5)}, resp.Result)
+
+	w, _ = doAPIRequest(t, handler, http.MethodGet, "/getOffset", nil)
+	req.Equal(http.StatusOK, w.Code)
+	req.Equal("true", w.Header().Get("Deprecation"))
+	req.Contains(w.Header().Get("Link"), "/v1/offset")
+
+	w, _ = doAPIRequest(t, handler, http.MethodGet, "/v1/openapi.json", nil)
+	req.Equal(http.StatusOK, w.Code)
+}
+
+func TestAPIV1_Pagination(t *testing.T) {
+	req := require.New(t)
+	c, cleanup := newTestAPIClient(t)
+	defer cleanup()
+	handler := c.httpHandler()
+
+	createdAt := time.Now()
+	var operationIDs []string
+	for i := 0; i < 5; i++ {
+		operation := &types.Operation{
+			ID:        uuid.New().String(),
+			Type:      types.DKGCommits,
+			CreatedAt: createdAt.Add(time.Duration(i) * time.Second),
+		}
+		req.NoError(c.state.PutOperation(operation))
+		operationIDs = append(operationIDs, operation.ID)
+	}
+
+	w, resp := doAPIRequest(t, handler, http.MethodGet, "/v1/operations?limit=2&offset=3", nil)
+	req.Equal(http.StatusOK, w.Code)
+	page := resp.Result.(map[string]interface{})
+	req.Equal(float64(5), page["total"])
+	items := page["items"].([]interface{})
+	req.Len(items, 2)
+	req.Equal(operationIDs[3], items[0].(map[string]interface{})["ID"])
+	req.Equal(operationIDs[4], items[1].(map[string]interface{})["ID"])
+
+	w, resp = doAPIRequest(t, handler, http.MethodGet, "/v1/operations?offset=10", nil)
+	req.Equal(http.StatusOK, w.Code)
+	req.Empty(resp.Result.(map[string]interface{})["items"])
+	w, resp = doAPIRequest(t, handler, http.MethodGet, fmt.Sprintf("/v1/operations?offset=%d", math.MaxInt64), nil)
+	req.Equal(http.StatusOK, w.Code)
+	req.Empty(resp.Result.(map[string]interface{})["items"])
+
+	for _, query := range []string{"limit=0", "limit=abc", fmt.Sprintf("limit=%d", api.MaxPageLimit+1), "offset=-1"} {
+		w, resp = doAPIRequest(t, handler, http.MethodGet, "/v1/operations?"+query, nil)
+		req.Equal(http.StatusBadRequest, w.Code, query)
+		req.Equal(api.ErrorInvalidRequest, resp.Error.Code, query)
+	}
+
+	w, resp = doAPIRequest(t, handler, http.MethodGet, "/v1/operations/"+operationIDs[0], nil)
+	req.Equal(http.StatusOK, w.Code)
+	req.Equal(operationIDs[0], resp.Result.(map[string]interface{})["ID"])
+}
+
+func TestAPIV1_ListKeysSkipsBrokenRounds(t *testing.T) {
+	req := require.New(t)
+	c, cleanup := newTestAPIClient(t)
+	defer cleanup()
+	handler := c.httpHandler()
+
+	// a key without the FSM state of its round is skipped instead of failing the whole list
+	req.NoError(c.state.SaveDKGKey(&types.DKGKey{DKGRoundID: "orphan"}))
+	w, resp := doAPIRequest(t, handler, http.MethodGet, "/v1/keys", nil)
+	req.Equal(http.StatusOK, w.Code, resp)
+	req.Empty(resp.Result.(map[string]interface{})["items"])
+
+	w, _ = doAPIRequest(t, handler, http.MethodGet, "/v1/keys/orphan", nil)
+	req.Equal(http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,168 @@
+package client
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/lidofinance/dc4bc/client/api"
+)
+
+// auditJournal is an append-only file of hash-chained audit records, one JSON record per line.
+// Records are signed with the communication key of the node. The journal is not anchored anywhere else,
+// so removed records at the end of the journal are not detected unless the last index and hash are kept aside.
+type auditJournal struct {
+	sync.Mutex
+	path     string
+	file     *os.File
+	sign     func([]byte) ([]byte, error)
+	next     uint64
+	lastHash []byte
+	// truncated is the size of the incomplete record dropped from the end of the journal on open
+	truncated int64
+}
+
+func openAuditJournal(path string, sign func([]byte) ([]byte, error)) (*auditJournal, error) {
+	j := &auditJournal{path: path, sign: sign}
+	records, size, err := j.readRecords()
+	if err != nil {
+		return nil, err
+	}
+	// a record is written with one write, so only the last record can be incomplete if the node was stopped
+	// while writing it. The record was not written successfully, so it is dropped to continue the chain.
+	if info, err := os.Stat(path); err == nil && info.Size() > size {
+		if err = os.Truncate(path, size); err != nil {
+			return nil, fmt.Errorf("failed to drop incomplete audit record: %w", err)
+		}
+		j.truncated = info.Size() - size
+	}
+	// the chain is continued after the last record, the journal itself is verified by dc4bc_cli
+	if len(records) > 0 {
+		last := records[len(records)-1]
+		j.next, j.lastHash = last.Index+1, last.Hash
+	}
+	if j.file, err = os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600); err != nil {
+		return nil, fmt.Errorf("failed to open audit journal: %w", err)
+	}
+	return j, nil
+}
+
+// append chains, signs and writes the record
+func (j *auditJournal) append(record api.AuditRecord) error {
+	j.Lock()
+	defer j.Unlock()
+
+	record.Index = j.next
+	record.CreatedAt = time.Now().UTC()
+	record.PrevHash = j.lastHash
+	hash, err := record.ComputeHash()
+	if err != nil {
+		return err
+	}
+	record.Hash = hash
+	if record.Signature, err = j.sign(hash); err != nil {
+		return fmt.Errorf("failed to sign audit record: %w", err)
+	}
+
+	bz, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit record: %w", err)
+	}
+	if _, err = j.file.Write(append(bz, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit record: %w", err)
+	}
+	if err = j.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync audit journal: %w", err)
+	}
+	j.next, j.lastHash = record.Index+1, record.Hash
+	return nil
+}
+
+// records reads all complete records of the journal
+func (j *auditJournal) records() ([]api.AuditRecord, error) {
+	records, _, err := j.readRecords()
+	return records, err
+}
+
+// readRecords reads all complete records of the journal and returns the size of the file they take,
+// the last line without the line break is an incomplete record and is skipped
+func (j *auditJournal) readRecords() ([]api.AuditRecord, int64, error) {
+	file, err := os.Open(j.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return []api.AuditRecord{}, 0, nil
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to open audit journal: %w", err)
+	}
+	defer file.Close()
+
+	records := []api.AuditRecord{}
+	size := int64(0)
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			return records, size, nil
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to read audit journal: %w", err)
+		}
+		var record api.AuditRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			return nil, 0, fmt.Errorf("failed to unmarshal audit record %d: %w", len(records), err)
+		}
+		records = append(records, record)
+		size += int64(len(line))
+	}
+}
+
+// SetAuditJournal enables the audit journal of security-relevant actions of the node, it must be called before Poll
+func (c *BaseClient) SetAuditJournal(path string) error {
+	journal, err := openAuditJournal(path, c.signMessage)
+	if err != nil {
+		return err
+	}
+	if journal.truncated > 0 {
+		c.Logger.with("bytes", journal.truncated).Warn("Incomplete record is dropped from the end of the audit journal")
+	}
+	c.journal = journal
+	return nil
+}
+
+// audit appends the record to the audit journal if it is enabled. The action has already happened, so
+// a failure is logged and does not fail the action.
+func (c *BaseClient) audit(record api.AuditRecord) {
+	if c.journal == nil {
+		return
+	}
+	if err := c.journal.append(record); err != nil {
+		c.Logger.with("action", record.Action).with("error", err).Error("Failed to write the audit journal")
+	}
+}
+
+func (c *BaseClient) listAuditRecordsV1(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	if c.journal == nil {
+		apiErrorResponse(w, api.ErrorNotFound, "audit journal is not enabled")
+		return
+	}
+	c.journal.Lock()
+	records, err := c.journal.records()
+	c.journal.Unlock()
+	if err != nil {
+		apiErrorResponse(w, api.ErrorInternal, fmt.Sprintf("failed to read audit journal: %v", err))
+		return
+	}
+
+	page, err := paginate(r, len(records), func(from, to int) interface{} { return records[from:to] })
+	if err != nil {
+		apiErrorResponse(w, api.ErrorInvalidRequest, err.Error())
+		return
+	}
+	apiSuccessResponse(w, http.StatusOK, page)
+}
@@ -0,0 +1,106 @@
+package client
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lidofinance/dc4bc/client/api"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestAuditJournal(t *testing.T, path string, privKey ed25519.PrivateKey) *auditJournal {
+	journal, err := openAuditJournal(path, func(data []byte) ([]byte, error) {
+		return ed25519.Sign(privKey, data), nil
+	})
+	require.NoError(t, err)
+	return journal
+}
+
+func TestAuditJournal(t *testing.T) {
+	req := require.New(t)
+	dir, err := ioutil.TempDir("", "dc4bc_test_audit_journal")
+	req.NoError(err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "journal")
+	pubKey, privKey, err := ed25519.GenerateKey(rand.Reader)
+	req.NoError(err)
+
+	journal := newTestAuditJournal(t, path, privKey)
+	req.NoError(journal.append(api.AuditRecord{Action: api.AuditDKGStarted, DKGRoundID: "dkg_id"}))
+	req.NoError(journal.append(api.AuditRecord{Action: api.AuditSigningProposed, DKGRoundID: "dkg_id", SigningID: "signing_id"}))
+
+	// the node is stopped while writing a record, the incomplete record is dropped on the restart
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	req.NoError(err)
+	_, err = file.Write([]byte(`{"index": 2, "act`))
+	req.NoError(err)
+	req.NoError(file.Close())
+
+	// the chain is continued after the node restarts
+	journal = newTestAuditJournal(t, path, privKey)
+	req.Equal(int64(len(`{"index": 2, "act`)), journal.truncated)
+	req.NoError(journal.append(api.AuditRecord{Action: api.AuditOperationHandled, OperationID: "operation_id", MessageEvents: []string{"event"}}))
+
+	records, err := journal.records()
+	req.NoError(err)
+	req.Len(records, 3)
+	for i, record := range records {
+		req.Equal(uint64(i), record.Index)
+	}
+	req.NoError(api.VerifyAuditRecords(records, pubKey))
+	req.NoError(api.VerifyAuditRecords(records[1:], pubKey))
+
+	_, otherPrivKey, err := ed25519.GenerateKey(rand.Reader)
+	req.NoError(err)
+	req.Error(api.VerifyAuditRecords(records, otherPrivKey.Public().(ed25519.PublicKey)))
+
+	tampered := append([]api.AuditRecord{}, records...)
+	tampered[1].SigningID = "other_signing_id"
+	req.Error(api.VerifyAuditRecords(tampered, pubKey))
+
+	// a removed record breaks the chain even if the indices are fixed
+	removed := []api.AuditRecord{records[0], records[2]}
+	removed[1].Index = 1
+	req.Error(api.VerifyAuditRecords(removed, pubKey))
+	req.Error(api.VerifyAuditRecords([]api.AuditRecord{records[0], records[2]}, pubKey))
+}
+
+func TestAPIV1_AuditRecords(t *testing.T) {
+	req := require.New(t)
+	c, cleanup := newTestAPIClient(t)
+	defer cleanup()
+	handler := c.httpHandler()
+
+	w, resp := doAPIRequest(t, handler, http.MethodGet, "/v1/audit", nil)
+	req.Equal(http.StatusNotFound, w.Code, resp)
+
+	dir, err := ioutil.TempDir("", "dc4bc_test_audit_api")
+	req.NoError(err)
+	defer os.RemoveAll(dir)
+	pubKey, privKey, err := ed25519.GenerateKey(rand.Reader)
+	req.NoError(err)
+	c.journal = newTestAuditJournal(t, filepath.Join(dir, "journal"), privKey)
+
+	req.NoError(c.state.SaveOffset(5))
+	w, _ = doAPIRequest(t, handler, http.MethodPut, "/v1/offset", []byte(`{"offset": 3}`))
+	req.Equal(http.StatusOK, w.Code)
+
+	w, resp = doAPIRequest(t, handler, http.MethodGet, "/v1/audit", nil)
+	req.Equal(http.StatusOK, w.Code)
+	resultBz, err := json.Marshal(resp.Result)
+	req.NoError(err)
+	var page api.AuditRecordsPage
+	req.NoError(json.Unmarshal(resultBz, &page))
+	req.Equal(1, page.Total)
+	record := page.Items[0]
+	req.Equal(api.AuditOffsetChanged, record.Action)
+	req.Equal(uint64(5), *record.PreviousOffset)
+	req.Equal(uint64(3), *record.Offset)
+	req.NoError(api.VerifyAuditRecords(page.Items, pubKey))
+}
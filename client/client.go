@@ -1,20 +1,24 @@
 package client
 
 import (
+	"bytes"
 	"context"
 	"crypto/ed25519"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
 	"sync"
 	"time"
 
+	"github.com/corestario/kyber/pairing"
+	"github.com/corestario/kyber/pairing/bls12381"
+	"github.com/corestario/kyber/sign/bls"
 	"github.com/lidofinance/dc4bc/fsm/types/responses"
 
 	sipf "github.com/lidofinance/dc4bc/fsm/state_machines/signing_proposal_fsm"
 
 	"github.com/google/uuid"
+	"github.com/lidofinance/dc4bc/client/api"
 	"github.com/lidofinance/dc4bc/client/types"
 	"github.com/lidofinance/dc4bc/fsm/types/requests"
 
@@ -31,6 +35,15 @@ const (
 	pollingPeriod = time.Second
 )
 
+// operationSourceEvents maps an operation type to the event of bulletin board messages
+// the operation payload is built from
+var operationSourceEvents = map[fsm.State]fsm.Event{
+	dpf.StateDkgDealsAwaitConfirmations:     dpf.EventDKGCommitConfirmationReceived,
+	dpf.StateDkgResponsesAwaitConfirmations: dpf.EventDKGDealConfirmationReceived,
+	dpf.StateDkgMasterKeyAwaitConfirmations: dpf.EventDKGResponseConfirmationReceived,
+	sipf.StateSigningPartialSignsCollected:  sipf.EventSigningPartialSignReceived,
+}
+
 type Client interface {
 	Poll() error
 	GetLogger() *logger
@@ -40,42 +53,92 @@ type Client interface {
 	ProcessMessage(message storage.Message) error
 	GetOperations() (map[string]*types.Operation, error)
 	StartHTTPServer(listenAddr string) error
+	SetAirgappedReconstruction(enabled bool)
+	SetHTTPAuth(cfg HTTPAuthConfig)
+	SetWebhooks(cfg WebhookConfig) error
+	SetLogger(cfg LoggerConfig) error
+	SetAuditJournal(path string) error
+	SetSigningPolicy(policy *SigningPolicy)
 }
 
 type BaseClient struct {
 	sync.Mutex
-	Logger   *logger
-	userName string
-	pubKey   ed25519.PublicKey
-	ctx      context.Context
-	state    State
-	storage  storage.Storage
-	keyStore KeyStore
+	Logger          *logger
+	userName        string
+	pubKey          ed25519.PublicKey
+	airgappedPubKey ed25519.PublicKey
+	ctx             context.Context
+	state           State
+	storage         storage.Storage
+	keyStore        KeyStore
+
+	// airgappedReconstruction makes the client pass collected partial signs to the airgapped machine
+	// instead of reconstructing signatures on its own
+	airgappedReconstruction bool
+
+	httpAuth HTTPAuthConfig
+
+	events  *eventBus
+	metrics *metrics
+	journal *auditJournal
+
+	signingPolicy *SigningPolicy
 }
 
+// NewClient creates a client. airgappedPubKey is the pinned identity key of our airgapped machine,
+// results of operations which are not signed with it are rejected
 func NewClient(
 	ctx context.Context,
 	userName string,
 	state State,
 	storage storage.Storage,
 	keyStore KeyStore,
+	airgappedPubKey ed25519.PublicKey,
 ) (Client, error) {
 	keyPair, err := keyStore.LoadKeys(userName, "")
 	if err != nil {
 		return nil, fmt.Errorf("failed to LoadKeys: %w", err)
 	}
 
+	if airgappedPubKey != nil && len(airgappedPubKey) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid airgapped public key size: %d", len(airgappedPubKey))
+	}
+
+	events, err := newEventBus(state)
+	if err != nil {
+		return nil, err
+	}
+
+	m := newMetrics(state)
 	return &BaseClient{
-		ctx:      ctx,
-		Logger:   newLogger(userName),
-		userName: userName,
-		pubKey:   keyPair.Pub,
-		state:    state,
-		storage:  storage,
-		keyStore: keyStore,
+		ctx:             ctx,
+		Logger:          newLogger(userName),
+		userName:        userName,
+		pubKey:          keyPair.Pub,
+		airgappedPubKey: airgappedPubKey,
+		state:           state,
+		storage:         newInstrumentedStorage(storage, m),
+		keyStore:        keyStore,
+		events:          events,
+		metrics:         m,
 	}, nil
 }
 
+// SetAirgappedReconstruction switches reconstruction of threshold signatures to the airgapped machine
+func (c *BaseClient) SetAirgappedReconstruction(enabled bool) {
+	c.airgappedReconstruction = enabled
+}
+
+// SetHTTPAuth sets TLS and bearer tokens of the HTTP API, it must be called before StartHTTPServer
+func (c *BaseClient) SetHTTPAuth(cfg HTTPAuthConfig) {
+	c.httpAuth = cfg
+}
+
+// SetLogger sets the level, format and output of the client log
+func (c *BaseClient) SetLogger(cfg LoggerConfig) error {
+	return c.Logger.configure(cfg)
+}
+
 func (c *BaseClient) GetLogger() *logger {
 	return c.Logger
 }
@@ -90,6 +153,9 @@ func (c *BaseClient) GetPubKey() ed25519.PublicKey {
 
 // Poll is a main client loop, which gets new messages from an append-only log and processes them
 func (c *BaseClient) Poll() error {
+	if err := c.registerMissingDKGKeys(); err != nil {
+		return err
+	}
 	tk := time.NewTicker(pollingPeriod)
 	for {
 		select {
@@ -105,23 +171,31 @@ func (c *BaseClient) Poll() error {
 			}
 
 			for _, message := range messages {
-				if message.RecipientAddr == "" || message.RecipientAddr == c.GetUsername() {
-					c.Logger.Log("Handling message with offset %d, type %s", message.Offset, message.Event)
-					if err := c.ProcessMessage(message); err != nil {
-						c.Logger.Log("Failed to process message with offset %d: %v", message.Offset, err)
-					} else {
-						c.Logger.Log("Successfully processed message with offset %d, type %s",
-							message.Offset, message.Event)
-					}
-				}
+				c.handleMessage(message)
 			}
 		case <-c.ctx.Done():
-			log.Println("Context closed, stop polling...")
+			c.Logger.Info("Context closed, stop polling...")
 			return nil
 		}
 	}
 }
 
+// handleMessage processes a message of the append-only log if it is addressed to the client
+func (c *BaseClient) handleMessage(message storage.Message) {
+	if message.RecipientAddr != "" && message.RecipientAddr != c.GetUsername() {
+		return
+	}
+	messageLogger := c.Logger.withMessage(message)
+	messageLogger.Debug("Handling message")
+	if err := c.ProcessMessage(message); err != nil {
+		c.metrics.messagesFailed.WithLabelValues(message.Event).Inc()
+		messageLogger.with("error", err).Error("Failed to process message")
+	} else {
+		c.metrics.messagesProcessed.WithLabelValues(message.Event).Inc()
+		messageLogger.Info("Successfully processed message")
+	}
+}
+
 func (c *BaseClient) SendMessage(message storage.Message) error {
 	if _, err := c.storage.Send(message); err != nil {
 		return fmt.Errorf("failed to post message: %w", err)
@@ -130,7 +204,7 @@ func (c *BaseClient) SendMessage(message storage.Message) error {
 	return nil
 }
 
-// processSignature saves a broadcasted reconstructed signature to a LevelDB
+// processSignature saves a broadcasted signing proposal to a LevelDB
 func (c *BaseClient) processSignature(message storage.Message) error {
 	var (
 		signature types.ReconstructedSignature
@@ -144,15 +218,101 @@ func (c *BaseClient) processSignature(message storage.Message) error {
 	return c.state.SaveSignature(signature)
 }
 
+// processReconstructedSignature verifies a broadcasted reconstructed signature and saves it to a LevelDB
+func (c *BaseClient) processReconstructedSignature(message storage.Message) (*types.ReconstructedSignature, error) {
+	fsmInstance, err := c.getFSMInstance(message.DkgRoundID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to getFSMInstance: %w", err)
+	}
+	if err = c.verifyMessage(fsmInstance, message); err != nil {
+		return nil, fmt.Errorf("failed to verifyMessage %+v: %w", message, err)
+	}
+
+	var signature types.ReconstructedSignature
+	if err = json.Unmarshal(message.Data, &signature); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal reconstructed signature: %w", err)
+	}
+	signature.Username = message.SenderAddr
+	signature.DKGRoundID = message.DkgRoundID
+
+	if err = c.verifyReconstructedSignature(fsmInstance, signature); err != nil {
+		return nil, fmt.Errorf("invalid reconstructed signature from %s: %w", message.SenderAddr, err)
+	}
+	if err = c.state.SaveSignature(signature); err != nil {
+		return nil, err
+	}
+	return &signature, nil
+}
+
+// verifyReconstructedSignature checks that the signature is made for the data proposed to sign
+// and is valid for the master public key of the DKG round
+func (c *BaseClient) verifyReconstructedSignature(fsmInstance *state_machines.FSMInstance, signature types.ReconstructedSignature) error {
+	signingData, err := c.state.GetSignatureByID(signature.DKGRoundID, signature.SigningID)
+	if err != nil {
+		return fmt.Errorf("failed to get signing %s: %w", signature.SigningID, err)
+	}
+	var isProposed bool
+	for _, entry := range signingData {
+		if len(entry.Signature) == 0 && bytes.Equal(entry.SrcPayload, signature.SrcPayload) {
+			isProposed = true
+			break
+		}
+	}
+	if !isProposed {
+		return errors.New("the signed data was not proposed to sign")
+	}
+
+	masterPubKeyBz, err := fsmInstance.GetMasterPubKey()
+	if err != nil {
+		return fmt.Errorf("failed to get master public key: %w", err)
+	}
+	suite := bls12381.NewBLS12381Suite(nil)
+	masterPubKey := suite.Point()
+	if err = masterPubKey.UnmarshalBinary(masterPubKeyBz); err != nil {
+		return fmt.Errorf("failed to unmarshal master public key: %w", err)
+	}
+	return bls.Verify(suite.(pairing.Suite), masterPubKey, signature.SrcPayload, signature.Signature)
+}
+
 func (c *BaseClient) ProcessMessage(message storage.Message) error {
+	messageLogger := c.Logger.withMessage(message)
+
 	// save broadcasted reconstructed signature
 	if fsm.Event(message.Event) == types.SignatureReconstructed {
-		if err := c.processSignature(message); err != nil {
+		signature, err := c.processReconstructedSignature(message)
+		if err != nil {
 			return fmt.Errorf("failed to process signature: %w", err)
 		}
 		if err := c.state.SaveOffset(message.Offset + 1); err != nil {
 			return fmt.Errorf("failed to SaveOffset: %w", err)
 		}
+		c.metrics.signaturesReconstructed.Inc()
+		c.publishEvents(messageProcessedEvent(message), api.Event{
+			Type:       api.EventSignatureReconstructed,
+			DKGRoundID: message.DkgRoundID,
+			Sender:     message.SenderAddr,
+			SigningID:  signature.SigningID,
+		})
+		return nil
+	}
+
+	// the airgapped machine of the sender failed to reconstruct the signature, the FSM is not affected
+	if fsm.Event(message.Event) == types.SignatureReconstructionFailed {
+		var reconstructionErr types.SignatureReconstructionError
+		if err := json.Unmarshal(message.Data, &reconstructionErr); err != nil {
+			return fmt.Errorf("failed to unmarshal signature reconstruction error: %w", err)
+		}
+		if err := c.state.SaveOffset(message.Offset + 1); err != nil {
+			return fmt.Errorf("failed to SaveOffset: %w", err)
+		}
+		messageLogger.with("signing_id", reconstructionErr.SigningID).with("error", reconstructionErr.Error).
+			Warn("Participant failed to reconstruct the signature")
+		c.publishEvents(messageProcessedEvent(message), api.Event{
+			Type:       api.EventSignatureReconstructionFailed,
+			DKGRoundID: message.DkgRoundID,
+			Sender:     message.SenderAddr,
+			SigningID:  reconstructionErr.SigningID,
+		})
 		return nil
 	}
 
@@ -167,6 +327,16 @@ func (c *BaseClient) ProcessMessage(message storage.Message) error {
 	if err != nil {
 		return fmt.Errorf("failed to getFSMInstance: %w", err)
 	}
+	previousState, err := fsmInstance.State()
+	if err != nil {
+		return fmt.Errorf("failed to get FSM state: %w", err)
+	}
+	// events are published after the new state is saved
+	events := []api.Event{messageProcessedEvent(message)}
+	trackState := func(state fsm.State) {
+		events = append(events, stateChangeEvents(message.DkgRoundID, previousState, state)...)
+		previousState = state
+	}
 
 	// we can't verify a message at this moment, cause we don't have public keys of participantss
 	if fsm.Event(message.Event) != spf.EventInitProposal {
@@ -184,8 +354,19 @@ func (c *BaseClient) ProcessMessage(message storage.Message) error {
 	if err != nil {
 		return fmt.Errorf("failed to Do operation in FSM: %w", err)
 	}
+	trackState(resp.State)
+
+	messageLogger.with("state", resp.State).Debug("Message is applied to the FSM")
 
-	c.Logger.Log("message %s done successfully from %s", message.Event, message.SenderAddr)
+	// save signed messages which will be passed to the airgapped machine as a proof of the operation payload
+	for _, event := range operationSourceEvents {
+		if fsm.Event(message.Event) == event {
+			if err := c.state.SaveMessage(message); err != nil {
+				return fmt.Errorf("failed to SaveMessage: %w", err)
+			}
+			break
+		}
+	}
 
 	// switch FSM state by hand due to implementation specifics
 	if resp.State == spf.StateSignatureProposalCollected {
@@ -199,21 +380,36 @@ func (c *BaseClient) ProcessMessage(message storage.Message) error {
 		if err != nil {
 			return fmt.Errorf("failed to Do operation in FSM: %w", err)
 		}
+		trackState(resp.State)
 	}
 	if resp.State == dpf.StateDkgMasterKeyCollected {
 		fsmInstance, err = state_machines.FromDump(fsmDump)
 		if err != nil {
 			return fmt.Errorf("failed get state_machines from dump: %w", err)
 		}
+		// the public polynomial is needed to reconstruct signatures without the airgapped machine
+		if err = c.registerDKGKey(fsmInstance, time.Now()); err != nil {
+			messageLogger.with("error", err).Warn("Failed to register the key of the DKG round")
+		}
 		resp, fsmDump, err = fsmInstance.Do(sipf.EventSigningInit, requests.DefaultRequest{
 			CreatedAt: time.Now(),
 		})
 		if err != nil {
 			return fmt.Errorf("failed to Do operation in FSM: %w", err)
 		}
+		trackState(resp.State)
 	}
 
-	var operation *types.Operation
+	var (
+		operation              *types.Operation
+		reconstructedSignature *storage.Message
+		reconstructedSigningID string
+		policyDecision         *types.PolicyDecision
+		policyDecline          *storage.Message
+		declinedSigningID      string
+		policyInitiator        string
+		policyRequestedAt      time.Time
+	)
 	switch resp.State {
 	// if the new state is waiting for RPC to airgapped machine
 	case
@@ -236,6 +432,35 @@ func (c *BaseClient) ProcessMessage(message storage.Message) error {
 				if initiator.Username == c.GetUsername() {
 					break
 				}
+				if c.signingPolicy != nil {
+					policyInitiator, policyRequestedAt = initiator.Username, time.Now()
+					decision := c.signingPolicy.evaluate(signingRequest{
+						DKGRoundID: message.DkgRoundID,
+						Initiator:  initiator.Username,
+						Payload:    data.SrcPayload,
+					}, policyRequestedAt)
+					if policyDecline, err = c.applySigningPolicy(messageLogger, message.DkgRoundID, data, decision); err != nil {
+						return fmt.Errorf("failed to apply signing policy: %w", err)
+					}
+					if policyDecline != nil {
+						declinedSigningID = data.SigningId
+						break
+					}
+					// operations allowed by default are not marked
+					if decision.Action != types.PolicyAllow || decision.Rule != "" {
+						policyDecision = &decision
+					}
+				}
+			}
+
+			// partial signs are verified by the FSM, so the signature can be reconstructed right here
+			if data, ok := resp.Data.(responses.SigningProcessParticipantResponse); ok && !c.airgappedReconstruction {
+				reconstructedSignature, err = c.reconstructSignature(fsmInstance, data)
+				if err == nil {
+					reconstructedSigningID = data.SigningId
+					break
+				}
+				messageLogger.with("error", err).Warn("Failed to reconstruct signature, passing partial signs to the airgapped machine")
 			}
 
 			bz, err := json.Marshal(resp.Data)
@@ -249,10 +474,11 @@ func (c *BaseClient) ProcessMessage(message storage.Message) error {
 				Payload:       bz,
 				DKGIdentifier: message.DkgRoundID,
 				CreatedAt:     time.Now(),
+				Policy:        policyDecision,
 			}
 		}
 	default:
-		c.Logger.Log("State %s does not require an operation", resp.State)
+		messageLogger.with("state", resp.State).Debug("State does not require an operation")
 	}
 
 	// switch FSM state by hand due to implementation specifics
@@ -267,12 +493,60 @@ func (c *BaseClient) ProcessMessage(message storage.Message) error {
 		if err != nil {
 			return fmt.Errorf("failed to Do operation in FSM: %w", err)
 		}
+		trackState(resp.State)
 	}
 
 	if operation != nil {
+		if operation.SourceMessages, err = c.getSourceMessages(operation); err != nil {
+			return fmt.Errorf("failed to get source messages: %w", err)
+		}
+		if err := c.signOperation(operation); err != nil {
+			return fmt.Errorf("failed to sign operation: %w", err)
+		}
 		if err := c.state.PutOperation(operation); err != nil {
 			return fmt.Errorf("failed to PutOperation: %w", err)
 		}
+		events = append(events, api.Event{
+			Type:          api.EventOperationCreated,
+			DKGRoundID:    operation.DKGIdentifier,
+			OperationID:   operation.ID,
+			OperationType: string(operation.Type),
+		})
+		c.audit(api.AuditRecord{
+			Action:        api.AuditOperationCreated,
+			DKGRoundID:    operation.DKGIdentifier,
+			OperationID:   operation.ID,
+			OperationType: string(operation.Type),
+		})
+		messageLogger.with("operation_id", operation.ID).
+			with("operation_type", operation.Type).
+			Info("Operation is created")
+	}
+
+	// the decline and the reconstructed signature are sent before the offset is saved,
+	// so the message is processed again if the sending fails
+	if policyDecline != nil {
+		if err := c.SendMessage(*policyDecline); err != nil {
+			return fmt.Errorf("failed to send signing decline: %w", err)
+		}
+		c.audit(api.AuditRecord{
+			Action:        api.AuditSigningDeclined,
+			DKGRoundID:    policyDecline.DkgRoundID,
+			SigningID:     declinedSigningID,
+			MessageEvents: []string{policyDecline.Event},
+		})
+	}
+
+	if reconstructedSignature != nil {
+		if err := c.SendMessage(*reconstructedSignature); err != nil {
+			return fmt.Errorf("failed to send reconstructed signature: %w", err)
+		}
+		c.audit(api.AuditRecord{
+			Action:        api.AuditSignatureBroadcast,
+			DKGRoundID:    reconstructedSignature.DkgRoundID,
+			SigningID:     reconstructedSigningID,
+			MessageEvents: []string{reconstructedSignature.Event},
+		})
 	}
 
 	if err := c.state.SaveOffset(message.Offset + 1); err != nil {
@@ -282,6 +556,11 @@ func (c *BaseClient) ProcessMessage(message storage.Message) error {
 	if err := c.state.SaveFSM(message.DkgRoundID, fsmDump); err != nil {
 		return fmt.Errorf("failed to SaveFSM: %w", err)
 	}
+	// the request counts for rate limits only when the message is processed, so it is not counted twice on retries
+	if policyInitiator != "" {
+		c.signingPolicy.record(policyInitiator, policyRequestedAt)
+	}
+	c.publishEvents(events...)
 
 	return nil
 }
@@ -290,7 +569,7 @@ func (c *BaseClient) GetOperations() (map[string]*types.Operation, error) {
 	return c.state.GetOperations()
 }
 
-//GetSignatures returns all signatures for the given DKG round that were reconstructed on the airgapped machine and
+//GetSignatures returns all signatures for the given DKG round that were reconstructed by participants and
 // broadcasted by users
 func (c *BaseClient) GetSignatures(dkgID string) (map[string][]types.ReconstructedSignature, error) {
 	return c.state.GetSignatures(dkgID)
@@ -316,8 +595,8 @@ func (c *BaseClient) getOperationJSON(operationID string) ([]byte, error) {
 }
 
 // handleProcessedOperation handles an operation which was processed by the airgapped machine
-// It checks that the operation exists in an operation pool, signs the operation, sends it to an append-only log and
-// deletes it from the pool.
+// It checks that the operation exists in an operation pool and was signed by our airgapped machine,
+// signs the operation, sends it to an append-only log and deletes it from the pool.
 func (c *BaseClient) handleProcessedOperation(operation types.Operation) error {
 	storedOperation, err := c.state.GetOperationByID(operation.ID)
 	if err != nil {
@@ -327,6 +606,16 @@ func (c *BaseClient) handleProcessedOperation(operation types.Operation) error {
 	if err := storedOperation.Check(&operation); err != nil {
 		return fmt.Errorf("processed operation does not match stored operation: %w", err)
 	}
+	if storedOperation.Policy.AwaitsApproval() {
+		return fmt.Errorf("operation %s is not approved by a second approver", operation.ID)
+	}
+
+	if c.airgappedPubKey == nil {
+		return errors.New("airgapped public key is not pinned, restart the node with the airgapped public key")
+	}
+	if err := operation.VerifyResultSignature(c.airgappedPubKey); err != nil {
+		return fmt.Errorf("failed to verify processed operation: %w", err)
+	}
 
 	for i, message := range operation.ResultMsgs {
 		message.SenderAddr = c.GetUsername()
@@ -343,10 +632,31 @@ func (c *BaseClient) handleProcessedOperation(operation types.Operation) error {
 	if _, err := c.storage.SendBatch(operation.ResultMsgs...); err != nil {
 		return fmt.Errorf("failed to post messages: %w", err)
 	}
+	messageEvents := make([]string, 0, len(operation.ResultMsgs))
+	for _, message := range operation.ResultMsgs {
+		messageEvents = append(messageEvents, message.Event)
+	}
+	c.audit(api.AuditRecord{
+		Action:        api.AuditOperationHandled,
+		DKGRoundID:    operation.DKGIdentifier,
+		OperationID:   operation.ID,
+		OperationType: string(operation.Type),
+		MessageEvents: messageEvents,
+	})
 
 	if err := c.state.DeleteOperation(operation.ID); err != nil {
 		return fmt.Errorf("failed to DeleteOperation: %w", err)
 	}
+	c.publishEvents(api.Event{
+		Type:          api.EventOperationHandled,
+		DKGRoundID:    operation.DKGIdentifier,
+		OperationID:   operation.ID,
+		OperationType: string(operation.Type),
+	})
+	c.Logger.with("dkg_round_id", operation.DKGIdentifier).
+		with("operation_id", operation.ID).
+		with("operation_type", operation.Type).
+		Info("Result of the operation is sent to the append-only log")
 
 	return nil
 }
@@ -385,6 +695,50 @@ func (c *BaseClient) signMessage(message []byte) ([]byte, error) {
 	return ed25519.Sign(keyPair.Priv, message), nil
 }
 
+// getSourceMessages returns signed bulletin board messages the operation payload was built from
+func (c *BaseClient) getSourceMessages(operation *types.Operation) ([]storage.Message, error) {
+	event, ok := operationSourceEvents[fsm.State(operation.Type)]
+	if !ok {
+		return nil, nil
+	}
+
+	messages, err := c.state.GetMessages(operation.DKGIdentifier, event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to GetMessages: %w", err)
+	}
+
+	if event != sipf.EventSigningPartialSignReceived {
+		return messages, nil
+	}
+
+	// partial signatures of all signing processes are kept together, so take only the current ones
+	var payload responses.SigningProcessParticipantResponse
+	if err = json.Unmarshal(operation.Payload, &payload); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal payload: %w", err)
+	}
+	var signingMessages []storage.Message
+	for _, message := range messages {
+		var req requests.SigningProposalPartialSignRequest
+		if err = json.Unmarshal(message.Data, &req); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal partial sign request: %w", err)
+		}
+		if req.SigningId == payload.SigningId {
+			signingMessages = append(signingMessages, message)
+		}
+	}
+	return signingMessages, nil
+}
+
+// signOperation signs the operation with the hot node key, so the airgapped machine can check the operation origin
+func (c *BaseClient) signOperation(operation *types.Operation) error {
+	keyPair, err := c.keyStore.LoadKeys(c.userName, "")
+	if err != nil {
+		return fmt.Errorf("failed to LoadKeys: %w", err)
+	}
+
+	return operation.Sign(keyPair.Priv)
+}
+
 func (c *BaseClient) verifyMessage(fsmInstance *state_machines.FSMInstance, message storage.Message) error {
 	senderPubKey, err := fsmInstance.GetPubKeyByUsername(message.SenderAddr)
 	if err != nil {
@@ -0,0 +1,206 @@
+package client
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/corestario/kyber"
+	"github.com/corestario/kyber/pairing/bls12381"
+	"github.com/corestario/kyber/share"
+	"github.com/lidofinance/dc4bc/client/types"
+	"github.com/lidofinance/dc4bc/dkg"
+	"github.com/lidofinance/dc4bc/fsm/state_machines"
+)
+
+// buildDKGKey builds the public polynomial and the public key of a finished DKG round from the FSM dump.
+// The polynomial is built from the broadcasted commits, so it is checked against the confirmed master public key.
+func buildDKGKey(fsmInstance *state_machines.FSMInstance) (*types.DKGKey, *share.PubPoly, error) {
+	dump := fsmInstance.FSMDump()
+	if dump.Payload.DKGProposalPayload == nil || dump.Payload.SignatureProposalPayload == nil {
+		return nil, nil, errors.New("DKG round is not started")
+	}
+	masterPubKey, err := fsmInstance.GetMasterPubKey()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get master public key: %w", err)
+	}
+
+	suite := bls12381.NewBLS12381Suite(nil)
+
+	// share indexes follow the order of participant ids
+	participantIDs := make([]int, 0, len(dump.Payload.DKGProposalPayload.Quorum))
+	for id := range dump.Payload.DKGProposalPayload.Quorum {
+		participantIDs = append(participantIDs, id)
+	}
+	sort.Ints(participantIDs)
+
+	commits := make([][]kyber.Point, 0, len(participantIDs))
+	for _, id := range participantIDs {
+		participantCommits, err := dkg.UnmarshalCommits(suite, dump.Payload.DKGProposalPayload.Quorum[id].DkgCommit)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get commits of participant %d: %w", id, err)
+		}
+		commits = append(commits, participantCommits)
+	}
+	pubPoly, err := dkg.PubPolyFromCommits(suite, commits)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to build public polynomial: %w", err)
+	}
+
+	commitBz, err := pubPoly.Commit().MarshalBinary()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal public polynomial commit: %w", err)
+	}
+	if !bytes.Equal(commitBz, masterPubKey) {
+		return nil, nil, errors.New("public polynomial does not match the master public key")
+	}
+
+	key := &types.DKGKey{
+		DKGRoundID:   fsmInstance.Id(),
+		MasterPubKey: masterPubKey,
+		PrysmPubKey:  "0x" + hex.EncodeToString(masterPubKey),
+		Threshold:    pubPoly.Threshold(),
+		Participants: make([]types.DKGKeyParticipant, 0, len(participantIDs)),
+	}
+	for index, id := range participantIDs {
+		pubShare, err := pubPoly.Eval(index).V.MarshalBinary()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal public share: %w", err)
+		}
+		key.Participants = append(key.Participants, types.DKGKeyParticipant{
+			ParticipantID: id,
+			Username:      dump.Payload.DKGProposalPayload.Quorum[id].Username,
+			Index:         index,
+			PubShare:      pubShare,
+		})
+	}
+	return key, pubPoly, nil
+}
+
+// registerDKGKey saves the public polynomial and the public key of a finished DKG round,
+// so the node is able to reconstruct threshold signatures and list the keys without the airgapped machine
+func (c *BaseClient) registerDKGKey(fsmInstance *state_machines.FSMInstance, createdAt time.Time) error {
+	key, pubPoly, err := buildDKGKey(fsmInstance)
+	if err != nil {
+		return err
+	}
+
+	_, pubPolyCommits := pubPoly.Info()
+	pubPolyBz, err := dkg.MarshalCommits(pubPolyCommits)
+	if err != nil {
+		return fmt.Errorf("failed to marshal public polynomial: %w", err)
+	}
+	if err = c.state.SavePubPoly(key.DKGRoundID, pubPolyBz); err != nil {
+		return fmt.Errorf("failed to save public polynomial: %w", err)
+	}
+
+	key.CreatedAt = createdAt
+	if err = c.state.SaveDKGKey(key); err != nil {
+		return fmt.Errorf("failed to save DKG key: %w", err)
+	}
+	return nil
+}
+
+// loadPubPoly returns the public polynomial saved by registerDKGKey when the DKG round finished
+func (c *BaseClient) loadPubPoly(dkgRoundID string) (*share.PubPoly, error) {
+	pubPolyBz, err := c.state.LoadPubPoly(dkgRoundID)
+	if err != nil {
+		return nil, err
+	}
+	suite := bls12381.NewBLS12381Suite(nil)
+	commits, err := dkg.UnmarshalCommits(suite, pubPolyBz)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal public polynomial: %w", err)
+	}
+	return share.NewPubPoly(suite, nil, commits), nil
+}
+
+// dkgFinishedAt returns the time the last master key of the DKG round was confirmed
+func dkgFinishedAt(fsmInstance *state_machines.FSMInstance) time.Time {
+	var finishedAt time.Time
+	for _, participant := range fsmInstance.FSMDump().Payload.DKGProposalPayload.Quorum {
+		if participant.UpdatedAt.After(finishedAt) {
+			finishedAt = participant.UpdatedAt
+		}
+	}
+	return finishedAt
+}
+
+// registerMissingDKGKeys registers keys of DKG rounds finished before the registry was introduced,
+// it is called once before the client starts to poll messages. A round which fails is logged and skipped.
+func (c *BaseClient) registerMissingDKGKeys() error {
+	fsmInstances, err := c.state.GetAllFSM()
+	if err != nil {
+		return fmt.Errorf("failed to get all FSM instances: %w", err)
+	}
+	keys, err := c.state.GetDKGKeys()
+	if err != nil {
+		return fmt.Errorf("failed to get DKG keys: %w", err)
+	}
+
+	for dkgRoundID, fsmInstance := range fsmInstances {
+		if _, ok := keys[dkgRoundID]; ok {
+			continue
+		}
+		if _, err = fsmInstance.GetMasterPubKey(); err != nil {
+			continue
+		}
+		if err = c.registerDKGKey(fsmInstance, dkgFinishedAt(fsmInstance)); err != nil {
+			c.Logger.with("dkg_round_id", dkgRoundID).with("error", err).Warn("Failed to register the key of the DKG round")
+		}
+	}
+	return nil
+}
+
+// GetDKGKeys returns public keys of finished DKG rounds. The registry is checked against the FSM dumps,
+// a key which does not match its DKG round is logged and skipped.
+func (c *BaseClient) GetDKGKeys() ([]*types.DKGKey, error) {
+	fsmInstances, err := c.state.GetAllFSM()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get all FSM instances: %w", err)
+	}
+	keys, err := c.state.GetDKGKeys()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get DKG keys: %w", err)
+	}
+
+	result := make([]*types.DKGKey, 0, len(keys))
+	for dkgRoundID, key := range keys {
+		if err = checkDKGKey(key, fsmInstances[dkgRoundID]); err != nil {
+			c.Logger.with("dkg_round_id", dkgRoundID).with("error", err).Warn("Skipped the key of the DKG round")
+			continue
+		}
+		result = append(result, key)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.Before(result[j].CreatedAt)
+	})
+	return result, nil
+}
+
+// checkDKGKey checks the registered key against the FSM dump of its DKG round
+func checkDKGKey(key *types.DKGKey, fsmInstance *state_machines.FSMInstance) error {
+	if fsmInstance == nil {
+		return errors.New("DKG key has no FSM instance")
+	}
+	if _, err := fsmInstance.GetMasterPubKey(); err != nil {
+		return fmt.Errorf("DKG key is registered, but the DKG round is not finished: %w", err)
+	}
+	expectedKey, _, err := buildDKGKey(fsmInstance)
+	if err != nil {
+		return fmt.Errorf("failed to build DKG key: %w", err)
+	}
+	if err = key.Check(expectedKey); err != nil {
+		return fmt.Errorf("DKG key does not match the FSM state: %w", err)
+	}
+	for _, participant := range fsmInstance.FSMDump().Payload.SignatureProposalPayload.Quorum {
+		if participant.Threshold != key.Threshold {
+			return fmt.Errorf("DKG key threshold %d does not match the FSM threshold %d", key.Threshold, participant.Threshold)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,238 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lidofinance/dc4bc/client/api"
+	"github.com/lidofinance/dc4bc/fsm/fsm"
+	dpf "github.com/lidofinance/dc4bc/fsm/state_machines/dkg_proposal_fsm"
+	"github.com/lidofinance/dc4bc/storage"
+)
+
+const (
+	// eventsBufferSize is the number of recent events kept to resume event streams
+	eventsBufferSize = 1000
+	// eventsSubscriberBuffer is the number of events a subscriber may lag behind before it is disconnected
+	eventsSubscriberBuffer = 100
+	// eventsKeepAlivePeriod is the period of comments sent to idle event streams to keep connections open
+	eventsKeepAlivePeriod = 15 * time.Second
+)
+
+// eventBus assigns offsets to events, keeps recent events and delivers new events to subscribers.
+// The offset of the last event is saved to the state, so offsets keep growing after the node restarts.
+type eventBus struct {
+	sync.Mutex
+	state       State
+	nextOffset  uint64
+	recent      []api.Event
+	subscribers map[chan api.Event]struct{}
+}
+
+// newEventBus creates a bus which continues offsets saved to the state, nil state keeps offsets in memory only
+func newEventBus(state State) (*eventBus, error) {
+	b := &eventBus{
+		state:       state,
+		nextOffset:  1,
+		subscribers: make(map[chan api.Event]struct{}),
+	}
+	if state != nil {
+		lastOffset, err := state.LoadEventsOffset()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load events offset: %w", err)
+		}
+		b.nextOffset = lastOffset + 1
+	}
+	return b, nil
+}
+
+// publish assigns offsets to the events and delivers them to subscribers, subscribers which can't keep up
+// are disconnected and have to resume the stream. The events are not delivered if their offsets are not saved,
+// otherwise the offsets could be assigned again after a restart.
+func (b *eventBus) publish(events ...api.Event) error {
+	b.Lock()
+	defer b.Unlock()
+
+	if len(events) == 0 {
+		return nil
+	}
+	if b.state != nil {
+		if err := b.state.SaveEventsOffset(b.nextOffset + uint64(len(events)) - 1); err != nil {
+			return err
+		}
+	}
+
+	for _, event := range events {
+		event.Offset = b.nextOffset
+		b.nextOffset++
+		if event.CreatedAt.IsZero() {
+			event.CreatedAt = time.Now()
+		}
+
+		b.recent = append(b.recent, event)
+		if len(b.recent) > eventsBufferSize {
+			b.recent = b.recent[len(b.recent)-eventsBufferSize:]
+		}
+
+		for ch := range b.subscribers {
+			select {
+			case ch <- event:
+			default:
+				delete(b.subscribers, ch)
+				close(ch)
+			}
+		}
+	}
+	return nil
+}
+
+// publishEvents publishes the events of the client. The events describe what has already happened, so
+// a failure is logged and does not fail the action.
+func (c *BaseClient) publishEvents(events ...api.Event) {
+	if err := c.events.publish(events...); err != nil {
+		c.Logger.with("error", err).Error("Failed to publish events")
+	}
+}
+
+// subscribe returns a channel of new events and, if replay is set, kept events with offsets greater than
+// fromOffset. If fromOffset is ahead of the bus, e.g. the state of the node was reset, all kept events are returned.
+func (b *eventBus) subscribe(fromOffset uint64, replay bool) ([]api.Event, chan api.Event) {
+	b.Lock()
+	defer b.Unlock()
+
+	var past []api.Event
+	if replay {
+		for _, event := range b.recent {
+			if event.Offset > fromOffset || fromOffset >= b.nextOffset {
+				past = append(past, event)
+			}
+		}
+	}
+	ch := make(chan api.Event, eventsSubscriberBuffer)
+	b.subscribers[ch] = struct{}{}
+	return past, ch
+}
+
+func (b *eventBus) unsubscribe(ch chan api.Event) {
+	b.Lock()
+	defer b.Unlock()
+
+	if _, ok := b.subscribers[ch]; ok {
+		delete(b.subscribers, ch)
+		close(ch)
+	}
+}
+
+func messageProcessedEvent(message storage.Message) api.Event {
+	return api.Event{
+		Type:          api.EventMessageProcessed,
+		DKGRoundID:    message.DkgRoundID,
+		MessageOffset: message.Offset,
+		MessageEvent:  message.Event,
+		Sender:        message.SenderAddr,
+	}
+}
+
+// stateChangeEvents returns events of the FSM state change of the DKG round
+func stateChangeEvents(dkgRoundID string, previousState, state fsm.State) []api.Event {
+	if previousState == state {
+		return nil
+	}
+	events := []api.Event{{
+		Type:          api.EventStateChanged,
+		DKGRoundID:    dkgRoundID,
+		PreviousState: string(previousState),
+		State:         string(state),
+	}}
+	var outcomes []api.EventType
+	if strings.Contains(string(state), "timeout") {
+		outcomes = append(outcomes, api.EventTimeout)
+	}
+	switch {
+	case state == dpf.StateDkgMasterKeyCollected:
+		outcomes = append(outcomes, api.EventRoundCompleted)
+	case strings.Contains(string(state), "canceled") || strings.Contains(string(state), "cancelled"):
+		outcomes = append(outcomes, api.EventRoundFailed)
+	}
+	for _, eventType := range outcomes {
+		events = append(events, api.Event{
+			Type:          eventType,
+			DKGRoundID:    dkgRoundID,
+			PreviousState: string(previousState),
+			State:         string(state),
+		})
+	}
+	return events
+}
+
+func writeEvent(w http.ResponseWriter, event api.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.Offset, event.Type, data)
+	return err
+}
+
+// watchEventsV1 streams events as server-sent events. The stream is resumed after the offset
+// from the Last-Event-ID header or the "from" query parameter.
+func (c *BaseClient) watchEventsV1(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		apiErrorResponse(w, api.ErrorInternal, "streaming is not supported")
+		return
+	}
+
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("from")
+	}
+	var fromOffset uint64
+	if lastEventID != "" {
+		var err error
+		if fromOffset, err = strconv.ParseUint(lastEventID, 10, 64); err != nil {
+			apiErrorResponse(w, api.ErrorInvalidRequest, fmt.Sprintf("invalid event offset: %v", err))
+			return
+		}
+	}
+
+	past, events := c.events.subscribe(fromOffset, lastEventID != "")
+	defer c.events.unsubscribe(events)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	for _, event := range past {
+		if err := writeEvent(w, event); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(eventsKeepAlivePeriod)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case event, ok := <-events:
+			if !ok {
+				// the subscriber lagged behind, it resumes the stream after reconnecting
+				return
+			}
+			if err := writeEvent(w, event); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
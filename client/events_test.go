@@ -0,0 +1,127 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/lidofinance/dc4bc/client/api"
+	"github.com/lidofinance/dc4bc/fsm/fsm"
+	dpf "github.com/lidofinance/dc4bc/fsm/state_machines/dkg_proposal_fsm"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventBus_Subscribe(t *testing.T) {
+	req := require.New(t)
+
+	bus, err := newEventBus(nil)
+	req.NoError(err)
+	for i := 0; i < eventsBufferSize+10; i++ {
+		bus.publish(api.Event{Type: api.EventMessageProcessed, MessageOffset: uint64(i)})
+	}
+
+	past, ch := bus.subscribe(0, false)
+	req.Empty(past)
+	bus.unsubscribe(ch)
+
+	past, ch = bus.subscribe(eventsBufferSize, true)
+	req.Len(past, 10)
+	req.Equal(uint64(eventsBufferSize+1), past[0].Offset)
+	bus.unsubscribe(ch)
+
+	// the offset is ahead of the bus after the state of the node is reset, so the stream starts over
+	past, ch = bus.subscribe(eventsBufferSize*10, true)
+	req.Len(past, eventsBufferSize)
+	req.Equal(uint64(11), past[0].Offset)
+	bus.unsubscribe(ch)
+
+	// a subscriber which does not read events is disconnected
+	_, ch = bus.subscribe(0, false)
+	for i := 0; i <= eventsSubscriberBuffer; i++ {
+		bus.publish(api.Event{Type: api.EventMessageProcessed})
+	}
+	for range ch {
+	}
+	bus.unsubscribe(ch)
+}
+
+func TestEventBus_Restart(t *testing.T) {
+	req := require.New(t)
+	dbPath, err := ioutil.TempDir("", "dc4bc_test_event_bus")
+	req.NoError(err)
+	defer os.RemoveAll(dbPath)
+	state, err := NewLevelDBState(dbPath)
+	req.NoError(err)
+
+	bus, err := newEventBus(state)
+	req.NoError(err)
+	req.NoError(bus.publish(api.Event{Type: api.EventMessageProcessed}, api.Event{Type: api.EventStateChanged}))
+
+	// the offsets continue after a restart of the node, so a resumed stream does not get the same offsets again
+	bus, err = newEventBus(state)
+	req.NoError(err)
+	past, ch := bus.subscribe(2, true)
+	req.Empty(past)
+	req.NoError(bus.publish(api.Event{Type: api.EventOperationCreated}))
+	event := <-ch
+	req.Equal(uint64(3), event.Offset)
+	bus.unsubscribe(ch)
+}
+
+func TestStateChangeEvents(t *testing.T) {
+	req := require.New(t)
+
+	req.Empty(stateChangeEvents("round", "state_a", "state_a"))
+
+	events := stateChangeEvents("round", "state_a", "state_b")
+	req.Len(events, 1)
+	req.Equal(api.EventStateChanged, events[0].Type)
+
+	events = stateChangeEvents("round", "state_a", fsm.State("state_dkg_commits_await_canceled_by_timeout"))
+	req.Len(events, 3)
+	req.Equal(api.EventTimeout, events[1].Type)
+	req.Equal(api.EventRoundFailed, events[2].Type)
+
+	events = stateChangeEvents("round", "state_a", dpf.StateDkgMasterKeyCollected)
+	req.Len(events, 2)
+	req.Equal(api.EventRoundCompleted, events[1].Type)
+}
+
+func TestAPIV1_WatchEvents(t *testing.T) {
+	req := require.New(t)
+
+	c, cleanup := newTestAPIClient(t)
+	defer cleanup()
+	server := httptest.NewServer(c.httpHandler())
+	defer server.Close()
+	nodeClient := api.NewClient(server.URL)
+
+	c.events.publish(api.Event{Type: api.EventOperationCreated, OperationID: "op_1"})
+	c.events.publish(api.Event{Type: api.EventOperationHandled, OperationID: "op_1"})
+
+	errStop := errors.New("stop")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var received []api.Event
+	err := nodeClient.Watch(ctx, 1, func(event api.Event) error {
+		received = append(received, event)
+		if len(received) == 1 {
+			c.events.publish(api.Event{Type: api.EventSignatureReconstructed, SigningID: "signing"})
+		}
+		if len(received) == 2 {
+			return errStop
+		}
+		return nil
+	})
+	req.Equal(errStop, err)
+	req.Len(received, 2)
+	req.Equal(uint64(2), received[0].Offset)
+	req.Equal(api.EventOperationHandled, received[0].Type)
+	req.Equal(uint64(3), received[1].Offset)
+	req.Equal("signing", received[1].SigningID)
+}
@@ -0,0 +1,21 @@
+package client
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/lidofinance/dc4bc/storage"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestClient builds a client with NewClient, the keys of the user are kept in a keystore inside dir
+func newTestClient(t *testing.T, ctx context.Context, dir, username string, state State, stg storage.Storage) *BaseClient {
+	keyStore, err := NewLevelDBKeyStore(username, filepath.Join(dir, "keystore_"+username))
+	require.NoError(t, err)
+	require.NoError(t, keyStore.PutKeys(username, NewKeyPair()))
+
+	c, err := NewClient(ctx, username, state, stg, keyStore, nil)
+	require.NoError(t, err)
+	return c.(*BaseClient)
+}
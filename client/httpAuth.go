@@ -0,0 +1,228 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/lidofinance/dc4bc/client/api"
+)
+
+// APIScope is a set of HTTP API endpoints a bearer token grants access to
+type APIScope string
+
+const (
+	// APIScopeRead allows to read the node state
+	APIScopeRead APIScope = "read"
+	// APIScopeOperator allows to change the node state and to post messages signed with the hot key,
+	// it includes the read scope
+	APIScopeOperator APIScope = "operator"
+	// APIScopeApprover allows to approve operations held by the signing policy, it includes the read scope.
+	// The operator scope does not include it, so an approval needs a second person.
+	APIScopeApprover APIScope = "approver"
+)
+
+// APIToken is a bearer token of the HTTP API
+type APIToken struct {
+	Scope APIScope
+	// Name identifies the holder of the token, approvals of operations are recorded under it
+	Name string
+}
+
+// apiTokenKey is the key of the bearer token of an authenticated request in the request context
+type apiTokenKey struct{}
+
+// apiTokenFromContext returns the bearer token the request was authenticated with, there is no token
+// when authentication is disabled
+func apiTokenFromContext(ctx context.Context) (APIToken, bool) {
+	token, ok := ctx.Value(apiTokenKey{}).(APIToken)
+	return token, ok
+}
+
+// endpointScopes maps a deprecated endpoint to the scope required to call it, endpoints which are not listed
+// require the operator scope
+var endpointScopes = map[string]APIScope{
+	"/getUsername":      APIScopeRead,
+	"/getPubKey":        APIScopeRead,
+	"/getOperations":    APIScopeRead,
+	"/getOperation":     APIScopeRead,
+	"/getSignatures":    APIScopeRead,
+	"/getSignatureByID": APIScopeRead,
+	"/getKeys":          APIScopeRead,
+	"/getOffset":        APIScopeRead,
+	"/getFSMDump":       APIScopeRead,
+	"/getFSMList":       APIScopeRead,
+	"/metrics":          APIScopeRead,
+
+	"/sendMessage":                  APIScopeOperator,
+	"/handleProcessedOperationJSON": APIScopeOperator,
+	"/startDKG":                     APIScopeOperator,
+	"/proposeSignMessage":           APIScopeOperator,
+	"/saveOffset":                   APIScopeOperator,
+}
+
+// requiredScope returns the scope required by the endpoint of the request. GET requests of the v1 API
+// require the read scope, approvals of operations require the approver scope and other requests
+// of the v1 API require the operator scope.
+func requiredScope(r *http.Request) APIScope {
+	endpoint := path.Clean(r.URL.Path)
+	if strings.HasPrefix(endpoint, apiV1Prefix+"/") {
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			return APIScopeRead
+		}
+		if strings.HasPrefix(endpoint, apiV1Prefix+"/operations/") && strings.HasSuffix(endpoint, "/approve") {
+			return APIScopeApprover
+		}
+		return APIScopeOperator
+	}
+	if scope, ok := endpointScopes[endpoint]; ok {
+		return scope
+	}
+	return APIScopeOperator
+}
+
+func (s APIScope) allows(required APIScope) bool {
+	switch {
+	case s == required:
+		return true
+	case required == APIScopeApprover:
+		return false
+	case required == APIScopeRead:
+		return s == APIScopeOperator || s == APIScopeApprover
+	}
+	return s == APIScopeOperator
+}
+
+// HTTPAuthConfig configures authentication of the HTTP API. TLS is enabled when the certificate is set,
+// client certificates signed by ClientCAFile are required when it is set (mTLS).
+// Bearer tokens are required when Tokens are set.
+type HTTPAuthConfig struct {
+	TLSCertFile  string
+	TLSKeyFile   string
+	ClientCAFile string
+	Tokens       map[string]APIToken
+}
+
+// LoadAPITokens reads bearer tokens from a file, every line of the file is "<scope> <token> [<name>]",
+// empty lines and lines starting with # are skipped. Tokens of the approver scope must have a name,
+// since approvals are recorded under it.
+func LoadAPITokens(filename string) (map[string]APIToken, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open tokens file: %w", err)
+	}
+	defer file.Close()
+
+	tokens := make(map[string]APIToken)
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 && len(fields) != 3 {
+			return nil, fmt.Errorf("invalid token at line %d: expected \"<scope> <token> [<name>]\"", lineNumber)
+		}
+		token := APIToken{Scope: APIScope(fields[0])}
+		if len(fields) == 3 {
+			token.Name = fields[2]
+		}
+		if token.Scope != APIScopeRead && token.Scope != APIScopeOperator && token.Scope != APIScopeApprover {
+			return nil, fmt.Errorf("invalid token at line %d: unknown scope %s", lineNumber, token.Scope)
+		}
+		if token.Scope == APIScopeApprover && token.Name == "" {
+			return nil, fmt.Errorf("invalid token at line %d: approver token has no name", lineNumber)
+		}
+		tokens[fields[1]] = token
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read tokens file: %w", err)
+	}
+	return tokens, nil
+}
+
+func (cfg HTTPAuthConfig) tlsConfig() (*tls.Config, error) {
+	if cfg.TLSCertFile == "" {
+		if cfg.ClientCAFile != "" {
+			return nil, errors.New("client certificates require TLS to be enabled")
+		}
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if cfg.ClientCAFile != "" {
+		caBz, err := ioutil.ReadFile(cfg.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client CA: %w", err)
+		}
+		clientCAs := x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(caBz) {
+			return nil, errors.New("failed to parse client CA")
+		}
+		tlsConfig.ClientCAs = clientCAs
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tlsConfig, nil
+}
+
+// token returns the bearer token of the request
+func (cfg HTTPAuthConfig) token(r *http.Request) (APIToken, bool) {
+	bearer := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if bearer == "" {
+		return APIToken{}, false
+	}
+
+	var (
+		token APIToken
+		found bool
+	)
+	// all tokens are compared to not leak which of them matched through timing
+	for knownBearer, knownToken := range cfg.Tokens {
+		if subtle.ConstantTimeCompare([]byte(bearer), []byte(knownBearer)) == 1 {
+			token, found = knownToken, true
+		}
+	}
+	return token, found
+}
+
+// authMiddleware checks that the bearer token of the request has the scope required by the endpoint
+func (cfg HTTPAuthConfig) authMiddleware(next http.Handler) http.Handler {
+	if len(cfg.Tokens) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		isV1 := strings.HasPrefix(path.Clean(r.URL.Path), apiV1Prefix+"/")
+
+		token, ok := cfg.token(r)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			if isV1 {
+				apiErrorResponse(w, api.ErrorUnauthorized, "invalid or missing bearer token")
+			} else {
+				errorResponse(w, http.StatusUnauthorized, "invalid or missing bearer token")
+			}
+			return
+		}
+
+		if required := requiredScope(r); !token.Scope.allows(required) {
+			message := fmt.Sprintf("token scope %s does not allow %s %s", token.Scope, r.Method, r.URL.Path)
+			if isV1 {
+				apiErrorResponse(w, api.ErrorForbidden, message)
+			} else {
+				errorResponse(w, http.StatusForbidden, message)
+			}
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), apiTokenKey{}, token)))
+	})
+}
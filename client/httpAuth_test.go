@@ -0,0 +1,96 @@
+package client
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadAPITokens(t *testing.T) {
+	req := require.New(t)
+
+	dir, err := ioutil.TempDir("", "dc4bc_test_api_tokens")
+	req.NoError(err)
+	defer os.RemoveAll(dir)
+
+	tokensFile := filepath.Join(dir, "tokens")
+	req.NoError(ioutil.WriteFile(tokensFile, []byte("# tokens\nread read_token\n\noperator operator_token john_doe\napprover approver_token jane_doe\n"), 0600))
+
+	tokens, err := LoadAPITokens(tokensFile)
+	req.NoError(err)
+	req.Equal(map[string]APIToken{
+		"read_token":     {Scope: APIScopeRead},
+		"operator_token": {Scope: APIScopeOperator, Name: "john_doe"},
+		"approver_token": {Scope: APIScopeApprover, Name: "jane_doe"},
+	}, tokens)
+
+	for _, invalid := range []string{"admin admin_token\n", "approver approver_token\n", "read read_token name extra\n"} {
+		req.NoError(ioutil.WriteFile(tokensFile, []byte(invalid), 0600))
+		_, err = LoadAPITokens(tokensFile)
+		req.Error(err, invalid)
+	}
+}
+
+func TestHTTPAuthConfig_AuthMiddleware(t *testing.T) {
+	req := require.New(t)
+
+	cfg := HTTPAuthConfig{
+		Tokens: map[string]APIToken{
+			"read_token":     {Scope: APIScopeRead},
+			"operator_token": {Scope: APIScopeOperator},
+			"approver_token": {Scope: APIScopeApprover, Name: "jane_doe"},
+		},
+	}
+	handler := cfg.authMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	testCases := []struct {
+		method string
+		path   string
+		token  string
+		status int
+	}{
+		{http.MethodGet, "/getOperations", "", http.StatusUnauthorized},
+		{http.MethodGet, "/getOperations", "unknown_token", http.StatusUnauthorized},
+		{http.MethodGet, "/getOperations", "read_token", http.StatusOK},
+		{http.MethodGet, "//getOperations", "read_token", http.StatusOK},
+		{http.MethodGet, "/getOperations", "operator_token", http.StatusOK},
+		{http.MethodPost, "/startDKG", "read_token", http.StatusForbidden},
+		{http.MethodPost, "/startDKG", "operator_token", http.StatusOK},
+		{http.MethodGet, "/unknownEndpoint", "read_token", http.StatusForbidden},
+		{http.MethodGet, "/v1/operations", "read_token", http.StatusOK},
+		{http.MethodPost, "/v1/rounds", "read_token", http.StatusForbidden},
+		{http.MethodPost, "/v1/rounds", "operator_token", http.StatusOK},
+		{http.MethodPut, "/v1/offset", "", http.StatusUnauthorized},
+		{http.MethodPost, "/v1/operations/id/approve", "operator_token", http.StatusForbidden},
+		{http.MethodPost, "/v1/operations/id/approve", "approver_token", http.StatusOK},
+		{http.MethodGet, "/v1/operations", "approver_token", http.StatusOK},
+		{http.MethodPost, "/v1/rounds", "approver_token", http.StatusForbidden},
+	}
+	for _, tc := range testCases {
+		r := httptest.NewRequest(tc.method, tc.path, nil)
+		if tc.token != "" {
+			r.Header.Set("Authorization", "Bearer "+tc.token)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		req.Equal(tc.status, w.Code, "%s %s with token %q", tc.method, tc.path, tc.token)
+	}
+}
+
+func TestHTTPAuthConfig_TLSConfig(t *testing.T) {
+	req := require.New(t)
+
+	tlsConfig, err := HTTPAuthConfig{}.tlsConfig()
+	req.NoError(err)
+	req.Nil(tlsConfig)
+
+	_, err = HTTPAuthConfig{ClientCAFile: "ca.pem"}.tlsConfig()
+	req.Error(err)
+}
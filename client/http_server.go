@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lidofinance/dc4bc/client/api"
 	"github.com/lidofinance/dc4bc/client/types"
 	"github.com/lidofinance/dc4bc/fsm/fsm"
 	spf "github.com/lidofinance/dc4bc/fsm/state_machines/signature_proposal_fsm"
@@ -18,6 +19,7 @@ import (
 	"github.com/lidofinance/dc4bc/fsm/types/requests"
 
 	"github.com/lidofinance/dc4bc/storage"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 type Response struct {
@@ -58,32 +60,66 @@ func successResponse(w http.ResponseWriter, response interface{}) {
 	}
 }
 
-func (c *BaseClient) StartHTTPServer(listenAddr string) error {
+// handleDeprecated registers an endpoint which is kept for compatibility, responses point to the v1 endpoint replacing it
+func handleDeprecated(mux *http.ServeMux, pattern, successor string, handler http.HandlerFunc) {
+	mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", successor))
+		handler(w, r)
+	})
+}
+
+// httpHandler returns the handler of the HTTP API: the v1 API and the deprecated endpoints
+func (c *BaseClient) httpHandler() http.Handler {
 	mux := http.NewServeMux()
 
-	mux.HandleFunc("/getUsername", c.getUsernameHandler)
-	mux.HandleFunc("/getPubKey", c.getPubkeyHandler)
+	mux.Handle(apiV1Prefix+"/", c.apiV1Handler())
+	mux.Handle("/metrics", promhttp.HandlerFor(c.metrics.registry, promhttp.HandlerOpts{}))
+
+	// deprecated endpoints, they are kept until clients move to the v1 API
+	handleDeprecated(mux, "/getUsername", "/v1/node", c.getUsernameHandler)
+	handleDeprecated(mux, "/getPubKey", "/v1/node", c.getPubkeyHandler)
 
-	mux.HandleFunc("/sendMessage", c.sendMessageHandler)
-	mux.HandleFunc("/getOperations", c.getOperationsHandler)
+	handleDeprecated(mux, "/sendMessage", "/v1/messages", c.sendMessageHandler)
+	handleDeprecated(mux, "/getOperations", "/v1/operations", c.getOperationsHandler)
 
-	mux.HandleFunc("/getSignatures", c.getSignaturesHandler)
-	mux.HandleFunc("/getSignatureByID", c.getSignatureByIDHandler)
+	handleDeprecated(mux, "/getSignatures", "/v1/rounds/{roundID}/signatures", c.getSignaturesHandler)
+	handleDeprecated(mux, "/getSignatureByID", "/v1/rounds/{roundID}/signatures/{signingID}", c.getSignatureByIDHandler)
 
-	mux.HandleFunc("/handleProcessedOperationJSON", c.handleJSONOperationHandler)
-	mux.HandleFunc("/getOperation", c.getOperationHandler)
+	handleDeprecated(mux, "/getKeys", "/v1/keys", c.getKeysHandler)
 
-	mux.HandleFunc("/startDKG", c.startDKGHandler)
-	mux.HandleFunc("/proposeSignMessage", c.proposeSignDataHandler)
+	handleDeprecated(mux, "/handleProcessedOperationJSON", "/v1/operations/{operationID}/result", c.handleJSONOperationHandler)
+	handleDeprecated(mux, "/getOperation", "/v1/operations/{operationID}", c.getOperationHandler)
 
-	mux.HandleFunc("/saveOffset", c.saveOffsetHandler)
-	mux.HandleFunc("/getOffset", c.getOffsetHandler)
+	handleDeprecated(mux, "/startDKG", "/v1/rounds", c.startDKGHandler)
+	handleDeprecated(mux, "/proposeSignMessage", "/v1/rounds/{roundID}/signatures", c.proposeSignDataHandler)
 
-	mux.HandleFunc("/getFSMDump", c.getFSMDumpHandler)
-	mux.HandleFunc("/getFSMList", c.getFSMList)
+	handleDeprecated(mux, "/saveOffset", "/v1/offset", c.saveOffsetHandler)
+	handleDeprecated(mux, "/getOffset", "/v1/offset", c.getOffsetHandler)
+
+	handleDeprecated(mux, "/getFSMDump", "/v1/rounds/{roundID}", c.getFSMDumpHandler)
+	handleDeprecated(mux, "/getFSMList", "/v1/rounds", c.getFSMList)
+
+	return c.httpAuth.authMiddleware(mux)
+}
 
-	c.Logger.Log("HTTP server started on address: %s", listenAddr)
-	return http.ListenAndServe(listenAddr, mux)
+func (c *BaseClient) StartHTTPServer(listenAddr string) error {
+	tlsConfig, err := c.httpAuth.tlsConfig()
+	if err != nil {
+		return fmt.Errorf("failed to init TLS: %w", err)
+	}
+	server := &http.Server{
+		Addr:      listenAddr,
+		Handler:   c.httpHandler(),
+		TLSConfig: tlsConfig,
+	}
+
+	if tlsConfig != nil {
+		c.Logger.Info("HTTPS server started on address: %s", listenAddr)
+		return server.ListenAndServeTLS(c.httpAuth.TLSCertFile, c.httpAuth.TLSKeyFile)
+	}
+	c.Logger.Info("HTTP server started on address: %s", listenAddr)
+	return server.ListenAndServe()
 }
 
 func (c *BaseClient) getFSMDumpHandler(w http.ResponseWriter, r *http.Request) {
@@ -171,7 +207,7 @@ func (c *BaseClient) saveOffsetHandler(w http.ResponseWriter, r *http.Request) {
 		errorResponse(w, http.StatusInternalServerError, fmt.Sprintf("offset cannot be null: %v", err))
 		return
 	}
-	if err = c.state.SaveOffset(req["offset"]); err != nil {
+	if err = c.saveOffset(req["offset"]); err != nil {
 		errorResponse(w, http.StatusInternalServerError, fmt.Sprintf("failed to save offset: %v", err))
 		return
 	}
@@ -249,6 +285,21 @@ func (c *BaseClient) getSignatureByIDHandler(w http.ResponseWriter, r *http.Requ
 	successResponse(w, signature)
 }
 
+func (c *BaseClient) getKeysHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		errorResponse(w, http.StatusBadRequest, "Wrong HTTP method")
+		return
+	}
+
+	keys, err := c.GetDKGKeys()
+	if err != nil {
+		errorResponse(w, http.StatusInternalServerError, fmt.Sprintf("failed to get keys: %v", err))
+		return
+	}
+
+	successResponse(w, keys)
+}
+
 func (c *BaseClient) getOperationHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		errorResponse(w, http.StatusBadRequest, "Wrong HTTP method")
@@ -277,14 +328,8 @@ func (c *BaseClient) startDKGHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	defer r.Body.Close()
 
-	dkgRoundID := md5.Sum(reqBody)
-	message, err := c.buildMessage(hex.EncodeToString(dkgRoundID[:]), spf.EventInitProposal, reqBody)
-	if err != nil {
-		errorResponse(w, http.StatusInternalServerError, fmt.Sprintf("failed to build message: %v", err))
-		return
-	}
-	if err = c.SendMessage(*message); err != nil {
-		errorResponse(w, http.StatusInternalServerError, fmt.Sprintf("failed to send message: %v", err))
+	if _, err = c.startDKG(reqBody); err != nil {
+		errorResponse(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 	successResponse(w, "ok")
@@ -308,39 +353,72 @@ func (c *BaseClient) proposeSignDataHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	fsmInstance, err := c.getFSMInstance(hex.EncodeToString(req["dkgID"]))
-	if err != nil {
-		errorResponse(w, http.StatusInternalServerError, fmt.Sprintf("failed to get FSM instance: %v", err))
+	if _, err = c.proposeSignData(hex.EncodeToString(req["dkgID"]), req["data"]); err != nil {
+		errorResponse(w, http.StatusInternalServerError, err.Error())
 		return
 	}
+	successResponse(w, "ok")
+}
+
+// saveOffset sets the offset of the next message to be processed, the change is recorded in the audit journal
+func (c *BaseClient) saveOffset(offset uint64) error {
+	previousOffset, err := c.state.LoadOffset()
+	if err != nil {
+		return fmt.Errorf("failed to load offset: %w", err)
+	}
+	if err = c.state.SaveOffset(offset); err != nil {
+		return err
+	}
+	c.audit(api.AuditRecord{Action: api.AuditOffsetChanged, PreviousOffset: &previousOffset, Offset: &offset})
+	return nil
+}
+
+// startDKG sends a proposal to start a DKG round, the round ID is the hash of the proposal
+func (c *BaseClient) startDKG(proposal []byte) (string, error) {
+	dkgRoundIDHash := md5.Sum(proposal)
+	dkgRoundID := hex.EncodeToString(dkgRoundIDHash[:])
+	message, err := c.buildMessage(dkgRoundID, spf.EventInitProposal, proposal)
+	if err != nil {
+		return "", fmt.Errorf("failed to build message: %w", err)
+	}
+	if err = c.SendMessage(*message); err != nil {
+		return "", fmt.Errorf("failed to send message: %w", err)
+	}
+	c.audit(api.AuditRecord{Action: api.AuditDKGStarted, DKGRoundID: dkgRoundID})
+	return dkgRoundID, nil
+}
+
+// proposeSignData sends a proposal to sign the data with the key of the DKG round and returns the signing ID
+func (c *BaseClient) proposeSignData(dkgRoundID string, data []byte) (string, error) {
+	fsmInstance, err := c.getFSMInstance(dkgRoundID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get FSM instance: %w", err)
+	}
 	participantID, err := fsmInstance.GetIDByUsername(c.GetUsername())
 	if err != nil {
-		errorResponse(w, http.StatusInternalServerError, fmt.Sprintf("failed to get participantID: %v", err))
-		return
+		return "", fmt.Errorf("failed to get participantID: %w", err)
 	}
 
 	messageDataSign := requests.SigningProposalStartRequest{
 		SigningID:     uuid.New().String(),
 		ParticipantId: participantID,
-		SrcPayload:    req["data"],
+		SrcPayload:    data,
 		CreatedAt:     time.Now(),
 	}
 	messageDataSignBz, err := json.Marshal(messageDataSign)
 	if err != nil {
-		errorResponse(w, http.StatusInternalServerError, fmt.Sprintf("failed to marshal SigningProposalStartRequest: %v", err))
-		return
+		return "", fmt.Errorf("failed to marshal SigningProposalStartRequest: %w", err)
 	}
 
-	message, err := c.buildMessage(hex.EncodeToString(req["dkgID"]), sif.EventSigningStart, messageDataSignBz)
+	message, err := c.buildMessage(dkgRoundID, sif.EventSigningStart, messageDataSignBz)
 	if err != nil {
-		errorResponse(w, http.StatusInternalServerError, fmt.Sprintf("failed to build message: %v", err))
-		return
+		return "", fmt.Errorf("failed to build message: %w", err)
 	}
 	if err = c.SendMessage(*message); err != nil {
-		errorResponse(w, http.StatusInternalServerError, fmt.Sprintf("failed to send message: %v", err))
-		return
+		return "", fmt.Errorf("failed to send message: %w", err)
 	}
-	successResponse(w, "ok")
+	c.audit(api.AuditRecord{Action: api.AuditSigningProposed, DKGRoundID: dkgRoundID, SigningID: messageDataSign.SigningID})
+	return messageDataSign.SigningID, nil
 }
 
 func (c *BaseClient) handleJSONOperationHandler(w http.ResponseWriter, r *http.Request) {
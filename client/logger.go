@@ -1,20 +1,196 @@
 package client
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lidofinance/dc4bc/storage"
+)
+
+// LogLevel is the severity of a log entry, entries below the configured level are dropped
+type LogLevel int
+
+const (
+	LogLevelDebug LogLevel = iota
+	LogLevelInfo
+	LogLevelWarn
+	LogLevelError
+)
+
+var logLevelNames = map[LogLevel]string{
+	LogLevelDebug: "debug",
+	LogLevelInfo:  "info",
+	LogLevelWarn:  "warn",
+	LogLevelError: "error",
+}
+
+func (l LogLevel) String() string {
+	if name, ok := logLevelNames[l]; ok {
+		return name
+	}
+	return fmt.Sprintf("level(%d)", int(l))
+}
+
+// ParseLogLevel parses a level name: debug, info, warn or error
+func ParseLogLevel(name string) (LogLevel, error) {
+	for level, levelName := range logLevelNames {
+		if strings.EqualFold(name, levelName) {
+			return level, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown log level: %s", name)
+}
+
+const (
+	LogFormatJSON = "json"
+	LogFormatText = "text"
 )
 
-// logger is a glorious logger implementation.
+// LoggerConfig configures the client logger
+type LoggerConfig struct {
+	Level  LogLevel
+	Format string
+	Output io.Writer
+}
+
+// logOutput is shared by a logger and all loggers derived from it
+type logOutput struct {
+	sync.Mutex
+	level  LogLevel
+	format string
+	w      io.Writer
+}
+
+type logField struct {
+	key   string
+	value interface{}
+}
+
+// logger writes leveled entries as JSON lines or text. Every entry has the username of the client
+// and the fields attached with with().
 type logger struct {
+	out      *logOutput
 	userName string
+	fields   []logField
 }
 
 func newLogger(username string) *logger {
 	return &logger{
+		out:      &logOutput{level: LogLevelInfo, format: LogFormatJSON, w: os.Stdout},
 		userName: username,
 	}
 }
 
+// configure changes the output of the logger and all loggers derived from it
+func (l *logger) configure(cfg LoggerConfig) error {
+	switch cfg.Format {
+	case "":
+		cfg.Format = LogFormatJSON
+	case LogFormatJSON, LogFormatText:
+	default:
+		return fmt.Errorf("unknown log format: %s", cfg.Format)
+	}
+	if cfg.Output == nil {
+		cfg.Output = os.Stdout
+	}
+
+	l.out.Lock()
+	defer l.out.Unlock()
+	l.out.level = cfg.Level
+	l.out.format = cfg.Format
+	l.out.w = cfg.Output
+	return nil
+}
+
+// with returns a logger which adds the field to every entry
+func (l *logger) with(key string, value interface{}) *logger {
+	fields := make([]logField, len(l.fields), len(l.fields)+1)
+	copy(fields, l.fields)
+	return &logger{
+		out:      l.out,
+		userName: l.userName,
+		fields:   append(fields, logField{key: key, value: value}),
+	}
+}
+
+// withMessage returns a logger which adds the fields of the append-only log message to every entry
+func (l *logger) withMessage(message storage.Message) *logger {
+	return l.with("dkg_round_id", message.DkgRoundID).
+		with("offset", message.Offset).
+		with("event", message.Event).
+		with("sender", message.SenderAddr)
+}
+
+func (l *logger) Debug(format string, args ...interface{}) {
+	l.write(LogLevelDebug, format, args...)
+}
+
+func (l *logger) Info(format string, args ...interface{}) {
+	l.write(LogLevelInfo, format, args...)
+}
+
+func (l *logger) Warn(format string, args ...interface{}) {
+	l.write(LogLevelWarn, format, args...)
+}
+
+func (l *logger) Error(format string, args ...interface{}) {
+	l.write(LogLevelError, format, args...)
+}
+
+// Log writes an info entry
 func (l *logger) Log(format string, args ...interface{}) {
-	fmt.Printf("[%s] %s\n", l.userName, fmt.Sprintf(format, args...))
+	l.write(LogLevelInfo, format, args...)
+}
+
+func (l *logger) write(level LogLevel, format string, args ...interface{}) {
+	l.out.Lock()
+	defer l.out.Unlock()
+	if level < l.out.level {
+		return
+	}
+
+	var (
+		now = time.Now().UTC()
+		msg = fmt.Sprintf(format, args...)
+		buf bytes.Buffer
+	)
+	if l.out.format == LogFormatText {
+		fmt.Fprintf(&buf, "%s %-5s [%s] %s", now.Format(time.RFC3339), strings.ToUpper(level.String()), l.userName, msg)
+		for _, field := range l.fields {
+			fmt.Fprintf(&buf, " %s=%v", field.key, field.value)
+		}
+	} else {
+		fields := append([]logField{
+			{key: "time", value: now.Format(time.RFC3339Nano)},
+			{key: "level", value: level.String()},
+			{key: "username", value: l.userName},
+			{key: "msg", value: msg},
+		}, l.fields...)
+		buf.WriteByte('{')
+		for i, field := range fields {
+			if err, ok := field.value.(error); ok {
+				field.value = err.Error()
+			}
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			key, _ := json.Marshal(field.key)
+			value, err := json.Marshal(field.value)
+			if err != nil {
+				value, _ = json.Marshal(fmt.Sprint(field.value))
+			}
+			buf.Write(key)
+			buf.WriteByte(':')
+			buf.Write(value)
+		}
+		buf.WriteByte('}')
+	}
+	buf.WriteByte('\n')
+	l.out.w.Write(buf.Bytes())
 }
@@ -0,0 +1,61 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/lidofinance/dc4bc/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := newLogger("test")
+	require.NoError(t, l.configure(LoggerConfig{Level: LogLevelInfo, Output: &buf}))
+
+	messageLogger := l.withMessage(storage.Message{
+		DkgRoundID: "dkg_id",
+		Offset:     42,
+		Event:      "event",
+		SenderAddr: "sender",
+	})
+	messageLogger.Debug("dropped")
+	messageLogger.with("operation_id", "operation").with("error", errors.New("failure")).Error("failed %d", 1)
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	require.NotEmpty(t, entry["time"])
+	delete(entry, "time")
+	require.Equal(t, map[string]interface{}{
+		"level":        "error",
+		"username":     "test",
+		"msg":          "failed 1",
+		"dkg_round_id": "dkg_id",
+		"offset":       float64(42),
+		"event":        "event",
+		"sender":       "sender",
+		"operation_id": "operation",
+		"error":        "failure",
+	}, entry)
+
+	// derived loggers share the output, so reconfiguring the client logger affects all of them
+	buf.Reset()
+	require.NoError(t, l.configure(LoggerConfig{Level: LogLevelDebug, Format: LogFormatText, Output: &buf}))
+	messageLogger.Debug("handling")
+	line := buf.String()
+	require.True(t, strings.HasSuffix(line, "DEBUG [test] handling dkg_round_id=dkg_id offset=42 event=event sender=sender\n"), line)
+
+	require.Error(t, l.configure(LoggerConfig{Format: "xml"}))
+}
+
+func TestParseLogLevel(t *testing.T) {
+	level, err := ParseLogLevel("WARN")
+	require.NoError(t, err)
+	require.Equal(t, LogLevelWarn, level)
+
+	_, err = ParseLogLevel("verbose")
+	require.Error(t, err)
+}
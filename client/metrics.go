@@ -0,0 +1,208 @@
+package client
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/lidofinance/dc4bc/storage"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const metricsNamespace = "dc4bc"
+
+// metrics are Prometheus metrics of the client, they are exported at /metrics
+type metrics struct {
+	registry *prometheus.Registry
+
+	// headOffset is the offset of the next message to be appended to the append-only log
+	headOffset uint64
+
+	messagesProcessed       *prometheus.CounterVec
+	messagesFailed          *prometheus.CounterVec
+	signaturesReconstructed prometheus.Counter
+	storageDuration         *prometheus.HistogramVec
+	storageErrors           *prometheus.CounterVec
+}
+
+func newMetrics(state State) *metrics {
+	m := &metrics{
+		registry: prometheus.NewRegistry(),
+		messagesProcessed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "messages_processed_total",
+			Help:      "Number of messages of the append-only log processed successfully.",
+		}, []string{"event"}),
+		messagesFailed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "messages_failed_total",
+			Help:      "Number of messages of the append-only log which failed to be processed.",
+		}, []string{"event"}),
+		signaturesReconstructed: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "signatures_reconstructed_total",
+			Help:      "Number of verified reconstructed signatures received from participants.",
+		}),
+		storageDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "storage_request_duration_seconds",
+			Help:      "Latency of requests to the append-only log.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"backend", "method"}),
+		storageErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "storage_errors_total",
+			Help:      "Number of failed requests to the append-only log.",
+		}, []string{"backend", "method"}),
+	}
+	m.registry.MustRegister(
+		m.messagesProcessed,
+		m.messagesFailed,
+		m.signaturesReconstructed,
+		m.storageDuration,
+		m.storageErrors,
+		&stateCollector{state: state, metrics: m},
+	)
+	return m
+}
+
+func (m *metrics) setHeadOffset(offset uint64) {
+	atomic.StoreUint64(&m.headOffset, offset)
+}
+
+var (
+	offsetDesc = prometheus.NewDesc(metricsNamespace+"_log_offset",
+		"Offset of the next message of the append-only log to be processed.", nil, nil)
+	headOffsetDesc = prometheus.NewDesc(metricsNamespace+"_log_head_offset",
+		"Offset of the next message to be appended to the append-only log as seen by the last poll.", nil, nil)
+	lagDesc = prometheus.NewDesc(metricsNamespace+"_log_lag",
+		"Number of messages of the append-only log which are not processed yet.", nil, nil)
+	pendingOperationsDesc = prometheus.NewDesc(metricsNamespace+"_pending_operations",
+		"Number of operations waiting for the airgapped machine.", []string{"type"}, nil)
+	pendingOperationAgeDesc = prometheus.NewDesc(metricsNamespace+"_pending_operation_max_age_seconds",
+		"Age of the oldest operation waiting for the airgapped machine.", []string{"type"}, nil)
+	fsmsDesc = prometheus.NewDesc(metricsNamespace+"_fsms",
+		"Number of FSMs of DKG rounds by state.", []string{"state"}, nil)
+)
+
+// stateCollector reads metrics from the client state when they are scraped
+type stateCollector struct {
+	state   State
+	metrics *metrics
+}
+
+func (sc *stateCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- offsetDesc
+	ch <- headOffsetDesc
+	ch <- lagDesc
+	ch <- pendingOperationsDesc
+	ch <- pendingOperationAgeDesc
+	ch <- fsmsDesc
+}
+
+func (sc *stateCollector) Collect(ch chan<- prometheus.Metric) {
+	if offset, err := sc.state.LoadOffset(); err == nil {
+		headOffset := atomic.LoadUint64(&sc.metrics.headOffset)
+		var lag uint64
+		if headOffset > offset {
+			lag = headOffset - offset
+		}
+		ch <- prometheus.MustNewConstMetric(offsetDesc, prometheus.GaugeValue, float64(offset))
+		ch <- prometheus.MustNewConstMetric(headOffsetDesc, prometheus.GaugeValue, float64(headOffset))
+		ch <- prometheus.MustNewConstMetric(lagDesc, prometheus.GaugeValue, float64(lag))
+	} else {
+		ch <- prometheus.NewInvalidMetric(offsetDesc, err)
+	}
+
+	if operations, err := sc.state.GetOperations(); err == nil {
+		counts := make(map[string]int)
+		maxAges := make(map[string]float64)
+		for _, operation := range operations {
+			operationType := string(operation.Type)
+			counts[operationType]++
+			if age := time.Since(operation.CreatedAt).Seconds(); age > maxAges[operationType] {
+				maxAges[operationType] = age
+			}
+		}
+		for operationType, count := range counts {
+			ch <- prometheus.MustNewConstMetric(pendingOperationsDesc, prometheus.GaugeValue, float64(count), operationType)
+			ch <- prometheus.MustNewConstMetric(pendingOperationAgeDesc, prometheus.GaugeValue, maxAges[operationType], operationType)
+		}
+	} else {
+		ch <- prometheus.NewInvalidMetric(pendingOperationsDesc, err)
+	}
+
+	if fsmInstances, err := sc.state.GetAllFSM(); err == nil {
+		counts := make(map[string]int)
+		for _, fsmInstance := range fsmInstances {
+			state, err := fsmInstance.State()
+			if err != nil {
+				continue
+			}
+			counts[string(state)]++
+		}
+		for state, count := range counts {
+			ch <- prometheus.MustNewConstMetric(fsmsDesc, prometheus.GaugeValue, float64(count), state)
+		}
+	} else {
+		ch <- prometheus.NewInvalidMetric(fsmsDesc, err)
+	}
+}
+
+// instrumentedStorage measures latency and errors of requests to the append-only log, the storage shared
+// by the participants hosted by one node records requests to the metrics of every participant
+type instrumentedStorage struct {
+	storage.Storage
+	backend string
+	metrics []*metrics
+}
+
+func newInstrumentedStorage(stg storage.Storage, m ...*metrics) storage.Storage {
+	backend := "unknown"
+	switch stg.(type) {
+	case *storage.KafkaStorage:
+		backend = "kafka"
+	case *storage.FileStorage:
+		backend = "file"
+	}
+	return &instrumentedStorage{Storage: stg, backend: backend, metrics: m}
+}
+
+func (s *instrumentedStorage) observe(method string, start time.Time, err error) {
+	duration := time.Since(start).Seconds()
+	for _, m := range s.metrics {
+		m.storageDuration.WithLabelValues(s.backend, method).Observe(duration)
+		if err != nil {
+			m.storageErrors.WithLabelValues(s.backend, method).Inc()
+		}
+	}
+}
+
+func (s *instrumentedStorage) Send(message storage.Message) (storage.Message, error) {
+	start := time.Now()
+	message, err := s.Storage.Send(message)
+	s.observe("send", start, err)
+	return message, err
+}
+
+func (s *instrumentedStorage) SendBatch(messages ...storage.Message) ([]storage.Message, error) {
+	start := time.Now()
+	messages, err := s.Storage.SendBatch(messages...)
+	s.observe("send_batch", start, err)
+	return messages, err
+}
+
+func (s *instrumentedStorage) GetMessages(offset uint64) ([]storage.Message, error) {
+	start := time.Now()
+	messages, err := s.Storage.GetMessages(offset)
+	s.observe("get_messages", start, err)
+	if err == nil {
+		headOffset := offset
+		if len(messages) > 0 {
+			headOffset = messages[len(messages)-1].Offset + 1
+		}
+		for _, m := range s.metrics {
+			m.setHeadOffset(headOffset)
+		}
+	}
+	return messages, err
+}
@@ -0,0 +1,59 @@
+package client
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lidofinance/dc4bc/client/types"
+	"github.com/lidofinance/dc4bc/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetrics(t *testing.T) {
+	req := require.New(t)
+
+	c, cleanup := newTestAPIClient(t)
+	defer cleanup()
+
+	dir, err := ioutil.TempDir("", "dc4bc_test_metrics")
+	req.NoError(err)
+	defer os.RemoveAll(dir)
+	stg, err := storage.NewFileStorage(filepath.Join(dir, "storage"), filepath.Join(dir, "storage.lock"))
+	req.NoError(err)
+	defer stg.Close()
+	c.storage = newInstrumentedStorage(stg, c.metrics)
+
+	for i := 0; i < 3; i++ {
+		req.NoError(c.SendMessage(storage.Message{Event: "event"}))
+	}
+	_, err = c.storage.GetMessages(0)
+	req.NoError(err)
+	req.NoError(c.state.SaveOffset(1))
+	req.NoError(c.state.PutOperation(&types.Operation{
+		ID:        "operation",
+		Type:      "state_dkg_commits_await_confirmations",
+		CreatedAt: time.Now().Add(-time.Minute),
+	}))
+	c.metrics.messagesProcessed.WithLabelValues("event").Inc()
+
+	w, _ := doAPIRequest(t, c.httpHandler(), http.MethodGet, "/metrics", nil)
+	req.Equal(http.StatusOK, w.Code)
+	body := w.Body.String()
+	for _, expected := range []string{
+		"dc4bc_log_offset 1\n",
+		"dc4bc_log_head_offset 3\n",
+		"dc4bc_log_lag 2\n",
+		`dc4bc_messages_processed_total{event="event"} 1` + "\n",
+		`dc4bc_pending_operations{type="state_dkg_commits_await_confirmations"} 1` + "\n",
+		`dc4bc_storage_request_duration_seconds_count{backend="file",method="send"} 3` + "\n",
+		`dc4bc_storage_request_duration_seconds_count{backend="file",method="get_messages"} 1` + "\n",
+	} {
+		req.True(strings.Contains(body, expected), "metric %q is not exported:\n%s", expected, body)
+	}
+	req.Contains(body, `dc4bc_pending_operation_max_age_seconds{type="state_dkg_commits_await_confirmations"} 6`)
+}
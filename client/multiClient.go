@@ -0,0 +1,156 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lidofinance/dc4bc/client/api"
+	"github.com/lidofinance/dc4bc/storage"
+)
+
+// MultiClient hosts several participants in one node. Every participant is a client with its own keys, state
+// and operation pool, the participants share one subscription to the append-only log and one HTTP server.
+type MultiClient struct {
+	ctx      context.Context
+	Logger   *logger
+	storage  storage.Storage
+	clients  []*BaseClient
+	httpAuth HTTPAuthConfig
+}
+
+// NewMultiClient creates a node hosting the clients, the clients must be created with NewClient
+func NewMultiClient(ctx context.Context, storage storage.Storage, clients ...Client) (*MultiClient, error) {
+	if len(clients) == 0 {
+		return nil, errors.New("no participants to host")
+	}
+	m := &MultiClient{
+		ctx:    ctx,
+		Logger: newLogger(""),
+	}
+	usernames := make(map[string]bool)
+	var clientsMetrics []*metrics
+	for _, cli := range clients {
+		c, ok := cli.(*BaseClient)
+		if !ok {
+			return nil, fmt.Errorf("unsupported client type %T", cli)
+		}
+		if c.GetUsername() == "" || strings.Contains(c.GetUsername(), "/") {
+			return nil, fmt.Errorf("invalid username: %q", c.GetUsername())
+		}
+		if usernames[c.GetUsername()] {
+			return nil, fmt.Errorf("participant %s is hosted twice", c.GetUsername())
+		}
+		usernames[c.GetUsername()] = true
+		m.clients = append(m.clients, c)
+		clientsMetrics = append(clientsMetrics, c.metrics)
+	}
+	m.storage = newInstrumentedStorage(storage, clientsMetrics...)
+	return m, nil
+}
+
+// SetHTTPAuth sets TLS of the HTTP server, bearer tokens are set for every participant with its SetHTTPAuth,
+// so a token gives access to the API of one participant only. It must be called before StartHTTPServer.
+func (m *MultiClient) SetHTTPAuth(cfg HTTPAuthConfig) {
+	m.httpAuth = cfg
+}
+
+// SetLogger sets the level, format and output of the log of the node
+func (m *MultiClient) SetLogger(cfg LoggerConfig) error {
+	return m.Logger.configure(cfg)
+}
+
+// Poll gets new messages from the append-only log once for all participants and passes every message
+// to the participants which have not processed it yet
+func (m *MultiClient) Poll() error {
+	for _, c := range m.clients {
+		if err := c.registerMissingDKGKeys(); err != nil {
+			return fmt.Errorf("failed to register DKG keys of %s: %w", c.GetUsername(), err)
+		}
+	}
+	tk := time.NewTicker(pollingPeriod)
+	for {
+		select {
+		case <-tk.C:
+			if err := m.poll(); err != nil {
+				return err
+			}
+		case <-m.ctx.Done():
+			m.Logger.Info("Context closed, stop polling...")
+			return nil
+		}
+	}
+}
+
+func (m *MultiClient) poll() error {
+	offsets := make([]uint64, len(m.clients))
+	minOffset := uint64(0)
+	for i, c := range m.clients {
+		offset, err := c.state.LoadOffset()
+		if err != nil {
+			return fmt.Errorf("failed to load offset of %s: %w", c.GetUsername(), err)
+		}
+		offsets[i] = offset
+		if i == 0 || offset < minOffset {
+			minOffset = offset
+		}
+	}
+
+	messages, err := m.storage.GetMessages(minOffset)
+	if err != nil {
+		return fmt.Errorf("failed to GetMessages: %w", err)
+	}
+
+	for i, c := range m.clients {
+		for _, message := range messages {
+			if message.Offset >= offsets[i] {
+				c.handleMessage(message)
+			}
+		}
+	}
+	return nil
+}
+
+// httpHandler serves the HTTP API of every participant under /participants/{username}
+func (m *MultiClient) httpHandler() http.Handler {
+	handlers := make(map[string]http.Handler, len(m.clients))
+	for _, c := range m.clients {
+		handlers[c.GetUsername()] = http.StripPrefix(api.ParticipantsPathPrefix+c.GetUsername(), c.httpHandler())
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, api.ParticipantsPathPrefix) {
+			apiErrorResponse(w, api.ErrorNotFound, fmt.Sprintf("path %s is not found, the API of a participant is "+
+				"served under %s{username}", r.URL.Path, api.ParticipantsPathPrefix))
+			return
+		}
+		username := strings.SplitN(strings.TrimPrefix(r.URL.Path, api.ParticipantsPathPrefix), "/", 2)[0]
+		handler, ok := handlers[username]
+		if !ok {
+			apiErrorResponse(w, api.ErrorNotFound, fmt.Sprintf("participant %s is not hosted by the node", username))
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+func (m *MultiClient) StartHTTPServer(listenAddr string) error {
+	tlsConfig, err := m.httpAuth.tlsConfig()
+	if err != nil {
+		return fmt.Errorf("failed to init TLS: %w", err)
+	}
+	server := &http.Server{
+		Addr:      listenAddr,
+		Handler:   m.httpHandler(),
+		TLSConfig: tlsConfig,
+	}
+
+	if tlsConfig != nil {
+		m.Logger.Info("HTTPS server started on address: %s", listenAddr)
+		return server.ListenAndServeTLS(m.httpAuth.TLSCertFile, m.httpAuth.TLSKeyFile)
+	}
+	m.Logger.Info("HTTP server started on address: %s", listenAddr)
+	return server.ListenAndServe()
+}
@@ -0,0 +1,159 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lidofinance/dc4bc/client/api"
+	"github.com/lidofinance/dc4bc/client/types"
+	"github.com/lidofinance/dc4bc/storage"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestMultiClient(t *testing.T, stg storage.Storage, usernames ...string) (*MultiClient, func()) {
+	dir, err := ioutil.TempDir("", "dc4bc_test_multi_client")
+	require.NoError(t, err)
+	states, err := NewLevelDBStates(filepath.Join(dir, "state"), usernames)
+	require.NoError(t, err)
+
+	var clients []Client
+	for i, username := range usernames {
+		clients = append(clients, newTestClient(t, context.Background(), dir, username, states[i], stg))
+	}
+	m, err := NewMultiClient(context.Background(), stg, clients...)
+	require.NoError(t, err)
+	return m, func() { os.RemoveAll(dir) }
+}
+
+func TestNewLevelDBStates(t *testing.T) {
+	req := require.New(t)
+	dir, err := ioutil.TempDir("", "dc4bc_test_states")
+	req.NoError(err)
+	defer os.RemoveAll(dir)
+
+	_, err = NewLevelDBStates(filepath.Join(dir, "invalid"), []string{"alice/bob"})
+	req.Error(err)
+
+	states, err := NewLevelDBStates(filepath.Join(dir, "state"), []string{"alice", "bob"})
+	req.NoError(err)
+	alice, bob := states[0], states[1]
+
+	req.NoError(alice.SaveOffset(5))
+	req.NoError(alice.PutOperation(&types.Operation{ID: "operation"}))
+
+	offset, err := bob.LoadOffset()
+	req.NoError(err)
+	req.Equal(uint64(0), offset)
+	operations, err := bob.GetOperations()
+	req.NoError(err)
+	req.Empty(operations)
+	operations, err = alice.GetOperations()
+	req.NoError(err)
+	req.Len(operations, 1)
+}
+
+func TestMigrateLevelDBState(t *testing.T) {
+	req := require.New(t)
+	dir, err := ioutil.TempDir("", "dc4bc_test_migrate_state")
+	req.NoError(err)
+	defer os.RemoveAll(dir)
+	stateDbPath := filepath.Join(dir, "state")
+
+	legacy, err := NewLevelDBState(stateDbPath)
+	req.NoError(err)
+	req.NoError(legacy.SaveOffset(5))
+	req.NoError(legacy.PutOperation(&types.Operation{ID: "operation"}))
+	req.NoError(legacy.SavePubPoly("dkg_id", []byte("pub_poly")))
+	req.NoError(legacy.(*LevelDBState).stateDb.Close())
+
+	// the state of a single-participant node is not hidden by the namespaces
+	_, err = NewLevelDBStates(stateDbPath, []string{"alice", "bob"})
+	req.True(errors.Is(err, ErrLegacyState))
+
+	req.Error(MigrateLevelDBState(stateDbPath, "alice/bob"))
+	req.NoError(MigrateLevelDBState(stateDbPath, "alice"))
+	req.NoError(MigrateLevelDBState(stateDbPath, "bob"))
+
+	states, err := NewLevelDBStates(stateDbPath, []string{"alice", "bob"})
+	req.NoError(err)
+	alice, bob := states[0], states[1]
+
+	offset, err := alice.LoadOffset()
+	req.NoError(err)
+	req.Equal(uint64(5), offset)
+	operations, err := alice.GetOperations()
+	req.NoError(err)
+	req.Len(operations, 1)
+	pubPoly, err := alice.LoadPubPoly("dkg_id")
+	req.NoError(err)
+	req.Equal([]byte("pub_poly"), pubPoly)
+
+	offset, err = bob.LoadOffset()
+	req.NoError(err)
+	req.Equal(uint64(0), offset)
+}
+
+func TestMultiClient_Poll(t *testing.T) {
+	req := require.New(t)
+	dir, err := ioutil.TempDir("", "dc4bc_test_multi_client_poll")
+	req.NoError(err)
+	defer os.RemoveAll(dir)
+	stg, err := storage.NewFileStorage(filepath.Join(dir, "storage"), filepath.Join(dir, "storage.lock"))
+	req.NoError(err)
+	defer stg.Close()
+
+	m, cleanup := newTestMultiClient(t, stg, "alice", "bob")
+	defer cleanup()
+	alice, bob := m.clients[0], m.clients[1]
+
+	for _, recipient := range []string{"", "", "alice", ""} {
+		_, err = stg.Send(storage.Message{Event: "event", RecipientAddr: recipient})
+		req.NoError(err)
+	}
+	req.NoError(bob.state.SaveOffset(2))
+
+	// the messages are not valid, so every message passed to a participant is counted as failed
+	req.NoError(m.poll())
+	req.Equal(float64(4), testutil.ToFloat64(alice.metrics.messagesFailed.WithLabelValues("event")))
+	req.Equal(float64(1), testutil.ToFloat64(bob.metrics.messagesFailed.WithLabelValues("event")))
+
+	// the shared request to the append-only log is recorded for every participant
+	for _, c := range m.clients {
+		req.Equal(1, testutil.CollectAndCount(c.metrics.storageDuration))
+		req.Equal(uint64(4), c.metrics.headOffset)
+	}
+}
+
+func TestMultiClient_HTTPHandler(t *testing.T) {
+	req := require.New(t)
+	m, cleanup := newTestMultiClient(t, nil, "alice", "bob")
+	defer cleanup()
+	m.clients[1].SetHTTPAuth(HTTPAuthConfig{Tokens: map[string]APIToken{"bob_token": {Scope: APIScopeRead}}})
+	handler := m.httpHandler()
+
+	w, _ := doAPIRequest(t, handler, http.MethodGet, api.ParticipantPath("alice")+"/v1/node", nil)
+	req.Equal(http.StatusOK, w.Code)
+	var resp api.Response
+	req.NoError(json.Unmarshal(w.Body.Bytes(), &resp))
+	resultBz, err := json.Marshal(resp.Result)
+	req.NoError(err)
+	var node api.NodeInfo
+	req.NoError(json.Unmarshal(resultBz, &node))
+	req.Equal("alice", node.Username)
+
+	// tokens of a participant are checked by the API of the participant only
+	w, _ = doAPIRequest(t, handler, http.MethodGet, api.ParticipantPath("bob")+"/v1/node", nil)
+	req.Equal(http.StatusUnauthorized, w.Code)
+
+	w, _ = doAPIRequest(t, handler, http.MethodGet, api.ParticipantPath("carol")+"/v1/node", nil)
+	req.Equal(http.StatusNotFound, w.Code)
+	w, _ = doAPIRequest(t, handler, http.MethodGet, "/v1/node", nil)
+	req.Equal(http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,1444 @@
+package client
+
+// openAPISpec is the OpenAPI document of the v1 API served at /v1/openapi.json
+const openAPISpec = `{
+  "openapi": "3.0.3",
+  "info": {
+    "title": "dc4bc node API",
+    "version": "1.0.0",
+    "description": "HTTP API of the dc4bc hot node. Every response is an object with either the result or the error field. Lists are paginated with the limit and offset query parameters. GET requests require the read scope, approvals of operations require the approver scope, other requests require the operator scope. When the node hosts several participants, the API of a participant is served under /participants/{username} and its tokens are valid for that participant only."
+  },
+  "servers": [
+    {
+      "url": "/"
+    }
+  ],
+  "security": [
+    {
+      "bearerAuth": []
+    }
+  ],
+  "paths": {
+    "/v1/openapi.json": {
+      "get": {
+        "summary": "OpenAPI document of the API",
+        "operationId": "getOpenAPISpec",
+        "responses": {
+          "200": {
+            "description": "OpenAPI document",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "object"
+                }
+              }
+            }
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "403": {
+            "$ref": "#/components/responses/Forbidden"
+          },
+          "500": {
+            "$ref": "#/components/responses/Internal"
+          }
+        }
+      }
+    },
+    "/v1/node": {
+      "get": {
+        "summary": "Username and communication public key of the node",
+        "operationId": "getNode",
+        "responses": {
+          "200": {
+            "description": "OK",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "object",
+                  "properties": {
+                    "result": {
+                      "$ref": "#/components/schemas/NodeInfo"
+                    }
+                  }
+                }
+              }
+            }
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "403": {
+            "$ref": "#/components/responses/Forbidden"
+          },
+          "500": {
+            "$ref": "#/components/responses/Internal"
+          }
+        }
+      }
+    },
+    "/v1/rounds": {
+      "get": {
+        "summary": "List DKG rounds",
+        "operationId": "listRounds",
+        "responses": {
+          "200": {
+            "description": "OK",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "object",
+                  "properties": {
+                    "result": {
+                      "allOf": [
+                        {
+                          "$ref": "#/components/schemas/Page"
+                        },
+                        {
+                          "type": "object",
+                          "properties": {
+                            "items": {
+                              "type": "array",
+                              "items": {
+                                "$ref": "#/components/schemas/Round"
+                              }
+                            }
+                          }
+                        }
+                      ]
+                    }
+                  }
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/InvalidRequest"
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "403": {
+            "$ref": "#/components/responses/Forbidden"
+          },
+          "500": {
+            "$ref": "#/components/responses/Internal"
+          }
+        },
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/limit"
+          },
+          {
+            "$ref": "#/components/parameters/offset"
+          }
+        ]
+      },
+      "post": {
+        "summary": "Start a DKG round",
+        "operationId": "startRound",
+        "responses": {
+          "202": {
+            "description": "The proposal is sent to the message board",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "object",
+                  "properties": {
+                    "result": {
+                      "$ref": "#/components/schemas/Round"
+                    }
+                  }
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/InvalidRequest"
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "403": {
+            "$ref": "#/components/responses/Forbidden"
+          },
+          "500": {
+            "$ref": "#/components/responses/Internal"
+          }
+        },
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "type": "object",
+                "description": "Signature proposal participants list, the round ID is the MD5 hash of the request body"
+              }
+            }
+          }
+        }
+      }
+    },
+    "/v1/rounds/{roundID}": {
+      "get": {
+        "summary": "FSM dump of the DKG round",
+        "operationId": "getRound",
+        "responses": {
+          "200": {
+            "description": "OK",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "object",
+                  "properties": {
+                    "result": {
+                      "type": "object",
+                      "description": "FSM dump"
+                    }
+                  }
+                }
+              }
+            }
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "403": {
+            "$ref": "#/components/responses/Forbidden"
+          },
+          "404": {
+            "$ref": "#/components/responses/NotFound"
+          },
+          "500": {
+            "$ref": "#/components/responses/Internal"
+          }
+        },
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/roundID"
+          }
+        ]
+      }
+    },
+    "/v1/rounds/{roundID}/signatures": {
+      "get": {
+        "summary": "List signatures reconstructed in the DKG round",
+        "operationId": "listSignatures",
+        "responses": {
+          "200": {
+            "description": "OK",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "object",
+                  "properties": {
+                    "result": {
+                      "allOf": [
+                        {
+                          "$ref": "#/components/schemas/Page"
+                        },
+                        {
+                          "type": "object",
+                          "properties": {
+                            "items": {
+                              "type": "array",
+                              "items": {
+                                "$ref": "#/components/schemas/Signing"
+                              }
+                            }
+                          }
+                        }
+                      ]
+                    }
+                  }
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/InvalidRequest"
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "403": {
+            "$ref": "#/components/responses/Forbidden"
+          },
+          "500": {
+            "$ref": "#/components/responses/Internal"
+          }
+        },
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/roundID"
+          },
+          {
+            "$ref": "#/components/parameters/limit"
+          },
+          {
+            "$ref": "#/components/parameters/offset"
+          }
+        ]
+      },
+      "post": {
+        "summary": "Propose signing of the data",
+        "operationId": "proposeSigning",
+        "responses": {
+          "202": {
+            "description": "The proposal is sent to the message board",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "object",
+                  "properties": {
+                    "result": {
+                      "$ref": "#/components/schemas/Signing"
+                    }
+                  }
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/InvalidRequest"
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "403": {
+            "$ref": "#/components/responses/Forbidden"
+          },
+          "404": {
+            "$ref": "#/components/responses/NotFound"
+          },
+          "500": {
+            "$ref": "#/components/responses/Internal"
+          }
+        },
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/roundID"
+          }
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/ProposeSigningRequest"
+              }
+            }
+          }
+        }
+      }
+    },
+    "/v1/rounds/{roundID}/signatures/{signingID}": {
+      "get": {
+        "summary": "Signatures reconstructed for the signing proposal",
+        "operationId": "getSignatures",
+        "responses": {
+          "200": {
+            "description": "OK",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "object",
+                  "properties": {
+                    "result": {
+                      "$ref": "#/components/schemas/Signing"
+                    }
+                  }
+                }
+              }
+            }
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "403": {
+            "$ref": "#/components/responses/Forbidden"
+          },
+          "404": {
+            "$ref": "#/components/responses/NotFound"
+          },
+          "500": {
+            "$ref": "#/components/responses/Internal"
+          }
+        },
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/roundID"
+          },
+          {
+            "$ref": "#/components/parameters/signingID"
+          }
+        ]
+      }
+    },
+    "/v1/operations": {
+      "get": {
+        "summary": "List pending operations for the airgapped machine",
+        "operationId": "listOperations",
+        "responses": {
+          "200": {
+            "description": "OK",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "object",
+                  "properties": {
+                    "result": {
+                      "allOf": [
+                        {
+                          "$ref": "#/components/schemas/Page"
+                        },
+                        {
+                          "type": "object",
+                          "properties": {
+                            "items": {
+                              "type": "array",
+                              "items": {
+                                "$ref": "#/components/schemas/Operation"
+                              }
+                            }
+                          }
+                        }
+                      ]
+                    }
+                  }
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/InvalidRequest"
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "403": {
+            "$ref": "#/components/responses/Forbidden"
+          },
+          "500": {
+            "$ref": "#/components/responses/Internal"
+          }
+        },
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/limit"
+          },
+          {
+            "$ref": "#/components/parameters/offset"
+          }
+        ]
+      }
+    },
+    "/v1/operations/{operationID}": {
+      "get": {
+        "summary": "Pending operation",
+        "operationId": "getOperation",
+        "responses": {
+          "200": {
+            "description": "OK",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "object",
+                  "properties": {
+                    "result": {
+                      "$ref": "#/components/schemas/Operation"
+                    }
+                  }
+                }
+              }
+            }
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "403": {
+            "$ref": "#/components/responses/Forbidden"
+          },
+          "404": {
+            "$ref": "#/components/responses/NotFound"
+          }
+        },
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/operationID"
+          }
+        ]
+      }
+    },
+    "/v1/operations/{operationID}/result": {
+      "post": {
+        "summary": "Handle the operation processed by the airgapped machine",
+        "operationId": "handleOperationResult",
+        "responses": {
+          "200": {
+            "description": "OK",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "object",
+                  "properties": {
+                    "result": {
+                      "type": "string",
+                      "description": "operation ID"
+                    }
+                  }
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/InvalidRequest"
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "403": {
+            "$ref": "#/components/responses/Forbidden"
+          },
+          "404": {
+            "$ref": "#/components/responses/NotFound"
+          }
+        },
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/operationID"
+          }
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/Operation"
+              }
+            }
+          }
+        }
+      }
+    },
+    "/v1/operations/{operationID}/approve": {
+      "post": {
+        "summary": "Approve the operation held by the signing policy, requires the approver scope",
+        "description": "The approval is recorded under the name of the bearer token. Responds with forbidden if the node has no API tokens.",
+        "operationId": "approveOperation",
+        "responses": {
+          "200": {
+            "description": "OK",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "object",
+                  "properties": {
+                    "result": {
+                      "$ref": "#/components/schemas/Operation"
+                    }
+                  }
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/InvalidRequest"
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "403": {
+            "$ref": "#/components/responses/Forbidden"
+          },
+          "404": {
+            "$ref": "#/components/responses/NotFound"
+          }
+        },
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/operationID"
+          }
+        ]
+      }
+    },
+    "/v1/keys": {
+      "get": {
+        "summary": "List public keys of finished DKG rounds",
+        "operationId": "listKeys",
+        "responses": {
+          "200": {
+            "description": "OK",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "object",
+                  "properties": {
+                    "result": {
+                      "allOf": [
+                        {
+                          "$ref": "#/components/schemas/Page"
+                        },
+                        {
+                          "type": "object",
+                          "properties": {
+                            "items": {
+                              "type": "array",
+                              "items": {
+                                "$ref": "#/components/schemas/DKGKey"
+                              }
+                            }
+                          }
+                        }
+                      ]
+                    }
+                  }
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/InvalidRequest"
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "403": {
+            "$ref": "#/components/responses/Forbidden"
+          },
+          "500": {
+            "$ref": "#/components/responses/Internal"
+          }
+        },
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/limit"
+          },
+          {
+            "$ref": "#/components/parameters/offset"
+          }
+        ]
+      }
+    },
+    "/v1/keys/{roundID}": {
+      "get": {
+        "summary": "Public key of the finished DKG round",
+        "operationId": "getKey",
+        "responses": {
+          "200": {
+            "description": "OK",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "object",
+                  "properties": {
+                    "result": {
+                      "$ref": "#/components/schemas/DKGKey"
+                    }
+                  }
+                }
+              }
+            }
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "403": {
+            "$ref": "#/components/responses/Forbidden"
+          },
+          "404": {
+            "$ref": "#/components/responses/NotFound"
+          },
+          "500": {
+            "$ref": "#/components/responses/Internal"
+          }
+        },
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/roundID"
+          }
+        ]
+      }
+    },
+    "/v1/messages": {
+      "post": {
+        "summary": "Send a message to the message board",
+        "operationId": "sendMessage",
+        "responses": {
+          "202": {
+            "description": "The message is sent",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "object",
+                  "properties": {
+                    "result": {
+                      "type": "string",
+                      "description": "message ID"
+                    }
+                  }
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/InvalidRequest"
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "403": {
+            "$ref": "#/components/responses/Forbidden"
+          },
+          "500": {
+            "$ref": "#/components/responses/Internal"
+          }
+        },
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/Message"
+              }
+            }
+          }
+        }
+      }
+    },
+    "/v1/events": {
+      "get": {
+        "summary": "Stream of node events as server-sent events",
+        "operationId": "watchEvents",
+        "description": "Every event is sent with its offset as the event ID. The stream is resumed after the offset from the Last-Event-ID header or the from query parameter, without them only new events are sent.",
+        "parameters": [
+          {
+            "name": "Last-Event-ID",
+            "in": "header",
+            "schema": {
+              "type": "integer",
+              "minimum": 0
+            }
+          },
+          {
+            "name": "from",
+            "in": "query",
+            "schema": {
+              "type": "integer",
+              "minimum": 0
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "Event stream, the data of every event is an Event object",
+            "content": {
+              "text/event-stream": {
+                "schema": {
+                  "$ref": "#/components/schemas/Event"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/InvalidRequest"
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "403": {
+            "$ref": "#/components/responses/Forbidden"
+          },
+          "500": {
+            "$ref": "#/components/responses/Internal"
+          }
+        }
+      }
+    },
+    "/v1/audit": {
+      "get": {
+        "summary": "List records of the audit journal",
+        "operationId": "listAuditRecords",
+        "responses": {
+          "200": {
+            "description": "OK",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "object",
+                  "properties": {
+                    "result": {
+                      "allOf": [
+                        {
+                          "$ref": "#/components/schemas/Page"
+                        },
+                        {
+                          "type": "object",
+                          "properties": {
+                            "items": {
+                              "type": "array",
+                              "items": {
+                                "$ref": "#/components/schemas/AuditRecord"
+                              }
+                            }
+                          }
+                        }
+                      ]
+                    }
+                  }
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/InvalidRequest"
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "403": {
+            "$ref": "#/components/responses/Forbidden"
+          },
+          "404": {
+            "$ref": "#/components/responses/NotFound"
+          },
+          "500": {
+            "$ref": "#/components/responses/Internal"
+          }
+        },
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/limit"
+          },
+          {
+            "$ref": "#/components/parameters/offset"
+          }
+        ],
+        "description": "The offset of a page is the index of its first record. Records are chained by hashes and signed with the communication key of the node, see NodeInfo.pub_key. Responds with not_found if the journal is disabled."
+      }
+    },
+    "/v1/offset": {
+      "get": {
+        "summary": "Offset of the next message to process",
+        "operationId": "getOffset",
+        "responses": {
+          "200": {
+            "description": "OK",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "object",
+                  "properties": {
+                    "result": {
+                      "$ref": "#/components/schemas/Offset"
+                    }
+                  }
+                }
+              }
+            }
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "403": {
+            "$ref": "#/components/responses/Forbidden"
+          },
+          "500": {
+            "$ref": "#/components/responses/Internal"
+          }
+        }
+      },
+      "put": {
+        "summary": "Set the offset of the next message to process",
+        "operationId": "saveOffset",
+        "responses": {
+          "200": {
+            "description": "OK",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "object",
+                  "properties": {
+                    "result": {
+                      "$ref": "#/components/schemas/Offset"
+                    }
+                  }
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/InvalidRequest"
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "403": {
+            "$ref": "#/components/responses/Forbidden"
+          },
+          "500": {
+            "$ref": "#/components/responses/Internal"
+          }
+        },
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/Offset"
+              }
+            }
+          }
+        }
+      }
+    }
+  },
+  "components": {
+    "securitySchemes": {
+      "bearerAuth": {
+        "type": "http",
+        "scheme": "bearer"
+      }
+    },
+    "parameters": {
+      "limit": {
+        "name": "limit",
+        "in": "query",
+        "schema": {
+          "type": "integer",
+          "minimum": 1,
+          "maximum": 1000,
+          "default": 100
+        }
+      },
+      "offset": {
+        "name": "offset",
+        "in": "query",
+        "schema": {
+          "type": "integer",
+          "minimum": 0,
+          "default": 0
+        }
+      },
+      "roundID": {
+        "name": "roundID",
+        "in": "path",
+        "required": true,
+        "schema": {
+          "type": "string"
+        }
+      },
+      "signingID": {
+        "name": "signingID",
+        "in": "path",
+        "required": true,
+        "schema": {
+          "type": "string"
+        }
+      },
+      "operationID": {
+        "name": "operationID",
+        "in": "path",
+        "required": true,
+        "schema": {
+          "type": "string"
+        }
+      }
+    },
+    "responses": {
+      "InvalidRequest": {
+        "description": "Invalid request (invalid_request)",
+        "content": {
+          "application/json": {
+            "schema": {
+              "$ref": "#/components/schemas/ErrorResponse"
+            }
+          }
+        }
+      },
+      "Unauthorized": {
+        "description": "Invalid or missing bearer token (unauthorized)",
+        "content": {
+          "application/json": {
+            "schema": {
+              "$ref": "#/components/schemas/ErrorResponse"
+            }
+          }
+        }
+      },
+      "Forbidden": {
+        "description": "The token scope does not allow the request (forbidden)",
+        "content": {
+          "application/json": {
+            "schema": {
+              "$ref": "#/components/schemas/ErrorResponse"
+            }
+          }
+        }
+      },
+      "NotFound": {
+        "description": "Resource not found (not_found)",
+        "content": {
+          "application/json": {
+            "schema": {
+              "$ref": "#/components/schemas/ErrorResponse"
+            }
+          }
+        }
+      },
+      "MethodNotAllowed": {
+        "description": "Method not allowed (method_not_allowed)",
+        "content": {
+          "application/json": {
+            "schema": {
+              "$ref": "#/components/schemas/ErrorResponse"
+            }
+          }
+        }
+      },
+      "Internal": {
+        "description": "Internal error (internal)",
+        "content": {
+          "application/json": {
+            "schema": {
+              "$ref": "#/components/schemas/ErrorResponse"
+            }
+          }
+        }
+      }
+    },
+    "schemas": {
+      "ErrorResponse": {
+        "type": "object",
+        "required": [
+          "error"
+        ],
+        "properties": {
+          "error": {
+            "$ref": "#/components/schemas/Error"
+          }
+        }
+      },
+      "Error": {
+        "type": "object",
+        "required": [
+          "code",
+          "message"
+        ],
+        "properties": {
+          "code": {
+            "type": "string",
+            "enum": [
+              "invalid_request",
+              "unauthorized",
+              "forbidden",
+              "not_found",
+              "method_not_allowed",
+              "internal"
+            ]
+          },
+          "message": {
+            "type": "string"
+          }
+        }
+      },
+      "Page": {
+        "type": "object",
+        "required": [
+          "items",
+          "total",
+          "offset",
+          "limit"
+        ],
+        "properties": {
+          "items": {
+            "type": "array",
+            "items": {}
+          },
+          "total": {
+            "type": "integer"
+          },
+          "offset": {
+            "type": "integer"
+          },
+          "limit": {
+            "type": "integer"
+          }
+        }
+      },
+      "NodeInfo": {
+        "type": "object",
+        "properties": {
+          "username": {
+            "type": "string"
+          },
+          "pub_key": {
+            "type": "string",
+            "format": "byte"
+          }
+        }
+      },
+      "Round": {
+        "type": "object",
+        "properties": {
+          "id": {
+            "type": "string"
+          },
+          "state": {
+            "type": "string"
+          }
+        }
+      },
+      "ReconstructedSignature": {
+        "type": "object",
+        "properties": {
+          "SigningID": {
+            "type": "string"
+          },
+          "SrcPayload": {
+            "type": "string",
+            "format": "byte"
+          },
+          "Signature": {
+            "type": "string",
+            "format": "byte"
+          },
+          "Username": {
+            "type": "string"
+          },
+          "DKGRoundID": {
+            "type": "string"
+          }
+        }
+      },
+      "Signing": {
+        "type": "object",
+        "properties": {
+          "signing_id": {
+            "type": "string"
+          },
+          "signatures": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/ReconstructedSignature"
+            }
+          }
+        }
+      },
+      "ProposeSigningRequest": {
+        "type": "object",
+        "required": [
+          "data"
+        ],
+        "properties": {
+          "data": {
+            "type": "string",
+            "format": "byte"
+          }
+        }
+      },
+      "PolicyDecision": {
+        "type": "object",
+        "properties": {
+          "Action": {
+            "type": "string",
+            "enum": [
+              "allow",
+              "decline",
+              "flag",
+              "require_approval"
+            ]
+          },
+          "Rule": {
+            "type": "string",
+            "description": "Matched rule, empty when the default action is applied"
+          },
+          "Reason": {
+            "type": "string"
+          },
+          "ApprovedBy": {
+            "type": "string"
+          },
+          "ApprovedAt": {
+            "type": "string",
+            "format": "date-time"
+          }
+        }
+      },
+      "Message": {
+        "type": "object",
+        "properties": {
+          "id": {
+            "type": "string"
+          },
+          "dkg_round_id": {
+            "type": "string"
+          },
+          "offset": {
+            "type": "integer"
+          },
+          "event": {
+            "type": "string"
+          },
+          "data": {
+            "type": "string",
+            "format": "byte"
+          },
+          "signature": {
+            "type": "string",
+            "format": "byte"
+          },
+          "sender": {
+            "type": "string"
+          },
+          "recipient": {
+            "type": "string"
+          }
+        }
+      },
+      "Operation": {
+        "type": "object",
+        "properties": {
+          "ID": {
+            "type": "string"
+          },
+          "Type": {
+            "type": "string"
+          },
+          "Payload": {
+            "type": "string",
+            "format": "byte"
+          },
+          "ResultMsgs": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/Message"
+            }
+          },
+          "CreatedAt": {
+            "type": "string",
+            "format": "date-time"
+          },
+          "DKGIdentifier": {
+            "type": "string"
+          },
+          "To": {
+            "type": "string"
+          },
+          "Event": {
+            "type": "string"
+          },
+          "SourceMessages": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/Message"
+            }
+          },
+          "Signature": {
+            "type": "string",
+            "format": "byte"
+          },
+          "ResultSignature": {
+            "type": "string",
+            "format": "byte"
+          },
+          "Policy": {
+            "$ref": "#/components/schemas/PolicyDecision"
+          }
+        }
+      },
+      "DKGKeyParticipant": {
+        "type": "object",
+        "properties": {
+          "ParticipantID": {
+            "type": "integer"
+          },
+          "Username": {
+            "type": "string"
+          },
+          "Index": {
+            "type": "integer"
+          },
+          "PubShare": {
+            "type": "string",
+            "format": "byte"
+          }
+        }
+      },
+      "DKGKey": {
+        "type": "object",
+        "properties": {
+          "DKGRoundID": {
+            "type": "string"
+          },
+          "MasterPubKey": {
+            "type": "string",
+            "format": "byte"
+          },
+          "PrysmPubKey": {
+            "type": "string"
+          },
+          "Threshold": {
+            "type": "integer"
+          },
+          "Participants": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/DKGKeyParticipant"
+            }
+          },
+          "CreatedAt": {
+            "type": "string",
+            "format": "date-time"
+          }
+        }
+      },
+      "Event": {
+        "type": "object",
+        "required": [
+          "offset",
+          "type",
+          "created_at"
+        ],
+        "properties": {
+          "offset": {
+            "type": "integer"
+          },
+          "type": {
+            "type": "string",
+            "enum": [
+              "message_processed",
+              "state_changed",
+              "operation_created",
+              "operation_handled",
+              "signature_reconstructed",
+              "signature_reconstruction_failed",
+              "timeout",
+              "round_completed",
+              "round_failed"
+            ]
+          },
+          "dkg_round_id": {
+            "type": "string"
+          },
+          "created_at": {
+            "type": "string",
+            "format": "date-time"
+          },
+          "message_offset": {
+            "type": "integer"
+          },
+          "message_event": {
+            "type": "string"
+          },
+          "sender": {
+            "type": "string"
+          },
+          "previous_state": {
+            "type": "string"
+          },
+          "state": {
+            "type": "string"
+          },
+          "operation_id": {
+            "type": "string"
+          },
+          "operation_type": {
+            "type": "string"
+          },
+          "signing_id": {
+            "type": "string"
+          }
+        }
+      },
+      "AuditRecord": {
+        "type": "object",
+        "required": [
+          "index",
+          "created_at",
+          "action",
+          "prev_hash",
+          "hash",
+          "signature"
+        ],
+        "properties": {
+          "index": {
+            "type": "integer"
+          },
+          "created_at": {
+            "type": "string",
+            "format": "date-time"
+          },
+          "action": {
+            "type": "string",
+            "enum": [
+              "dkg_started",
+              "signing_proposed",
+              "operation_created",
+              "operation_handled",
+              "signature_broadcast",
+              "offset_changed",
+              "signing_declined",
+              "operation_approved"
+            ]
+          },
+          "dkg_round_id": {
+            "type": "string"
+          },
+          "signing_id": {
+            "type": "string"
+          },
+          "operation_id": {
+            "type": "string"
+          },
+          "operation_type": {
+            "type": "string"
+          },
+          "message_events": {
+            "type": "array",
+            "items": {
+              "type": "string"
+            }
+          },
+          "previous_offset": {
+            "type": "integer"
+          },
+          "offset": {
+            "type": "integer"
+          },
+          "approver": {
+            "type": "string"
+          },
+          "prev_hash": {
+            "type": "string",
+            "format": "byte",
+            "description": "Hash of the previous record, null for the first record"
+          },
+          "hash": {
+            "type": "string",
+            "format": "byte",
+            "description": "SHA-256 of the JSON encoding of the record without hash and signature"
+          },
+          "signature": {
+            "type": "string",
+            "format": "byte",
+            "description": "Ed25519 signature of the hash"
+          }
+        }
+      },
+      "Offset": {
+        "type": "object",
+        "required": [
+          "offset"
+        ],
+        "properties": {
+          "offset": {
+            "type": "integer"
+          }
+        }
+      }
+    }
+  }
+}
+`
@@ -0,0 +1,355 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/lidofinance/dc4bc/client/api"
+	"github.com/lidofinance/dc4bc/client/types"
+	sipf "github.com/lidofinance/dc4bc/fsm/state_machines/signing_proposal_fsm"
+	"github.com/lidofinance/dc4bc/fsm/types/requests"
+	"github.com/lidofinance/dc4bc/fsm/types/responses"
+	"github.com/lidofinance/dc4bc/storage"
+)
+
+const (
+	payloadTypeJSON   = "json"
+	payloadTypeText   = "text"
+	payloadTypeBinary = "binary"
+
+	eth2KindDeposit       = "deposit"
+	eth2KindVoluntaryExit = "voluntary_exit"
+	eth2KindBeaconBlock   = "beacon_block"
+	eth2KindAttestation   = "attestation"
+	eth2KindSigningRoot   = "signing_root"
+	eth2KindUnknown       = "unknown"
+)
+
+// SigningPolicy decides what the node does with signing requests of other participants. Rules are checked
+// in order and the first matching rule is applied, DefaultAction is applied when no rule matches.
+type SigningPolicy struct {
+	DefaultAction types.PolicyAction  `json:"default_action"`
+	Rules         []SigningPolicyRule `json:"rules"`
+
+	mu sync.Mutex
+	// requests are the times of recent signing requests by initiator, they are kept in memory for rate limits,
+	// so rate limits start over after a restart of the node
+	requests  map[string][]time.Time
+	maxPeriod time.Duration
+}
+
+// SigningPolicyRule matches a signing request when all of its set conditions match
+type SigningPolicyRule struct {
+	Name   string             `json:"name"`
+	Action types.PolicyAction `json:"action"`
+
+	DKGRoundIDs []string `json:"dkg_round_ids,omitempty"`
+	Initiators  []string `json:"initiators,omitempty"`
+	// PayloadTypes are json, text or binary
+	PayloadTypes []string `json:"payload_types,omitempty"`
+	// Eth2Kinds are deposit, voluntary_exit, beacon_block, attestation, signing_root or unknown
+	Eth2Kinds      []string `json:"eth2_kinds,omitempty"`
+	MinPayloadSize int      `json:"min_payload_size,omitempty"`
+	MaxPayloadSize int      `json:"max_payload_size,omitempty"`
+	// RateLimit matches when the initiator sent more signing requests than allowed
+	RateLimit *SigningRateLimit `json:"rate_limit,omitempty"`
+}
+
+// SigningRateLimit allows Requests signing requests of an initiator in the Period, e.g. "1h"
+type SigningRateLimit struct {
+	Requests int    `json:"requests"`
+	Period   string `json:"period"`
+
+	period time.Duration
+}
+
+// signingRequest is a signing request received from the append-only log
+type signingRequest struct {
+	DKGRoundID string
+	Initiator  string
+	Payload    []byte
+}
+
+// LoadSigningPolicy reads the signing policy from a JSON file
+func LoadSigningPolicy(filename string) (*SigningPolicy, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing policy: %w", err)
+	}
+	var policy SigningPolicy
+	if err = json.Unmarshal(data, &policy); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal signing policy: %w", err)
+	}
+	if err = policy.init(); err != nil {
+		return nil, fmt.Errorf("invalid signing policy: %w", err)
+	}
+	return &policy, nil
+}
+
+func validPolicyAction(action types.PolicyAction) bool {
+	switch action {
+	case types.PolicyAllow, types.PolicyDecline, types.PolicyFlag, types.PolicyRequireApproval:
+		return true
+	}
+	return false
+}
+
+// init validates the policy and parses periods of rate limits
+func (p *SigningPolicy) init() error {
+	if p.DefaultAction == "" {
+		p.DefaultAction = types.PolicyAllow
+	}
+	if !validPolicyAction(p.DefaultAction) {
+		return fmt.Errorf("unknown default action: %s", p.DefaultAction)
+	}
+	for i := range p.Rules {
+		rule := &p.Rules[i]
+		if rule.Name == "" {
+			return fmt.Errorf("rule %d has no name", i)
+		}
+		if !validPolicyAction(rule.Action) {
+			return fmt.Errorf("rule %s: unknown action: %s", rule.Name, rule.Action)
+		}
+		if rule.MaxPayloadSize != 0 && rule.MaxPayloadSize < rule.MinPayloadSize {
+			return fmt.Errorf("rule %s: max payload size is less than min payload size", rule.Name)
+		}
+		if rule.RateLimit == nil {
+			continue
+		}
+		period, err := time.ParseDuration(rule.RateLimit.Period)
+		if err != nil || period <= 0 {
+			return fmt.Errorf("rule %s: invalid rate limit period: %s", rule.Name, rule.RateLimit.Period)
+		}
+		if rule.RateLimit.Requests <= 0 {
+			return fmt.Errorf("rule %s: rate limit requests must be positive", rule.Name)
+		}
+		rule.RateLimit.period = period
+		if period > p.maxPeriod {
+			p.maxPeriod = period
+		}
+	}
+	p.requests = make(map[string][]time.Time)
+	return nil
+}
+
+// evaluate returns the decision on the request, rate limits count the recorded requests of the initiator
+// along with this one. The request itself is recorded with record once the message is processed.
+func (p *SigningPolicy) evaluate(request signingRequest, now time.Time) types.PolicyDecision {
+	p.mu.Lock()
+	recent := append(p.recentRequests(request.Initiator, now), now)
+	p.mu.Unlock()
+
+	payloadType := signingPayloadType(request.Payload)
+	eth2Kind := signingEth2Kind(request.Payload)
+	for _, rule := range p.Rules {
+		switch {
+		case len(rule.DKGRoundIDs) > 0 && !containsString(rule.DKGRoundIDs, request.DKGRoundID):
+		case len(rule.Initiators) > 0 && !containsString(rule.Initiators, request.Initiator):
+		case len(rule.PayloadTypes) > 0 && !containsString(rule.PayloadTypes, payloadType):
+		case len(rule.Eth2Kinds) > 0 && !containsString(rule.Eth2Kinds, eth2Kind):
+		case len(request.Payload) < rule.MinPayloadSize:
+		case rule.MaxPayloadSize != 0 && len(request.Payload) > rule.MaxPayloadSize:
+		case rule.RateLimit != nil && countSince(recent, now.Add(-rule.RateLimit.period)) <= rule.RateLimit.Requests:
+		default:
+			return types.PolicyDecision{
+				Action: rule.Action,
+				Rule:   rule.Name,
+				Reason: fmt.Sprintf("%s payload of %d bytes (eth2 kind: %s) from %s matches rule %s",
+					payloadType, len(request.Payload), eth2Kind, request.Initiator, rule.Name),
+			}
+		}
+	}
+	return types.PolicyDecision{Action: p.DefaultAction, Reason: "no rule matches"}
+}
+
+// record records the signing request of the initiator for rate limits
+func (p *SigningPolicy) record(initiator string, now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.requests[initiator] = append(p.recentRequests(initiator, now), now)
+}
+
+// recentRequests returns the recorded requests of the initiator, requests older than the longest period
+// do not affect any rate limit
+func (p *SigningPolicy) recentRequests(initiator string, now time.Time) []time.Time {
+	var recent []time.Time
+	for _, t := range p.requests[initiator] {
+		if now.Sub(t) < p.maxPeriod {
+			recent = append(recent, t)
+		}
+	}
+	return recent
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func countSince(times []time.Time, since time.Time) int {
+	var count int
+	for _, t := range times {
+		if t.After(since) {
+			count++
+		}
+	}
+	return count
+}
+
+// signingPayloadType returns json for valid JSON, text for printable UTF-8 and binary for other payloads
+func signingPayloadType(payload []byte) string {
+	if json.Valid(payload) {
+		return payloadTypeJSON
+	}
+	if !utf8.Valid(payload) {
+		return payloadTypeBinary
+	}
+	for _, r := range string(payload) {
+		if !unicode.IsPrint(r) && !unicode.IsSpace(r) {
+			return payloadTypeBinary
+		}
+	}
+	return payloadTypeText
+}
+
+// signingEth2Kind guesses the kind of the Eth2 object by the fields of its JSON encoding,
+// a 32-byte payload is a signing root
+func signingEth2Kind(payload []byte) string {
+	if len(payload) == 32 {
+		return eth2KindSigningRoot
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &fields); err != nil {
+		return eth2KindUnknown
+	}
+	// signed objects wrap the object into the message field
+	if message, ok := fields["message"]; ok {
+		var messageFields map[string]json.RawMessage
+		if err := json.Unmarshal(message, &messageFields); err == nil {
+			fields = messageFields
+		}
+	}
+	has := func(keys ...string) bool {
+		for _, key := range keys {
+			if _, ok := fields[key]; !ok {
+				return false
+			}
+		}
+		return true
+	}
+	switch {
+	case has("pubkey", "withdrawal_credentials", "amount"):
+		return eth2KindDeposit
+	case has("slot", "proposer_index", "parent_root"):
+		return eth2KindBeaconBlock
+	case has("slot", "index", "beacon_block_root", "source", "target"):
+		return eth2KindAttestation
+	case has("epoch", "validator_index"):
+		return eth2KindVoluntaryExit
+	}
+	return eth2KindUnknown
+}
+
+// SetSigningPolicy sets the policy applied to signing requests of other participants, it must be called before Poll
+func (c *BaseClient) SetSigningPolicy(policy *SigningPolicy) {
+	c.signingPolicy = policy
+}
+
+// applySigningPolicy logs the decision on the signing request and returns the message declining the signing
+// if the decision is to decline it
+func (c *BaseClient) applySigningPolicy(
+	messageLogger *logger,
+	dkgRoundID string,
+	data responses.SigningProposalParticipantInvitationsResponse,
+	decision types.PolicyDecision,
+) (*storage.Message, error) {
+	decisionLogger := messageLogger.with("signing_id", data.SigningId).
+		with("policy_action", decision.Action).
+		with("policy_rule", decision.Rule).
+		with("reason", decision.Reason)
+	switch decision.Action {
+	case types.PolicyAllow:
+		if decision.Rule != "" {
+			decisionLogger.Info("Signing request is allowed by the signing policy")
+		}
+		return nil, nil
+	case types.PolicyFlag:
+		decisionLogger.Warn("Signing request is flagged by the signing policy")
+		return nil, nil
+	case types.PolicyRequireApproval:
+		decisionLogger.Warn("Signing request requires a second approver by the signing policy")
+		return nil, nil
+	}
+
+	participantID := -1
+	for _, participant := range data.Participants {
+		if participant.Username == c.GetUsername() {
+			participantID = participant.ParticipantId
+		}
+	}
+	if participantID < 0 {
+		return nil, fmt.Errorf("participant %s is not invited to the signing %s", c.GetUsername(), data.SigningId)
+	}
+	reqBz, err := json.Marshal(requests.SigningProposalParticipantRequest{
+		SigningId:     data.SigningId,
+		ParticipantId: participantID,
+		Error:         requests.NewProtocolError(requests.ErrorCodeDeclinedByPolicy, c.GetUsername(), decision.Reason),
+		CreatedAt:     time.Now(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	message, err := c.buildMessage(dkgRoundID, sipf.EventDeclineSigningConfirmation, reqBz)
+	if err != nil {
+		return nil, err
+	}
+	decisionLogger.Warn("Signing request is declined by the signing policy")
+	return message, nil
+}
+
+// approveOperation records the approval of an operation which requires a second approver by the signing policy
+func (c *BaseClient) approveOperation(operationID, approver string) (*types.Operation, error) {
+	if approver == "" {
+		return nil, errors.New("approver is not set")
+	}
+	operation, err := c.state.GetOperationByID(operationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get operation: %w", err)
+	}
+	if operation.Policy == nil || operation.Policy.Action != types.PolicyRequireApproval {
+		return nil, fmt.Errorf("operation %s does not require approval", operationID)
+	}
+	if !operation.Policy.AwaitsApproval() {
+		return nil, fmt.Errorf("operation %s is already approved by %s", operationID, operation.Policy.ApprovedBy)
+	}
+
+	now := time.Now().UTC()
+	operation.Policy.ApprovedBy = approver
+	operation.Policy.ApprovedAt = &now
+	if err = c.state.UpdateOperation(operation); err != nil {
+		return nil, fmt.Errorf("failed to update operation: %w", err)
+	}
+	c.Logger.with("dkg_round_id", operation.DKGIdentifier).
+		with("operation_id", operation.ID).
+		with("approver", approver).
+		Info("Operation is approved")
+	c.audit(api.AuditRecord{
+		Action:        api.AuditOperationApproved,
+		DKGRoundID:    operation.DKGIdentifier,
+		OperationID:   operation.ID,
+		OperationType: string(operation.Type),
+		Approver:      approver,
+	})
+	return operation, nil
+}
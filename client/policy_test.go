@@ -0,0 +1,154 @@
+package client
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lidofinance/dc4bc/client/api"
+	"github.com/lidofinance/dc4bc/client/types"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSigningPolicy(t *testing.T, policyJSON string) *SigningPolicy {
+	dir, err := ioutil.TempDir("", "dc4bc_test_signing_policy")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "policy.json")
+	require.NoError(t, ioutil.WriteFile(filename, []byte(policyJSON), 0600))
+	policy, err := LoadSigningPolicy(filename)
+	require.NoError(t, err)
+	return policy
+}
+
+func TestSigningPolicy_Evaluate(t *testing.T) {
+	req := require.New(t)
+	policy := newTestSigningPolicy(t, `{
+		"default_action": "require_approval",
+		"rules": [
+			{"name": "spam", "action": "decline", "rate_limit": {"requests": 1, "period": "1h"}},
+			{"name": "big", "action": "decline", "min_payload_size": 1024},
+			{"name": "exits", "action": "flag", "eth2_kinds": ["voluntary_exit"]},
+			{"name": "trusted", "action": "allow", "dkg_round_ids": ["dkg_id"], "initiators": ["alice"], "payload_types": ["text"]}
+		]
+	}`)
+
+	now := time.Now()
+	decision := policy.evaluate(signingRequest{DKGRoundID: "dkg_id", Initiator: "alice", Payload: []byte("hello")}, now)
+	req.Equal(types.PolicyAllow, decision.Action)
+	req.Equal("trusted", decision.Rule)
+	// the request is not recorded until it is processed
+	decision = policy.evaluate(signingRequest{DKGRoundID: "dkg_id", Initiator: "alice", Payload: []byte("hello")}, now)
+	req.Equal("trusted", decision.Rule)
+	policy.record("alice", now)
+
+	exit := []byte(`{"message": {"epoch": "1", "validator_index": "2"}, "signature": "0x00"}`)
+	decision = policy.evaluate(signingRequest{DKGRoundID: "dkg_id", Initiator: "bob", Payload: exit}, now)
+	req.Equal(types.PolicyFlag, decision.Action)
+
+	decision = policy.evaluate(signingRequest{DKGRoundID: "dkg_id", Initiator: "dave", Payload: make([]byte, 2048)}, now)
+	req.Equal(types.PolicyDecline, decision.Action)
+	req.Equal("big", decision.Rule)
+
+	// the second request of alice in an hour exceeds the rate limit
+	decision = policy.evaluate(signingRequest{DKGRoundID: "dkg_id", Initiator: "alice", Payload: []byte("hello")}, now)
+	req.Equal("spam", decision.Rule)
+	decision = policy.evaluate(signingRequest{DKGRoundID: "dkg_id", Initiator: "alice", Payload: []byte("hello")}, now.Add(2*time.Hour))
+	req.Equal("trusted", decision.Rule)
+
+	decision = policy.evaluate(signingRequest{DKGRoundID: "other_dkg_id", Initiator: "carol", Payload: []byte("hello")}, now)
+	req.Equal(types.PolicyRequireApproval, decision.Action)
+	req.Empty(decision.Rule)
+}
+
+func TestSigningEth2Kind(t *testing.T) {
+	req := require.New(t)
+	req.Equal(eth2KindSigningRoot, signingEth2Kind(make([]byte, 32)))
+	req.Equal(eth2KindDeposit, signingEth2Kind([]byte(`{"pubkey": "0x00", "withdrawal_credentials": "0x00", "amount": "32000000000"}`)))
+	req.Equal(eth2KindBeaconBlock, signingEth2Kind([]byte(`{"slot": "1", "proposer_index": "2", "parent_root": "0x00", "body": {}}`)))
+	req.Equal(eth2KindAttestation, signingEth2Kind([]byte(`{"slot": "1", "index": "0", "beacon_block_root": "0x00", "source": {}, "target": {}}`)))
+	req.Equal(eth2KindUnknown, signingEth2Kind([]byte("hello")))
+
+	req.Equal(payloadTypeJSON, signingPayloadType([]byte(`{"a": 1}`)))
+	req.Equal(payloadTypeText, signingPayloadType([]byte("hello\n")))
+	req.Equal(payloadTypeBinary, signingPayloadType([]byte{0, 1, 2}))
+}
+
+func TestLoadSigningPolicy_Invalid(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dc4bc_test_signing_policy")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "policy.json")
+	for _, policyJSON := range []string{
+		`{"default_action": "ignore"}`,
+		`{"rules": [{"action": "allow"}]}`,
+		`{"rules": [{"name": "rule", "action": "allow", "rate_limit": {"requests": 1, "period": "soon"}}]}`,
+		`{"rules": [{"name": "rule", "action": "allow", "min_payload_size": 10, "max_payload_size": 5}]}`,
+	} {
+		require.NoError(t, ioutil.WriteFile(filename, []byte(policyJSON), 0600))
+		_, err = LoadSigningPolicy(filename)
+		require.Error(t, err, policyJSON)
+	}
+}
+
+func TestAPIV1_ApproveOperation(t *testing.T) {
+	req := require.New(t)
+	c, cleanup := newTestAPIClient(t)
+	defer cleanup()
+	handler := c.httpHandler()
+
+	req.NoError(c.state.PutOperation(&types.Operation{ID: "flagged", Policy: &types.PolicyDecision{Action: types.PolicyFlag}}))
+	req.NoError(c.state.PutOperation(&types.Operation{ID: "held", Policy: &types.PolicyDecision{Action: types.PolicyRequireApproval}}))
+
+	// the node does not pass results of operations which are not approved
+	req.Error(c.handleProcessedOperation(types.Operation{ID: "held"}))
+
+	// the approver is the holder of the token, so approvals are refused while authentication is disabled
+	w, resp := doAPIRequest(t, handler, http.MethodPost, "/v1/operations/held/approve", nil)
+	req.Equal(http.StatusForbidden, w.Code, resp)
+
+	c.SetHTTPAuth(HTTPAuthConfig{Tokens: map[string]APIToken{
+		"operator_token": {Scope: APIScopeOperator, Name: "alice"},
+		"approver_token": {Scope: APIScopeApprover, Name: "bob"},
+	}})
+	handler = c.httpHandler()
+	approve := func(operationID, token string) (*httptest.ResponseRecorder, api.Response) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/v1/operations/"+operationID+"/approve", nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		handler.ServeHTTP(w, r)
+		var resp api.Response
+		req.NoError(json.Unmarshal(w.Body.Bytes(), &resp))
+		return w, resp
+	}
+
+	w, _ = approve("flagged", "approver_token")
+	req.Equal(http.StatusBadRequest, w.Code)
+	w, _ = approve("unknown", "approver_token")
+	req.Equal(http.StatusNotFound, w.Code)
+	w, _ = approve("held", "operator_token")
+	req.Equal(http.StatusForbidden, w.Code)
+
+	w, resp = approve("held", "approver_token")
+	req.Equal(http.StatusOK, w.Code, resp)
+	resultBz, err := json.Marshal(resp.Result)
+	req.NoError(err)
+	var operation types.Operation
+	req.NoError(json.Unmarshal(resultBz, &operation))
+	req.Equal("bob", operation.Policy.ApprovedBy)
+	req.NotNil(operation.Policy.ApprovedAt)
+
+	stored, err := c.state.GetOperationByID("held")
+	req.NoError(err)
+	req.False(stored.Policy.AwaitsApproval())
+
+	w, _ = approve("held", "approver_token")
+	req.Equal(http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,58 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/corestario/kyber/pairing"
+	"github.com/corestario/kyber/pairing/bls12381"
+	"github.com/corestario/kyber/sign/bls"
+	"github.com/corestario/kyber/sign/tbls"
+	"github.com/lidofinance/dc4bc/client/types"
+	"github.com/lidofinance/dc4bc/fsm/state_machines"
+	"github.com/lidofinance/dc4bc/fsm/types/responses"
+	"github.com/lidofinance/dc4bc/storage"
+)
+
+// reconstructSignature recovers the full signature from partial signs collected by the FSM, verifies it with
+// the master public key and returns a message to broadcast the signature
+func (c *BaseClient) reconstructSignature(fsmInstance *state_machines.FSMInstance,
+	payload responses.SigningProcessParticipantResponse) (*storage.Message, error) {
+	dkgRoundID := fsmInstance.Id()
+	dump := fsmInstance.FSMDump()
+	if dump.Payload.DKGProposalPayload == nil {
+		return nil, errors.New("DKG round is not finished")
+	}
+	participantsCount := len(dump.Payload.DKGProposalPayload.Quorum)
+
+	pubPoly, err := c.loadPubPoly(dkgRoundID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load public polynomial: %w", err)
+	}
+
+	partialSigns := make([][]byte, 0, len(payload.Participants))
+	for _, participant := range payload.Participants {
+		partialSigns = append(partialSigns, participant.PartialSign)
+	}
+
+	suite := bls12381.NewBLS12381Suite(nil).(pairing.Suite)
+	signature, err := tbls.Recover(suite, pubPoly, payload.SrcPayload, partialSigns, pubPoly.Threshold(), participantsCount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to recover signature: %w", err)
+	}
+	if err = bls.Verify(suite, pubPoly.Commit(), payload.SrcPayload, signature); err != nil {
+		return nil, fmt.Errorf("failed to verify reconstructed signature: %w", err)
+	}
+
+	signatureBz, err := json.Marshal(types.ReconstructedSignature{
+		SigningID:  payload.SigningId,
+		SrcPayload: payload.SrcPayload,
+		Signature:  signature,
+		DKGRoundID: dkgRoundID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal reconstructed signature: %w", err)
+	}
+	return c.buildMessage(dkgRoundID, types.SignatureReconstructed, signatureBz)
+}
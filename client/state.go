@@ -5,11 +5,14 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 	"sync"
 
 	"github.com/lidofinance/dc4bc/client/types"
 
+	"github.com/lidofinance/dc4bc/fsm/fsm"
 	"github.com/lidofinance/dc4bc/fsm/state_machines"
+	"github.com/lidofinance/dc4bc/storage"
 
 	"github.com/syndtr/goleveldb/leveldb"
 )
@@ -19,6 +22,10 @@ const (
 	operationsKey       = "operations"
 	fsmStateKey         = "fsm_state"
 	signaturesKeyPrefix = "signatures"
+	messagesKeyPrefix   = "messages"
+	pubPolyKeyPrefix    = "pub_poly"
+	dkgKeysKey          = "dkg_keys"
+	eventsOffsetKey     = "events_offset"
 )
 
 // State is the client's state (it keeps the offset, the FSM state and
@@ -27,11 +34,15 @@ type State interface {
 	SaveOffset(uint64) error
 	LoadOffset() (uint64, error)
 
+	SaveEventsOffset(uint64) error
+	LoadEventsOffset() (uint64, error)
+
 	SaveFSM(dkgRoundID string, dump []byte) error
 	LoadFSM(dkgRoundID string) (*state_machines.FSMInstance, bool, error)
 	GetAllFSM() (map[string]*state_machines.FSMInstance, error)
 
 	PutOperation(operation *types.Operation) error
+	UpdateOperation(operation *types.Operation) error
 	DeleteOperation(operationID string) error
 	GetOperations() (map[string]*types.Operation, error)
 	GetOperationByID(operationID string) (*types.Operation, error)
@@ -39,11 +50,23 @@ type State interface {
 	SaveSignature(signature types.ReconstructedSignature) error
 	GetSignatureByID(dkgID, signatureID string) ([]types.ReconstructedSignature, error)
 	GetSignatures(dkgID string) (map[string][]types.ReconstructedSignature, error)
+
+	SaveMessage(message storage.Message) error
+	GetMessages(dkgID string, event fsm.Event) ([]storage.Message, error)
+
+	SavePubPoly(dkgID string, pubPoly []byte) error
+	LoadPubPoly(dkgID string) ([]byte, error)
+
+	SaveDKGKey(key *types.DKGKey) error
+	GetDKGKey(dkgID string) (*types.DKGKey, error)
+	GetDKGKeys() (map[string]*types.DKGKey, error)
 }
 
 type LevelDBState struct {
 	sync.Mutex
 	stateDb *leveldb.DB
+	// namespace prefixes keys of the state, it separates states of participants hosted by one node
+	namespace string
 }
 
 func NewLevelDBState(stateDbPath string) (State, error) {
@@ -52,28 +75,154 @@ func NewLevelDBState(stateDbPath string) (State, error) {
 		return nil, fmt.Errorf("failed to open stateDB: %w", err)
 	}
 
+	return newLevelDBState(db, "")
+}
+
+// ErrLegacyState is returned by NewLevelDBStates for a stateDB which keeps the state of a node hosting one
+// participant, the state must be moved to one of the participants with MigrateLevelDBState first
+var ErrLegacyState = errors.New("stateDB keeps the state of a node hosting one participant")
+
+// NewLevelDBStates opens one stateDB for several participants hosted by the node, the state of every
+// participant is kept in its own namespace. The states are returned in the order of the usernames.
+func NewLevelDBStates(stateDbPath string, usernames []string) ([]State, error) {
+	for _, username := range usernames {
+		if !validNamespaceUsername(username) {
+			return nil, fmt.Errorf("invalid username: %q", username)
+		}
+	}
+
+	db, err := leveldb.OpenFile(stateDbPath, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open stateDB: %w", err)
+	}
+	// the participant of the node would replay the log from the start if its state was left outside its namespace
+	legacy, err := db.Has([]byte(offsetKey), nil)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to check stateDB: %w", err)
+	}
+	if legacy {
+		db.Close()
+		return nil, ErrLegacyState
+	}
+
+	states := make([]State, 0, len(usernames))
+	for _, username := range usernames {
+		state, err := newLevelDBState(db, username+"/")
+		if err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to init state of %s: %w", username, err)
+		}
+		states = append(states, state)
+	}
+	return states, nil
+}
+
+func validNamespaceUsername(username string) bool {
+	return username != "" && !strings.Contains(username, "/")
+}
+
+// isLegacyStateKey returns true for keys of the state of a node hosting one participant
+func isLegacyStateKey(key string) bool {
+	switch key {
+	case offsetKey, operationsKey, fsmStateKey, dkgKeysKey, eventsOffsetKey:
+		return true
+	}
+	for _, prefix := range []string{signaturesKeyPrefix, messagesKeyPrefix, pubPolyKeyPrefix} {
+		// keys of namespaced states contain the separator after the username
+		if strings.HasPrefix(key, prefix+"_") && !strings.Contains(key, "/") {
+			return true
+		}
+	}
+	return false
+}
+
+// MigrateLevelDBState moves the state of a node hosting one participant to the namespace of the participant,
+// so the stateDB can be opened with NewLevelDBStates. The keys are moved in one transaction, a stateDB without
+// such state is left as is.
+func MigrateLevelDBState(stateDbPath, username string) error {
+	if !validNamespaceUsername(username) {
+		return fmt.Errorf("invalid username: %q", username)
+	}
+	db, err := leveldb.OpenFile(stateDbPath, nil)
+	if err != nil {
+		return fmt.Errorf("failed to open stateDB: %w", err)
+	}
+	defer db.Close()
+
+	tx, err := db.OpenTransaction()
+	if err != nil {
+		return fmt.Errorf("failed to open transaction: %w", err)
+	}
+	defer tx.Discard()
+
+	legacy, err := tx.Has([]byte(offsetKey), nil)
+	if err != nil {
+		return fmt.Errorf("failed to check stateDB: %w", err)
+	}
+	if !legacy {
+		return nil
+	}
+	exists, err := tx.Has([]byte(username+"/"+offsetKey), nil)
+	if err != nil {
+		return fmt.Errorf("failed to check stateDB: %w", err)
+	}
+	if exists {
+		return fmt.Errorf("stateDB already has the state of %s", username)
+	}
+
+	iter := tx.NewIterator(nil, nil)
+	for iter.Next() {
+		key := string(iter.Key())
+		if !isLegacyStateKey(key) {
+			continue
+		}
+		value := append([]byte{}, iter.Value()...)
+		if err = tx.Put([]byte(username+"/"+key), value, nil); err != nil {
+			iter.Release()
+			return fmt.Errorf("failed to put %s: %w", key, err)
+		}
+		if err = tx.Delete([]byte(key), nil); err != nil {
+			iter.Release()
+			return fmt.Errorf("failed to delete %s: %w", key, err)
+		}
+	}
+	iter.Release()
+	if err = iter.Error(); err != nil {
+		return fmt.Errorf("failed to iterate over stateDB: %w", err)
+	}
+	return tx.Commit()
+}
+
+func newLevelDBState(db *leveldb.DB, namespace string) (State, error) {
 	state := &LevelDBState{
-		stateDb: db,
+		stateDb:   db,
+		namespace: namespace,
 	}
 
 	// Init state key for operations JSON.
-	if _, err := state.stateDb.Get([]byte(operationsKey), nil); err != nil {
+	if _, err := state.stateDb.Get(state.key(operationsKey), nil); err != nil {
 		if err := state.initJsonKey(operationsKey, map[string]*types.Operation{}); err != nil {
 			return nil, fmt.Errorf("failed to init %s storage: %w", operationsKey, err)
 		}
 	}
 
+	// Init state key for the registry of finished DKG rounds.
+	if err := state.initJsonKey(dkgKeysKey, map[string]*types.DKGKey{}); err != nil {
+		return nil, fmt.Errorf("failed to init %s storage: %w", dkgKeysKey, err)
+	}
+
 	// Init state key for offset bytes.
-	if _, err := state.stateDb.Get([]byte(offsetKey), nil); err != nil {
+	if _, err := state.stateDb.Get(state.key(offsetKey), nil); err != nil {
 		bz := make([]byte, 8)
 		binary.LittleEndian.PutUint64(bz, 0)
-		if err := db.Put([]byte(offsetKey), bz, nil); err != nil {
+		if err := db.Put(state.key(offsetKey), bz, nil); err != nil {
 			return nil, fmt.Errorf("failed to init %s storage: %w", offsetKey, err)
 		}
 	}
 
-	if _, err := state.stateDb.Get([]byte(fsmStateKey), nil); err != nil {
-		if err := db.Put([]byte(fsmStateKey), []byte{}, nil); err != nil {
+	if _, err := state.stateDb.Get(state.key(fsmStateKey), nil); err != nil {
+		if err := db.Put(state.key(fsmStateKey), []byte{}, nil); err != nil {
 			return nil, fmt.Errorf("failed to init %s storage: %w", offsetKey, err)
 		}
 	}
@@ -81,13 +230,17 @@ func NewLevelDBState(stateDbPath string) (State, error) {
 	return state, nil
 }
 
+func (s *LevelDBState) key(key string) []byte {
+	return []byte(s.namespace + key)
+}
+
 func (s *LevelDBState) initJsonKey(key string, data interface{}) error {
-	if _, err := s.stateDb.Get([]byte(key), nil); err != nil {
+	if _, err := s.stateDb.Get(s.key(key), nil); err != nil {
 		operationsBz, err := json.Marshal(data)
 		if err != nil {
 			return fmt.Errorf("failed to marshal storage structure: %w", err)
 		}
-		err = s.stateDb.Put([]byte(key), operationsBz, nil)
+		err = s.stateDb.Put(s.key(key), operationsBz, nil)
 		if err != nil {
 			return fmt.Errorf("failed to init state: %w", err)
 		}
@@ -100,7 +253,7 @@ func (s *LevelDBState) SaveOffset(offset uint64) error {
 	bz := make([]byte, 8)
 	binary.LittleEndian.PutUint64(bz, offset)
 
-	if err := s.stateDb.Put([]byte(offsetKey), bz, nil); err != nil {
+	if err := s.stateDb.Put(s.key(offsetKey), bz, nil); err != nil {
 		return fmt.Errorf("failed to set offset: %w", err)
 	}
 
@@ -108,7 +261,7 @@ func (s *LevelDBState) SaveOffset(offset uint64) error {
 }
 
 func (s *LevelDBState) LoadOffset() (uint64, error) {
-	bz, err := s.stateDb.Get([]byte(offsetKey), nil)
+	bz, err := s.stateDb.Get(s.key(offsetKey), nil)
 	if err != nil {
 		return 0, fmt.Errorf("failed to read offset: %w", err)
 	}
@@ -118,8 +271,31 @@ func (s *LevelDBState) LoadOffset() (uint64, error) {
 	return offset, nil
 }
 
+// SaveEventsOffset saves the offset of the last event published by the node
+func (s *LevelDBState) SaveEventsOffset(offset uint64) error {
+	bz := make([]byte, 8)
+	binary.LittleEndian.PutUint64(bz, offset)
+
+	if err := s.stateDb.Put(s.key(eventsOffsetKey), bz, nil); err != nil {
+		return fmt.Errorf("failed to set events offset: %w", err)
+	}
+	return nil
+}
+
+// LoadEventsOffset returns the offset of the last event published by the node, zero if no event was published
+func (s *LevelDBState) LoadEventsOffset() (uint64, error) {
+	bz, err := s.stateDb.Get(s.key(eventsOffsetKey), nil)
+	if err == leveldb.ErrNotFound {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read events offset: %w", err)
+	}
+	return binary.LittleEndian.Uint64(bz), nil
+}
+
 func (s *LevelDBState) SaveFSM(dkgRoundID string, dump []byte) error {
-	bz, err := s.stateDb.Get([]byte(fsmStateKey), nil)
+	bz, err := s.stateDb.Get(s.key(fsmStateKey), nil)
 	if err != nil {
 		return fmt.Errorf("failed to get FSM instances: %w", err)
 	}
@@ -138,7 +314,7 @@ func (s *LevelDBState) SaveFSM(dkgRoundID string, dump []byte) error {
 		return fmt.Errorf("failed to marshal FSM instances: %w", err)
 	}
 
-	if err := s.stateDb.Put([]byte(fsmStateKey), fsmInstancesBz, nil); err != nil {
+	if err := s.stateDb.Put(s.key(fsmStateKey), fsmInstancesBz, nil); err != nil {
 		return fmt.Errorf("failed to save fsm state: %w", err)
 	}
 
@@ -146,7 +322,7 @@ func (s *LevelDBState) SaveFSM(dkgRoundID string, dump []byte) error {
 }
 
 func (s *LevelDBState) GetAllFSM() (map[string]*state_machines.FSMInstance, error) {
-	bz, err := s.stateDb.Get([]byte(fsmStateKey), nil)
+	bz, err := s.stateDb.Get(s.key(fsmStateKey), nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get FSM instances: %w", err)
 	}
@@ -167,7 +343,7 @@ func (s *LevelDBState) GetAllFSM() (map[string]*state_machines.FSMInstance, erro
 }
 
 func (s *LevelDBState) LoadFSM(dkgRoundID string) (*state_machines.FSMInstance, bool, error) {
-	bz, err := s.stateDb.Get([]byte(fsmStateKey), nil)
+	bz, err := s.stateDb.Get(s.key(fsmStateKey), nil)
 	if err != nil {
 		return nil, false, fmt.Errorf("failed to get FSM instances: %w", err)
 	}
@@ -211,7 +387,34 @@ func (s *LevelDBState) PutOperation(operation *types.Operation) error {
 		return fmt.Errorf("failed to marshal operations: %w", err)
 	}
 
-	if err := s.stateDb.Put([]byte(operationsKey), operationsJSON, nil); err != nil {
+	if err := s.stateDb.Put(s.key(operationsKey), operationsJSON, nil); err != nil {
+		return fmt.Errorf("failed to put operations: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateOperation replaces an existing operation of an operation pool
+func (s *LevelDBState) UpdateOperation(operation *types.Operation) error {
+	s.Lock()
+	defer s.Unlock()
+
+	operations, err := s.getOperations()
+	if err != nil {
+		return fmt.Errorf("failed to getOperations: %w", err)
+	}
+
+	if _, ok := operations[operation.ID]; !ok {
+		return errors.New("operation not found")
+	}
+
+	operations[operation.ID] = operation
+	operationsJSON, err := json.Marshal(operations)
+	if err != nil {
+		return fmt.Errorf("failed to marshal operations: %w", err)
+	}
+
+	if err := s.stateDb.Put(s.key(operationsKey), operationsJSON, nil); err != nil {
 		return fmt.Errorf("failed to put operations: %w", err)
 	}
 
@@ -235,7 +438,7 @@ func (s *LevelDBState) DeleteOperation(operationID string) error {
 		return fmt.Errorf("failed to marshal operations: %w", err)
 	}
 
-	if err := s.stateDb.Put([]byte(operationsKey), operationsJSON, nil); err != nil {
+	if err := s.stateDb.Put(s.key(operationsKey), operationsJSON, nil); err != nil {
 		return fmt.Errorf("failed to put operations: %w", err)
 	}
 
@@ -268,7 +471,7 @@ func (s *LevelDBState) GetOperationByID(operationID string) (*types.Operation, e
 }
 
 func (s *LevelDBState) getOperations() (map[string]*types.Operation, error) {
-	bz, err := s.stateDb.Get([]byte(operationsKey), nil)
+	bz, err := s.stateDb.Get(s.key(operationsKey), nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get Operations (key: %s): %w", operationsKey, err)
 	}
@@ -281,12 +484,12 @@ func (s *LevelDBState) getOperations() (map[string]*types.Operation, error) {
 	return operations, nil
 }
 
-func makeSignatureKey(dkgID string) []byte {
-	return []byte(fmt.Sprintf("%s_%s", signaturesKeyPrefix, dkgID))
+func makeSignatureKey(dkgID string) string {
+	return fmt.Sprintf("%s_%s", signaturesKeyPrefix, dkgID)
 }
 
 func (s *LevelDBState) getSignatures(dkgID string) (map[string][]types.ReconstructedSignature, error) {
-	bz, err := s.stateDb.Get(makeSignatureKey(dkgID), nil)
+	bz, err := s.stateDb.Get(s.key(makeSignatureKey(dkgID)), nil)
 	if err != nil {
 		if err == leveldb.ErrNotFound {
 			return nil, nil
@@ -347,9 +550,157 @@ func (s *LevelDBState) SaveSignature(signature types.ReconstructedSignature) err
 		return fmt.Errorf("failed to marshal signatures: %w", err)
 	}
 
-	if err := s.stateDb.Put(makeSignatureKey(signature.DKGRoundID), signaturesJSON, nil); err != nil {
+	if err := s.stateDb.Put(s.key(makeSignatureKey(signature.DKGRoundID)), signaturesJSON, nil); err != nil {
 		return fmt.Errorf("failed to save signatures: %w", err)
 	}
 
 	return nil
 }
+
+func makeMessagesKey(dkgID string) string {
+	return fmt.Sprintf("%s_%s", messagesKeyPrefix, dkgID)
+}
+
+func (s *LevelDBState) getMessages(dkgID string) (map[fsm.Event][]storage.Message, error) {
+	bz, err := s.stateDb.Get(s.key(makeMessagesKey(dkgID)), nil)
+	if err != nil {
+		if err == leveldb.ErrNotFound {
+			return make(map[fsm.Event][]storage.Message), nil
+		}
+		return nil, fmt.Errorf("failed to get messages for dkgID %s: %w", dkgID, err)
+	}
+
+	var messages map[fsm.Event][]storage.Message
+	if err := json.Unmarshal(bz, &messages); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal messages: %w", err)
+	}
+
+	return messages, nil
+}
+
+// SaveMessage saves a signed bulletin board message, so it can be passed to the airgapped machine later
+func (s *LevelDBState) SaveMessage(message storage.Message) error {
+	s.Lock()
+	defer s.Unlock()
+
+	messages, err := s.getMessages(message.DkgRoundID)
+	if err != nil {
+		return fmt.Errorf("failed to getMessages: %w", err)
+	}
+
+	event := fsm.Event(message.Event)
+	messages[event] = append(messages[event], message)
+
+	messagesJSON, err := json.Marshal(messages)
+	if err != nil {
+		return fmt.Errorf("failed to marshal messages: %w", err)
+	}
+
+	if err := s.stateDb.Put(s.key(makeMessagesKey(message.DkgRoundID)), messagesJSON, nil); err != nil {
+		return fmt.Errorf("failed to save messages: %w", err)
+	}
+
+	return nil
+}
+
+// GetMessages returns all saved bulletin board messages of the given DKG round with the given event
+func (s *LevelDBState) GetMessages(dkgID string, event fsm.Event) ([]storage.Message, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	messages, err := s.getMessages(dkgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to getMessages: %w", err)
+	}
+
+	return messages[event], nil
+}
+
+func makePubPolyKey(dkgID string) string {
+	return fmt.Sprintf("%s_%s", pubPolyKeyPrefix, dkgID)
+}
+
+// SavePubPoly saves commits of the public polynomial of a finished DKG round
+func (s *LevelDBState) SavePubPoly(dkgID string, pubPoly []byte) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if err := s.stateDb.Put(s.key(makePubPolyKey(dkgID)), pubPoly, nil); err != nil {
+		return fmt.Errorf("failed to save public polynomial: %w", err)
+	}
+	return nil
+}
+
+// LoadPubPoly returns commits of the public polynomial of a finished DKG round
+func (s *LevelDBState) LoadPubPoly(dkgID string) ([]byte, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	bz, err := s.stateDb.Get(s.key(makePubPolyKey(dkgID)), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get public polynomial for dkgID %s: %w", dkgID, err)
+	}
+	return bz, nil
+}
+
+func (s *LevelDBState) getDKGKeys() (map[string]*types.DKGKey, error) {
+	bz, err := s.stateDb.Get(s.key(dkgKeysKey), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get DKG keys (key: %s): %w", dkgKeysKey, err)
+	}
+
+	var keys map[string]*types.DKGKey
+	if err := json.Unmarshal(bz, &keys); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal DKG keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+// SaveDKGKey saves the public key of a finished DKG round to the registry
+func (s *LevelDBState) SaveDKGKey(key *types.DKGKey) error {
+	s.Lock()
+	defer s.Unlock()
+
+	keys, err := s.getDKGKeys()
+	if err != nil {
+		return fmt.Errorf("failed to getDKGKeys: %w", err)
+	}
+
+	keys[key.DKGRoundID] = key
+	keysJSON, err := json.Marshal(keys)
+	if err != nil {
+		return fmt.Errorf("failed to marshal DKG keys: %w", err)
+	}
+
+	if err := s.stateDb.Put(s.key(dkgKeysKey), keysJSON, nil); err != nil {
+		return fmt.Errorf("failed to put DKG keys: %w", err)
+	}
+
+	return nil
+}
+
+func (s *LevelDBState) GetDKGKey(dkgID string) (*types.DKGKey, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	keys, err := s.getDKGKeys()
+	if err != nil {
+		return nil, fmt.Errorf("failed to getDKGKeys: %w", err)
+	}
+
+	key, ok := keys[dkgID]
+	if !ok {
+		return nil, errors.New("DKG key not found")
+	}
+
+	return key, nil
+}
+
+// GetDKGKeys returns public keys of all finished DKG rounds
+func (s *LevelDBState) GetDKGKeys() (map[string]*types.DKGKey, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.getDKGKeys()
+}
@@ -115,3 +115,66 @@ func TestLevelDBState_DeleteOperation(t *testing.T) {
 	_, err = stg.GetOperationByID(operation.ID)
 	req.Error(err)
 }
+
+func TestLevelDBState_SavePubPoly(t *testing.T) {
+	var (
+		req    = require.New(t)
+		dbPath = "/tmp/dc4bc_test_SavePubPoly"
+	)
+	defer os.RemoveAll(dbPath)
+
+	stg, err := client.NewLevelDBState(dbPath)
+	req.NoError(err)
+
+	_, err = stg.LoadPubPoly("dkg_id")
+	req.Error(err)
+
+	pubPoly := []byte("pub_poly")
+	err = stg.SavePubPoly("dkg_id", pubPoly)
+	req.NoError(err)
+
+	loadedPubPoly, err := stg.LoadPubPoly("dkg_id")
+	req.NoError(err)
+	req.Equal(pubPoly, loadedPubPoly)
+}
+
+func TestLevelDBState_SaveDKGKey(t *testing.T) {
+	var (
+		req    = require.New(t)
+		dbPath = "/tmp/dc4bc_test_SaveDKGKey"
+	)
+	defer os.RemoveAll(dbPath)
+
+	stg, err := client.NewLevelDBState(dbPath)
+	req.NoError(err)
+
+	keys, err := stg.GetDKGKeys()
+	req.NoError(err)
+	req.Empty(keys)
+
+	key := &types.DKGKey{
+		DKGRoundID:   "dkg_id",
+		MasterPubKey: []byte("master_pub_key"),
+		PrysmPubKey:  "0x6d61737465725f7075625f6b6579",
+		Threshold:    2,
+		Participants: []types.DKGKeyParticipant{
+			{ParticipantID: 0, Username: "participant_0", Index: 0, PubShare: []byte("pub_share_0")},
+			{ParticipantID: 1, Username: "participant_1", Index: 1, PubShare: []byte("pub_share_1")},
+		},
+		CreatedAt: time.Now(),
+	}
+	err = stg.SaveDKGKey(key)
+	req.NoError(err)
+
+	loadedKey, err := stg.GetDKGKey(key.DKGRoundID)
+	req.NoError(err)
+	req.NoError(loadedKey.Check(key))
+	req.True(key.CreatedAt.Equal(loadedKey.CreatedAt))
+
+	keys, err = stg.GetDKGKeys()
+	req.NoError(err)
+	req.Len(keys, 1)
+
+	_, err = stg.GetDKGKey("unknown_dkg_id")
+	req.Error(err)
+}
@@ -2,7 +2,10 @@ package types
 
 import (
 	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
@@ -18,10 +21,20 @@ import (
 type OperationType string
 
 const (
-	DKGCommits             OperationType = "dkg_commits"
-	SignatureReconstructed fsm.Event     = "signature_reconstructed"
+	DKGCommits                    OperationType = "dkg_commits"
+	SignatureReconstructed        fsm.Event     = "signature_reconstructed"
+	SignatureReconstructionFailed fsm.Event     = "signature_reconstruction_failed"
 )
 
+// SignatureReconstructionError is broadcast when the airgapped machine fails to reconstruct the signature.
+// Other participants reconstruct the signature on their own, so the message is not passed to the FSM.
+type SignatureReconstructionError struct {
+	SigningID     string
+	ParticipantId int
+	Error         *requests.ProtocolError
+	CreatedAt     time.Time
+}
+
 type ReconstructedSignature struct {
 	SigningID  string
 	SrcPayload []byte
@@ -30,6 +43,53 @@ type ReconstructedSignature struct {
 	DKGRoundID string
 }
 
+// DKGKey is the public key of a finished DKG round
+type DKGKey struct {
+	DKGRoundID   string
+	MasterPubKey []byte
+	// PrysmPubKey is the master public key in the encoding used by prysm: 0x-prefixed hex of the compressed point
+	PrysmPubKey  string
+	Threshold    int
+	Participants []DKGKeyParticipant
+	CreatedAt    time.Time
+}
+
+// DKGKeyParticipant is a participant of a finished DKG round with the public key share
+// used to verify partial signatures of the participant
+type DKGKeyParticipant struct {
+	ParticipantID int
+	Username      string
+	Index         int
+	PubShare      []byte
+}
+
+// Check compares the key with the key built from the FSM state, the creation time is not compared
+func (k *DKGKey) Check(k2 *DKGKey) error {
+	if k.DKGRoundID != k2.DKGRoundID {
+		return errors.New("DKG round IDs are not equal")
+	}
+	if !bytes.Equal(k.MasterPubKey, k2.MasterPubKey) || k.PrysmPubKey != k2.PrysmPubKey {
+		return errors.New("master public keys are not equal")
+	}
+	if k.Threshold != k2.Threshold {
+		return errors.New("thresholds are not equal")
+	}
+	if len(k.Participants) != len(k2.Participants) {
+		return errors.New("participants are not equal")
+	}
+	for i, participant := range k.Participants {
+		participant2 := k2.Participants[i]
+		if participant.ParticipantID != participant2.ParticipantID || participant.Username != participant2.Username ||
+			participant.Index != participant2.Index {
+			return fmt.Errorf("participant %d is not equal", participant.ParticipantID)
+		}
+		if !bytes.Equal(participant.PubShare, participant2.PubShare) {
+			return fmt.Errorf("public shares of participant %d are not equal", participant.ParticipantID)
+		}
+	}
+	return nil
+}
+
 // Operation is the type for any Operation that might be required for
 // both DKG and signing process (e.g.,
 type Operation struct {
@@ -41,6 +101,132 @@ type Operation struct {
 	DKGIdentifier string
 	To            string
 	Event         fsm.Event
+	// SourceMessages are the signed bulletin board messages the payload was built from, so the airgapped
+	// machine is able to check that the payload was not forged by the hot node
+	SourceMessages []storage.Message
+	// Signature is a signature of the operation made with the hot node key
+	Signature []byte
+	// ResultSignature is a signature of the processed operation made with the airgapped machine identity key
+	ResultSignature []byte
+	// Policy is the decision of the signing policy of the node, it is local to the node and is not signed.
+	// It is not set for operations allowed by the default action.
+	Policy *PolicyDecision `json:",omitempty"`
+}
+
+// PolicyAction is what the node does with a signing request of another participant
+type PolicyAction string
+
+const (
+	PolicyAllow           PolicyAction = "allow"
+	PolicyDecline         PolicyAction = "decline"
+	PolicyFlag            PolicyAction = "flag"
+	PolicyRequireApproval PolicyAction = "require_approval"
+)
+
+// PolicyDecision is the decision of the signing policy of the node on a signing request
+type PolicyDecision struct {
+	Action PolicyAction
+	// Rule is the name of the matched rule, it is empty when the default action is applied
+	Rule       string
+	Reason     string
+	ApprovedBy string     `json:",omitempty"`
+	ApprovedAt *time.Time `json:",omitempty"`
+}
+
+// AwaitsApproval returns true if the operation can not be handled until it is approved by a second approver
+func (d *PolicyDecision) AwaitsApproval() bool {
+	return d != nil && d.Action == PolicyRequireApproval && d.ApprovedBy == ""
+}
+
+// SigningBytes returns the operation data covered by the hot node signature
+func (o *Operation) SigningBytes() ([]byte, error) {
+	sourceMessagesBz, err := json.Marshal(o.SourceMessages)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal source messages: %w", err)
+	}
+	sourceMessagesHash := sha256.Sum256(sourceMessagesBz)
+	payloadHash := sha256.Sum256(o.Payload)
+
+	buf := bytes.NewBuffer(nil)
+	buf.WriteString(o.ID)
+	buf.WriteString(string(o.Type))
+	buf.WriteString(string(o.Event))
+	buf.Write(payloadHash[:])
+	buf.WriteString(o.DKGIdentifier)
+	buf.WriteString(o.To)
+	buf.WriteString(o.CreatedAt.UTC().Format(time.RFC3339Nano))
+	buf.Write(sourceMessagesHash[:])
+
+	return buf.Bytes(), nil
+}
+
+// Sign signs the operation with the hot node key
+func (o *Operation) Sign(priv ed25519.PrivateKey) error {
+	signingBytes, err := o.SigningBytes()
+	if err != nil {
+		return err
+	}
+	o.Signature = ed25519.Sign(priv, signingBytes)
+	return nil
+}
+
+// VerifySignature checks that the operation was signed with the hot node key
+func (o *Operation) VerifySignature(pub ed25519.PublicKey) error {
+	if len(o.Signature) == 0 {
+		return fmt.Errorf("operation %s is not signed", o.ID)
+	}
+	signingBytes, err := o.SigningBytes()
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(pub, signingBytes, o.Signature) {
+		return errors.New("operation signature is corrupt")
+	}
+	return nil
+}
+
+// ResultSigningBytes returns the processed operation data covered by the airgapped machine signature
+func (o *Operation) ResultSigningBytes() ([]byte, error) {
+	signingBytes, err := o.SigningBytes()
+	if err != nil {
+		return nil, err
+	}
+	resultMsgsBz, err := json.Marshal(o.ResultMsgs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal result messages: %w", err)
+	}
+	resultMsgsHash := sha256.Sum256(resultMsgsBz)
+
+	buf := bytes.NewBuffer(signingBytes)
+	buf.WriteString(string(o.Event))
+	buf.Write(resultMsgsHash[:])
+
+	return buf.Bytes(), nil
+}
+
+// SignResult signs the processed operation with the airgapped machine identity key
+func (o *Operation) SignResult(priv ed25519.PrivateKey) error {
+	signingBytes, err := o.ResultSigningBytes()
+	if err != nil {
+		return err
+	}
+	o.ResultSignature = ed25519.Sign(priv, signingBytes)
+	return nil
+}
+
+// VerifyResultSignature checks that the processed operation was signed with the airgapped machine identity key
+func (o *Operation) VerifyResultSignature(pub ed25519.PublicKey) error {
+	if len(o.ResultSignature) == 0 {
+		return fmt.Errorf("result of operation %s is not signed", o.ID)
+	}
+	signingBytes, err := o.ResultSigningBytes()
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(pub, signingBytes, o.ResultSignature) {
+		return errors.New("result signature is corrupt")
+	}
+	return nil
 }
 
 func (o *Operation) Check(o2 *Operation) error {
@@ -66,55 +252,74 @@ func FSMRequestFromMessage(message storage.Message) (interface{}, error) {
 	case signature_proposal_fsm.EventConfirmSignatureProposal:
 		var req requests.SignatureProposalParticipantRequest
 		if err := json.Unmarshal(message.Data, &req); err != nil {
-			return fmt.Errorf("failed to unmarshal fsm req: %v", err), nil
+			return nil, fmt.Errorf("failed to unmarshal fsm req: %v", err)
 		}
 		resolvedValue = req
 	case signature_proposal_fsm.EventInitProposal:
 		var req requests.SignatureProposalParticipantsListRequest
 		if err := json.Unmarshal(message.Data, &req); err != nil {
-			return fmt.Errorf("failed to unmarshal fsm req: %v", err), nil
+			return nil, fmt.Errorf("failed to unmarshal fsm req: %v", err)
 		}
 		resolvedValue = req
 	case dkg_proposal_fsm.EventDKGCommitConfirmationReceived:
 		var req requests.DKGProposalCommitConfirmationRequest
 		if err := json.Unmarshal(message.Data, &req); err != nil {
-			return fmt.Errorf("failed to unmarshal fsm req: %v", err), nil
+			return nil, fmt.Errorf("failed to unmarshal fsm req: %v", err)
 		}
 		resolvedValue = req
 	case dkg_proposal_fsm.EventDKGDealConfirmationReceived:
 		var req requests.DKGProposalDealConfirmationRequest
 		if err := json.Unmarshal(message.Data, &req); err != nil {
-			return fmt.Errorf("failed to unmarshal fsm req: %v", err), nil
+			return nil, fmt.Errorf("failed to unmarshal fsm req: %v", err)
 		}
 		resolvedValue = req
 	case dkg_proposal_fsm.EventDKGResponseConfirmationReceived:
 		var req requests.DKGProposalResponseConfirmationRequest
 		if err := json.Unmarshal(message.Data, &req); err != nil {
-			return fmt.Errorf("failed to unmarshal fsm req: %v", err), nil
+			return nil, fmt.Errorf("failed to unmarshal fsm req: %v", err)
 		}
 		resolvedValue = req
 	case dkg_proposal_fsm.EventDKGMasterKeyConfirmationReceived:
 		var req requests.DKGProposalMasterKeyConfirmationRequest
 		if err := json.Unmarshal(message.Data, &req); err != nil {
-			return fmt.Errorf("failed to unmarshal fsm req: %v", err), nil
+			return nil, fmt.Errorf("failed to unmarshal fsm req: %v", err)
 		}
 		resolvedValue = req
 	case signing_proposal_fsm.EventSigningPartialSignReceived:
 		var req requests.SigningProposalPartialSignRequest
 		if err := json.Unmarshal(message.Data, &req); err != nil {
-			return fmt.Errorf("failed to unmarshal fsm req: %v", err), nil
+			return nil, fmt.Errorf("failed to unmarshal fsm req: %v", err)
 		}
 		resolvedValue = req
 	case signing_proposal_fsm.EventConfirmSigningConfirmation:
 		var req requests.SigningProposalParticipantRequest
 		if err := json.Unmarshal(message.Data, &req); err != nil {
-			return fmt.Errorf("failed to unmarshal fsm req: %v", err), nil
+			return nil, fmt.Errorf("failed to unmarshal fsm req: %v", err)
+		}
+		resolvedValue = req
+	case dkg_proposal_fsm.EventDKGCommitConfirmationError, dkg_proposal_fsm.EventDKGDealConfirmationError,
+		dkg_proposal_fsm.EventDKGResponseConfirmationError, dkg_proposal_fsm.EventDKGMasterKeyConfirmationError:
+		var req requests.DKGProposalConfirmationErrorRequest
+		if err := json.Unmarshal(message.Data, &req); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal fsm req: %v", err)
+		}
+		resolvedValue = req
+	case signing_proposal_fsm.EventSigningPartialSignError:
+		var req requests.SignatureProposalConfirmationErrorRequest
+		if err := json.Unmarshal(message.Data, &req); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal fsm req: %v", err)
+		}
+		resolvedValue = req
+	case signing_proposal_fsm.EventDeclineSigningConfirmation:
+		var req requests.SigningProposalParticipantRequest
+		if err := json.Unmarshal(message.Data, &req); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal fsm req: %v", err)
 		}
 		resolvedValue = req
 	case signing_proposal_fsm.EventSigningStart:
 		var req requests.SigningProposalStartRequest
 		if err := json.Unmarshal(message.Data, &req); err != nil {
-			return fmt.Errorf("failed to unmarshal fsm req: %v", err), nil
+			return nil, fmt.Errorf("failed to unmarshal fsm req: %v", err)
 		}
 		resolvedValue = req
 	default:
@@ -0,0 +1,187 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/lidofinance/dc4bc/client/api"
+)
+
+const (
+	defaultWebhookMaxRetries = 5
+	defaultWebhookRetryDelay = time.Second
+	maxWebhookRetryDelay     = time.Minute
+	webhookTimeout           = 10 * time.Second
+)
+
+// defaultWebhookEvents are the events sent to webhooks when the events are not configured
+var defaultWebhookEvents = []api.EventType{
+	api.EventOperationCreated,
+	api.EventRoundCompleted,
+	api.EventRoundFailed,
+	api.EventSignatureReconstructed,
+	api.EventSignatureReconstructionFailed,
+}
+
+// webhookEventTypes are the events which can be sent to webhooks
+var webhookEventTypes = map[api.EventType]bool{
+	api.EventMessageProcessed:              true,
+	api.EventStateChanged:                  true,
+	api.EventOperationCreated:              true,
+	api.EventOperationHandled:              true,
+	api.EventSignatureReconstructed:        true,
+	api.EventSignatureReconstructionFailed: true,
+	api.EventTimeout:                       true,
+	api.EventRoundCompleted:                true,
+	api.EventRoundFailed:                   true,
+}
+
+// WebhookConfig configures outgoing webhooks. Every event is POSTed as JSON to every URL, the body is signed
+// with HMAC-SHA256 of the secret. Failed deliveries are retried with an exponential backoff starting at RetryDelay.
+type WebhookConfig struct {
+	URLs       []string
+	Secret     string
+	Events     []api.EventType
+	MaxRetries int
+	RetryDelay time.Duration
+}
+
+// SetWebhooks starts sending events to webhooks until the client context is done, it must be called before Poll
+func (c *BaseClient) SetWebhooks(cfg WebhookConfig) error {
+	if len(cfg.URLs) == 0 {
+		return nil
+	}
+	if cfg.Secret == "" {
+		return errors.New("webhook secret is not set")
+	}
+	if len(cfg.Events) == 0 {
+		cfg.Events = defaultWebhookEvents
+	}
+	if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = defaultWebhookMaxRetries
+	}
+	if cfg.RetryDelay == 0 {
+		cfg.RetryDelay = defaultWebhookRetryDelay
+	}
+
+	events := make(map[api.EventType]bool)
+	for _, eventType := range cfg.Events {
+		if !webhookEventTypes[eventType] {
+			return fmt.Errorf("unknown webhook event: %s", eventType)
+		}
+		events[eventType] = true
+	}
+	httpClient := &http.Client{Timeout: webhookTimeout}
+	for _, url := range cfg.URLs {
+		w := &webhook{
+			url:        url,
+			cfg:        cfg,
+			events:     events,
+			username:   c.GetUsername(),
+			httpClient: httpClient,
+			logger:     c.Logger,
+		}
+		// the webhook is subscribed right away to not miss events published before the goroutine starts
+		_, events := c.events.subscribe(0, false)
+		go w.run(c.ctx, c.events, events)
+	}
+	return nil
+}
+
+// webhook delivers events to a single URL in order
+type webhook struct {
+	url        string
+	cfg        WebhookConfig
+	events     map[api.EventType]bool
+	username   string
+	httpClient *http.Client
+	logger     *logger
+}
+
+func (w *webhook) run(ctx context.Context, bus *eventBus, events chan api.Event) {
+	var lastOffset uint64
+	for {
+		for open := true; open; {
+			select {
+			case <-ctx.Done():
+				bus.unsubscribe(events)
+				return
+			case event, ok := <-events:
+				if !ok {
+					open = false
+					break
+				}
+				w.deliver(ctx, event)
+				lastOffset = event.Offset
+			}
+		}
+
+		// the webhook lagged behind the bus, so it is resubscribed after the last delivered event
+		w.logger.with("url", w.url).Warn("Webhook lagged behind events, resuming after event %d", lastOffset)
+		var past []api.Event
+		past, events = bus.subscribe(lastOffset, true)
+		for _, event := range past {
+			w.deliver(ctx, event)
+			lastOffset = event.Offset
+		}
+	}
+}
+
+// deliver sends the event retrying failed requests, the event is dropped when retries are exhausted
+func (w *webhook) deliver(ctx context.Context, event api.Event) {
+	if !w.events[event.Type] {
+		return
+	}
+	body, err := json.Marshal(api.WebhookPayload{Username: w.username, Event: event})
+	if err != nil {
+		w.logger.with("error", err).Error("Failed to marshal webhook payload")
+		return
+	}
+
+	delay := w.cfg.RetryDelay
+	for attempt := 0; ; attempt++ {
+		if err = w.send(ctx, event, body); err == nil {
+			return
+		}
+		if attempt >= w.cfg.MaxRetries {
+			w.logger.with("url", w.url).with("error", err).Error("Failed to send event %d to webhook, giving up", event.Offset)
+			return
+		}
+		w.logger.with("url", w.url).with("error", err).Warn("Failed to send event %d to webhook, retrying in %s", event.Offset, delay)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		if delay *= 2; delay > maxWebhookRetryDelay {
+			delay = maxWebhookRetryDelay
+		}
+	}
+}
+
+func (w *webhook) send(ctx context.Context, event api.Event, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(api.WebhookEventHeader, string(event.Type))
+	req.Header.Set(api.WebhookDeliveryHeader, strconv.FormatUint(event.Offset, 10))
+	req.Header.Set(api.WebhookSignatureHeader, api.SignWebhook([]byte(w.cfg.Secret), body))
+
+	resp, err := w.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lidofinance/dc4bc/client/api"
+	"github.com/stretchr/testify/require"
+)
+
+// webhookReceiver is a local HTTP receiver of webhooks which fails the first failures requests
+type webhookReceiver struct {
+	sync.Mutex
+	t        *testing.T
+	secret   []byte
+	failures int
+	requests int
+	payloads chan api.WebhookPayload
+}
+
+func (r *webhookReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, err := ioutil.ReadAll(req.Body)
+	require.NoError(r.t, err)
+	if err = api.VerifyWebhook(r.secret, body, req.Header.Get(api.WebhookSignatureHeader)); err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	r.Lock()
+	r.requests++
+	fail := r.requests <= r.failures
+	r.Unlock()
+	if fail {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	var payload api.WebhookPayload
+	require.NoError(r.t, json.Unmarshal(body, &payload))
+	require.Equal(r.t, string(payload.Event.Type), req.Header.Get(api.WebhookEventHeader))
+	r.payloads <- payload
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func TestWebhooks(t *testing.T) {
+	req := require.New(t)
+
+	receiver := &webhookReceiver{
+		t:        t,
+		secret:   []byte("secret"),
+		failures: 2,
+		payloads: make(chan api.WebhookPayload, 10),
+	}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	dir, err := ioutil.TempDir("", "dc4bc_test_webhooks")
+	req.NoError(err)
+	defer os.RemoveAll(dir)
+	state, err := NewLevelDBState(filepath.Join(dir, "state"))
+	req.NoError(err)
+	c := newTestClient(t, ctx, dir, "test", state, nil)
+
+	req.Error(c.SetWebhooks(WebhookConfig{URLs: []string{server.URL}}))
+	req.Error(c.SetWebhooks(WebhookConfig{URLs: []string{server.URL}, Secret: "secret", Events: []api.EventType{"unknown"}}))
+	req.NoError(c.SetWebhooks(WebhookConfig{
+		URLs:       []string{server.URL},
+		Secret:     "secret",
+		RetryDelay: 10 * time.Millisecond,
+	}))
+
+	c.events.publish(
+		api.Event{Type: api.EventMessageProcessed, DKGRoundID: "round"},
+		api.Event{Type: api.EventOperationCreated, DKGRoundID: "round", OperationID: "operation"},
+		api.Event{Type: api.EventRoundCompleted, DKGRoundID: "round"},
+	)
+
+	// the first event is filtered out, the second one is delivered after two retries
+	for _, expected := range []api.EventType{api.EventOperationCreated, api.EventRoundCompleted} {
+		select {
+		case payload := <-receiver.payloads:
+			req.Equal("test", payload.Username)
+			req.Equal(expected, payload.Event.Type)
+			req.Equal("round", payload.Event.DKGRoundID)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("webhook %s was not received", expected)
+		}
+	}
+	receiver.Lock()
+	req.Equal(4, receiver.requests)
+	receiver.Unlock()
+}
+
+func TestVerifyWebhook(t *testing.T) {
+	req := require.New(t)
+
+	body := []byte(`{"username":"test"}`)
+	signature := api.SignWebhook([]byte("secret"), body)
+	req.NoError(api.VerifyWebhook([]byte("secret"), body, signature))
+	req.Error(api.VerifyWebhook([]byte("other secret"), body, signature))
+	req.Error(api.VerifyWebhook([]byte("secret"), []byte(`{"username":"other"}`), signature))
+	req.Error(api.VerifyWebhook([]byte("secret"), body, "md5=abc"))
+}
@@ -4,6 +4,8 @@ import (
 	"bufio"
 	"bytes"
 	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"github.com/syndtr/goleveldb/leveldb"
@@ -21,6 +23,7 @@ import (
 	"golang.org/x/crypto/ssh/terminal"
 
 	"github.com/lidofinance/dc4bc/airgapped"
+	"github.com/lidofinance/dc4bc/transport"
 )
 
 func init() {
@@ -39,6 +42,8 @@ type prompt struct {
 	oldTerminalState *terminal.State
 	reader           *bufio.Reader
 	airgapped        *airgapped.Machine
+	transportDir     string
+	audit            *airgapped.AuditLog
 	commands         map[string]*promptCommand
 
 	currentCommand            string
@@ -47,10 +52,12 @@ type prompt struct {
 	exit chan bool
 }
 
-func NewPrompt(machine *airgapped.Machine) (*prompt, error) {
+func NewPrompt(machine *airgapped.Machine, transportDir string, audit *airgapped.AuditLog) (*prompt, error) {
 	p := prompt{
 		reader:                    bufio.NewReaderSize(os.Stdin, 100000),
 		airgapped:                 machine,
+		transportDir:              transportDir,
+		audit:                     audit,
 		commands:                  make(map[string]*promptCommand),
 		currentCommand:            "",
 		stopDroppingSensitiveData: make(chan bool),
@@ -64,7 +71,7 @@ func NewPrompt(machine *airgapped.Machine) (*prompt, error) {
 
 	p.addCommand("read_op", &promptCommand{
 		commandHandler: p.readOPCommand,
-		description:    "Reads a JSON file, handles a decoded operation and returns paths to qr chunks of operation's result",
+		description:    "Reads an operation bundle from the file <operation ID>.json and writes the result bundle to <operation ID>_res.json",
 	})
 	p.addCommand("help", &promptCommand{
 		commandHandler: p.helpCommand,
@@ -74,10 +81,22 @@ func NewPrompt(machine *airgapped.Machine) (*prompt, error) {
 		commandHandler: p.showDKGPubKeyCommand,
 		description:    "shows a dkg pub key",
 	})
+	p.addCommand("show_identity_pubkey", &promptCommand{
+		commandHandler: p.showIdentityPubKeyCommand,
+		description:    "shows an identity pub key which should be passed to the hot node to verify results of operations",
+	})
+	p.addCommand("pin_participant_hot_key", &promptCommand{
+		commandHandler: p.pinParticipantHotKeyCommand,
+		description:    "pins the hot node public key of another participant, dkg rounds listing the participant with another key are refused",
+	})
 	p.addCommand("show_finished_dkg", &promptCommand{
 		commandHandler: p.showFinishedDKGCommand,
 		description:    "shows a list of finished dkg rounds",
 	})
+	p.addCommand("verify_share", &promptCommand{
+		commandHandler: p.verifyShareCommand,
+		description:    "verifies the key share of a finished dkg round and shows the public key share with its index",
+	})
 	p.addCommand("replay_operations_log", &promptCommand{
 		commandHandler: p.replayOperationLogCommand,
 		description:    "replays the operation log for a given dkg round",
@@ -98,6 +117,38 @@ func NewPrompt(machine *airgapped.Machine) (*prompt, error) {
 		commandHandler: p.changeConfigurationCommand,
 		description:    "changes a configuration variables (frames delay, chunk size, etc...)",
 	})
+	p.addCommand("process_inbox", &promptCommand{
+		commandHandler: p.processInboxCommand,
+		description:    "handles all operation bundles from the transport folder and writes results back",
+	})
+	p.addCommand("show_mnemonic", &promptCommand{
+		commandHandler: p.showMnemonicCommand,
+		description:    "shows a BIP-39 mnemonic of the machine's seed, keep it offline",
+	})
+	p.addCommand("export_operations_log", &promptCommand{
+		commandHandler: p.exportOperationsLogCommand,
+		description:    "writes operations logs of all dkg rounds to a password-encrypted file, needed to restore keys from the mnemonic",
+	})
+	p.addCommand("restore_from_mnemonic", &promptCommand{
+		commandHandler: p.restoreFromMnemonicCommand,
+		description:    "restores keys of the machine from a BIP-39 mnemonic and operations logs",
+	})
+	p.addCommand("change_password", &promptCommand{
+		commandHandler: p.changePasswordCommand,
+		description:    "changes the encryption password and re-encrypts all the data",
+	})
+	p.addCommand("re_encrypt", &promptCommand{
+		commandHandler: p.reEncryptCommand,
+		description:    "re-encrypts all the data with the current kdf (see --kdf flag) and a new salt",
+	})
+	p.addCommand("export_keyrings", &promptCommand{
+		commandHandler: p.exportKeyringsCommand,
+		description:    "writes finished dkg rounds with operations logs to a password-encrypted archive",
+	})
+	p.addCommand("import_keyrings", &promptCommand{
+		commandHandler: p.importKeyringsCommand,
+		description:    "reads finished dkg rounds from an archive made by export_keyrings",
+	})
 	return &p, nil
 }
 
@@ -148,24 +199,35 @@ func (p *prompt) readOPCommand() error {
 		return fmt.Errorf("failed to read input: %w", err)
 	}
 
-	operationFile := fmt.Sprintf("%s.json", string(fileName))
-	f, err := ioutil.ReadFile(operationFile)
+	tr := transport.NewFileTransport(fmt.Sprintf("%s.json", fileName), fmt.Sprintf("%s_res.json", fileName))
+	_, rejected, err := p.airgapped.ProcessBundles(tr)
 	if err != nil {
-		return fmt.Errorf("failed to open operation file %s: %w", operationFile, err)
+		return err
 	}
+	if rejected > 0 {
+		return fmt.Errorf("operation is rejected, see the reason in %s", tr.RejectReasonFile())
+	}
+
+	p.println("Success - ")
+	return nil
+}
 
-	opResponse, err := p.airgapped.HandleQR(f)
+func (p *prompt) processInboxCommand() error {
+	tr, err := transport.NewAirgappedDirTransport(p.transportDir)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to init transport: %w", err)
 	}
 
-	outFileName := fmt.Sprintf("%s_res.json", string(fileName))
-	err = ioutil.WriteFile(outFileName, []byte(opResponse), 0400)
+	processed, rejected, err := p.airgapped.ProcessBundles(tr)
 	if err != nil {
-		return fmt.Errorf("failed to write result to %s: %w", outFileName, err)
+		p.printf("Processed %d and rejected %d bundles before the error\n", processed, rejected)
+		return err
 	}
 
-	p.println("Success - ")
+	p.printf("Successfully processed %d bundles\n", processed)
+	if rejected > 0 {
+		p.printf("Rejected %d bundles, see the reasons in the rejected folder of the inbox\n", rejected)
+	}
 	return nil
 }
 
@@ -180,6 +242,219 @@ func (p *prompt) showDKGPubKeyCommand() error {
 	return nil
 }
 
+func (p *prompt) showIdentityPubKeyCommand() error {
+	p.println(base64.StdEncoding.EncodeToString(p.airgapped.GetIdentityPubKey()))
+	return nil
+}
+
+func (p *prompt) pinParticipantHotKeyCommand() error {
+	p.print("> Enter the username of the participant: ")
+	username, err := p.terminal.ReadLine()
+	if err != nil {
+		return fmt.Errorf("failed to read username: %w", err)
+	}
+	p.print("> Enter the hot node public key of the participant (base64): ")
+	pubKeyInput, err := p.terminal.ReadLine()
+	if err != nil {
+		return fmt.Errorf("failed to read hot node public key: %w", err)
+	}
+	pubKey, err := base64.StdEncoding.DecodeString(strings.TrimSpace(pubKeyInput))
+	if err != nil {
+		return fmt.Errorf("failed to decode public key: %w", err)
+	}
+	if err = p.airgapped.PinParticipantHotKey(strings.TrimSpace(username), pubKey); err != nil {
+		return err
+	}
+	p.printf("Hot node public key of %s is pinned\n", strings.TrimSpace(username))
+	return nil
+}
+
+func (p *prompt) showMnemonicCommand() error {
+	mnemonic, err := p.airgapped.GetBaseSeedMnemonic()
+	if err != nil {
+		return err
+	}
+	p.println(mnemonic)
+	return nil
+}
+
+func (p *prompt) exportOperationsLogCommand() error {
+	p.print("> Enter a path to save operations logs: ")
+	path, err := p.reader.ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("failed to read path: %w", err)
+	}
+
+	password, err := p.readNewPassword("archive password")
+	if err != nil {
+		return err
+	}
+
+	archive, err := p.airgapped.ExportOperationsLogs(password)
+	if err != nil {
+		return fmt.Errorf("failed to export operations logs: %w", err)
+	}
+	if err = ioutil.WriteFile(strings.TrimSpace(path), archive, 0600); err != nil {
+		return fmt.Errorf("failed to write operations logs: %w", err)
+	}
+	p.printf("Operations logs were saved to %s\n", strings.TrimSpace(path))
+	return nil
+}
+
+func (p *prompt) restoreFromMnemonicCommand() error {
+	p.print("> Enter the mnemonic: ")
+	mnemonic, err := p.reader.ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("failed to read mnemonic: %w", err)
+	}
+
+	p.print("> Enter a path to exported operations logs (leave empty to use the stored ones): ")
+	path, err := p.reader.ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("failed to read path: %w", err)
+	}
+
+	var operationsLogs airgapped.RoundOperationLog
+	if path = strings.TrimSpace(path); len(path) > 0 {
+		operationsLogsBz, err := ioutil.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read operations logs: %w", err)
+		}
+		// operations logs exported by earlier versions are plaintext
+		if airgapped.IsOperationsLogsArchive(operationsLogsBz) {
+			p.print("Enter archive password: ")
+			password, err := terminal.ReadPassword(syscall.Stdin)
+			if err != nil {
+				return fmt.Errorf("failed to read password: %w", err)
+			}
+			p.println()
+			if operationsLogs, err = airgapped.OpenOperationsLogsArchive(operationsLogsBz, password); err != nil {
+				return fmt.Errorf("failed to open operations logs: %w", err)
+			}
+		} else if err = json.Unmarshal(operationsLogsBz, &operationsLogs); err != nil {
+			return fmt.Errorf("failed to unmarshal operations logs: %w", err)
+		}
+	}
+
+	if err = p.airgapped.RestoreFromMnemonic(strings.TrimSpace(mnemonic), operationsLogs); err != nil {
+		return fmt.Errorf("failed to restore from mnemonic: %w", err)
+	}
+	p.println("Keys were successfully restored")
+	return nil
+}
+
+// readNewPassword reads a new password and its confirmation
+func (p *prompt) readNewPassword(name string) ([]byte, error) {
+	p.printf("Enter %s: ", name)
+	password, err := terminal.ReadPassword(syscall.Stdin)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read password: %w", err)
+	}
+	p.println()
+	p.printf("Confirm %s: ", name)
+	confirmedPassword, err := terminal.ReadPassword(syscall.Stdin)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read password: %w", err)
+	}
+	p.println()
+	if !bytes.Equal(password, confirmedPassword) {
+		return nil, errors.New("passwords do not match")
+	}
+	return password, nil
+}
+
+func (p *prompt) changePasswordCommand() error {
+	p.print("Enter current encryption password: ")
+	oldPassword, err := terminal.ReadPassword(syscall.Stdin)
+	if err != nil {
+		return fmt.Errorf("failed to read password: %w", err)
+	}
+	p.println()
+
+	p.print("Enter new encryption password: ")
+	newPassword, err := terminal.ReadPassword(syscall.Stdin)
+	if err != nil {
+		return fmt.Errorf("failed to read password: %w", err)
+	}
+	p.println()
+	p.print("Confirm new encryption password: ")
+	confirmedPassword, err := terminal.ReadPassword(syscall.Stdin)
+	if err != nil {
+		return fmt.Errorf("failed to read password: %w", err)
+	}
+	p.println()
+	if !bytes.Equal(newPassword, confirmedPassword) {
+		return errors.New("passwords do not match")
+	}
+
+	if err = p.airgapped.ChangePassword(oldPassword, newPassword); err != nil {
+		return fmt.Errorf("failed to change password: %w", err)
+	}
+	p.println("Password was successfully changed")
+	return nil
+}
+
+func (p *prompt) reEncryptCommand() error {
+	reEncrypted, err := p.airgapped.ReEncrypt()
+	if err != nil {
+		return fmt.Errorf("failed to re-encrypt data: %w", err)
+	}
+	p.printf("Successfully re-encrypted %d entries\n", reEncrypted)
+	return nil
+}
+
+func (p *prompt) exportKeyringsCommand() error {
+	p.print("> Enter a path to save the archive: ")
+	path, err := p.reader.ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("failed to read path: %w", err)
+	}
+
+	password, err := p.readNewPassword("archive password")
+	if err != nil {
+		return err
+	}
+
+	archive, err := p.airgapped.ExportKeyrings(password)
+	if err != nil {
+		return fmt.Errorf("failed to export keyrings: %w", err)
+	}
+	if err = ioutil.WriteFile(strings.TrimSpace(path), archive, 0600); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	p.printf("Keyrings were exported to %s\n", strings.TrimSpace(path))
+	return nil
+}
+
+func (p *prompt) importKeyringsCommand() error {
+	p.print("> Enter a path to the archive: ")
+	path, err := p.reader.ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("failed to read path: %w", err)
+	}
+
+	archive, err := ioutil.ReadFile(strings.TrimSpace(path))
+	if err != nil {
+		return fmt.Errorf("failed to read archive: %w", err)
+	}
+
+	p.print("Enter archive password: ")
+	password, err := terminal.ReadPassword(syscall.Stdin)
+	if err != nil {
+		return fmt.Errorf("failed to read password: %w", err)
+	}
+	p.println()
+
+	imported, err := p.airgapped.ImportKeyrings(archive, password)
+	if err != nil {
+		return fmt.Errorf("failed to import keyrings: %w", err)
+	}
+	for _, dkgID := range imported {
+		p.printf("Imported DKG round: %s\n", dkgID)
+	}
+	return nil
+}
+
 func (p *prompt) helpCommand() error {
 	p.println("Available commands:")
 	for commandName, command := range p.commands {
@@ -206,6 +481,41 @@ func (p *prompt) showFinishedDKGCommand() error {
 	return nil
 }
 
+func (p *prompt) verifyShareCommand() error {
+	p.print("> Enter the DKGRoundIdentifier: ")
+	dkgRoundIdentifier, err := p.reader.ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("failed to read dkgRoundIdentifier: %w", err)
+	}
+
+	publicShare, err := p.airgapped.VerifyShare(strings.TrimSpace(dkgRoundIdentifier))
+	if err != nil {
+		return fmt.Errorf("failed to verify share: %w", err)
+	}
+	p.println("Share is valid!")
+	p.printf("Share index: %d\n", publicShare.Index)
+	p.printf("Public share: %s\n", base64.StdEncoding.EncodeToString(publicShare.PubShare))
+	p.printf("PubKey: %s\n", base64.StdEncoding.EncodeToString(publicShare.MasterPubKey))
+
+	p.print("> Enter a path to save the public share (leave empty to skip): ")
+	path, err := p.reader.ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("failed to read path: %w", err)
+	}
+	if path = strings.TrimSpace(path); path == "" {
+		return nil
+	}
+	publicShareBz, err := json.Marshal(publicShare)
+	if err != nil {
+		return fmt.Errorf("failed to marshal public share: %w", err)
+	}
+	if err = ioutil.WriteFile(path, publicShareBz, 0644); err != nil {
+		return fmt.Errorf("failed to write public share: %w", err)
+	}
+	p.printf("Public share was saved to %s\n", path)
+	return nil
+}
+
 func (p *prompt) replayOperationLogCommand() error {
 	p.print("> Enter the DKGRoundIdentifier: ")
 	dkgRoundIdentifier, err := p.reader.ReadString('\n')
@@ -356,6 +666,45 @@ func (p *prompt) enterEncryptionPasswordIfNeeded() error {
 			p.printf("Failed to init keys: %v\n", err)
 			continue
 		}
+		if repeatPassword {
+			mnemonic, err := p.airgapped.GetBaseSeedMnemonic()
+			if err != nil {
+				return err
+			}
+			p.println("Write down the mnemonic below and keep it offline, together with the exported operations logs it allows to restore the keys:")
+			p.println(mnemonic)
+		}
+		break
+	}
+	return nil
+}
+
+// enterHotPubKeyIfNeeded pins the public key of the hot node on the first start,
+// operations which are not signed with the corresponding private key will be rejected
+func (p *prompt) enterHotPubKeyIfNeeded() error {
+	_, err := p.airgapped.GetHotPubKey()
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, airgapped.ErrHotPubKeyNotSet) {
+		return err
+	}
+
+	for {
+		p.print("Enter the hot node public key (base64, see `dc4bc_cli get_pubkey`): ")
+		pubKeyInput, err := p.terminal.ReadLine()
+		if err != nil {
+			return fmt.Errorf("failed to read hot node public key: %w", err)
+		}
+		pubKey, err := base64.StdEncoding.DecodeString(strings.TrimSpace(pubKeyInput))
+		if err != nil {
+			p.printf("Failed to decode public key: %v\n", err)
+			continue
+		}
+		if err = p.airgapped.SetHotPubKey(pubKey); err != nil {
+			p.printf("Failed to set hot node public key: %v\n", err)
+			continue
+		}
 		break
 	}
 	return nil
@@ -365,6 +714,9 @@ func (p *prompt) run() error {
 	if err := p.enterEncryptionPasswordIfNeeded(); err != nil {
 		return err
 	}
+	if err := p.enterHotPubKeyIfNeeded(); err != nil {
+		return err
+	}
 	if err := p.helpCommand(); err != nil {
 		return err
 	}
@@ -402,9 +754,13 @@ func (p *prompt) run() error {
 			// we need to "turn off" terminal lib during command execution to be able to handle OS notifications inside
 			// commands and to read data from stdin without terminal features
 			p.restoreTerminal()
-			if err := handler.commandHandler(); err != nil {
+			err = handler.commandHandler()
+			if err != nil {
 				p.printf("failed to execute command %s: %v \n", command, err)
 			}
+			if err = p.audit.RecordCommand(clearCommand, err); err != nil {
+				p.printf("failed to write command %s to the audit log: %v \n", command, err)
+			}
 			// after command done, we turning terminal lib back on
 			if err = p.makeTerminal(); err != nil {
 				return err
@@ -468,6 +824,9 @@ var (
 	framesDelay        int
 	chunkSize          int
 	qrCodesFolder      string
+	transportDir       string
+	kdfName            string
+	auditLogPath       string
 )
 
 func init() {
@@ -476,6 +835,9 @@ func init() {
 	flag.IntVar(&framesDelay, "frames_delay", 10, "Delay times between frames in 100ths of a second")
 	flag.IntVar(&chunkSize, "chunk_size", 256, "QR-code's chunk size")
 	flag.StringVar(&qrCodesFolder, "qr_codes_folder", "/tmp/", "Folder to save result QR codes")
+	flag.StringVar(&transportDir, "transport_dir", "transport", "Folder (e.g. on a removable drive) to exchange operation bundles with the hot node")
+	flag.StringVar(&kdfName, "kdf", "scrypt", "Key derivation function to encrypt the data with (scrypt or argon2id)")
+	flag.StringVar(&auditLogPath, "audit_log", "airgapped_audit.log", "Path to the log of handled commands and operations, empty path disables the log")
 }
 
 func main() {
@@ -492,10 +854,25 @@ func main() {
 	}
 	air.SetResultQRFolder(qrCodesFolder)
 
+	kdf, err := airgapped.NewKDF(kdfName)
+	if err != nil {
+		log.Fatalf("invalid kdf: %v", err)
+	}
+	air.SetKDF(kdf)
+
+	var auditLog *airgapped.AuditLog
+	if auditLogPath != "" {
+		if auditLog, err = airgapped.OpenAuditLog(auditLogPath); err != nil {
+			log.Fatalf("failed to init audit log: %v", err)
+		}
+		defer auditLog.Close()
+	}
+	air.SetAuditLog(auditLog)
+
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt)
 
-	p, err := NewPrompt(air)
+	p, err := NewPrompt(air, transportDir, auditLog)
 	if err != nil {
 		log.Fatalf(err.Error())
 	}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+
+	"github.com/lidofinance/dc4bc/client/api"
+	"github.com/spf13/cobra"
+)
+
+const (
+	flagTLSCA       = "tls_ca"
+	flagTLSCert     = "tls_cert"
+	flagTLSKey      = "tls_key"
+	flagAPIToken    = "api_token"
+	flagParticipant = "participant"
+	envAPIToken     = "DC4BC_API_TOKEN"
+)
+
+// nodeClient is a client of the node HTTP API, it is initialized before any command is run
+var nodeClient *api.Client
+
+func init() {
+	rootCmd.PersistentFlags().String(flagTLSCA, "", "Path to the CA of the node certificate, enables HTTPS")
+	rootCmd.PersistentFlags().String(flagTLSCert, "", "Path to the client certificate for mTLS")
+	rootCmd.PersistentFlags().String(flagTLSKey, "", "Path to the private key of the client certificate")
+	rootCmd.PersistentFlags().String(flagAPIToken, "", "Bearer token of the node HTTP API (also read from "+envAPIToken+")")
+	rootCmd.PersistentFlags().String(flagParticipant, "", "Participant to manage if the node hosts several participants")
+	rootCmd.PersistentPreRunE = initAPIClient
+}
+
+// initAPIClient configures the node address, TLS and the bearer token of requests to the node
+func initAPIClient(cmd *cobra.Command, _ []string) error {
+	listenAddr, err := cmd.Flags().GetString(flagListenAddr)
+	if err != nil {
+		return fmt.Errorf("failed to read configuration: %v", err)
+	}
+	apiToken, err := cmd.Flags().GetString(flagAPIToken)
+	if err != nil {
+		return fmt.Errorf("failed to read configuration: %v", err)
+	}
+	if apiToken == "" {
+		apiToken = os.Getenv(envAPIToken)
+	}
+	caFile, err := cmd.Flags().GetString(flagTLSCA)
+	if err != nil {
+		return fmt.Errorf("failed to read configuration: %v", err)
+	}
+	certFile, err := cmd.Flags().GetString(flagTLSCert)
+	if err != nil {
+		return fmt.Errorf("failed to read configuration: %v", err)
+	}
+	keyFile, err := cmd.Flags().GetString(flagTLSKey)
+	if err != nil {
+		return fmt.Errorf("failed to read configuration: %v", err)
+	}
+	participant, err := cmd.Flags().GetString(flagParticipant)
+	if err != nil {
+		return fmt.Errorf("failed to read configuration: %v", err)
+	}
+	basePath := ""
+	if participant != "" {
+		basePath = api.ParticipantPath(participant)
+	}
+
+	if caFile == "" {
+		if certFile != "" {
+			return errors.New("client certificate requires the node CA to be set")
+		}
+		nodeClient = api.NewClient("http://" + listenAddr + basePath)
+		nodeClient.SetToken(apiToken)
+		return nil
+	}
+
+	caBz, err := ioutil.ReadFile(caFile)
+	if err != nil {
+		return fmt.Errorf("failed to read node CA: %w", err)
+	}
+	rootCAs := x509.NewCertPool()
+	if !rootCAs.AppendCertsFromPEM(caBz) {
+		return errors.New("failed to parse node CA")
+	}
+	tlsConfig := &tls.Config{RootCAs: rootCAs, MinVersion: tls.VersionTLS12}
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	nodeClient = api.NewClient("https://" + listenAddr + basePath)
+	nodeClient.SetToken(apiToken)
+	nodeClient.SetHTTPClient(&http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}})
+	return nil
+}
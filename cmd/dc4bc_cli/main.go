@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"crypto/md5"
 	"encoding/base64"
 	"encoding/hex"
@@ -10,7 +11,6 @@ import (
 	"github.com/lidofinance/dc4bc/fsm/state_machines"
 	"io/ioutil"
 	"log"
-	"net/http"
 	"sort"
 	"strconv"
 	"strings"
@@ -21,8 +21,10 @@ import (
 	"github.com/lidofinance/dc4bc/fsm/state_machines/signing_proposal_fsm"
 	"github.com/lidofinance/dc4bc/fsm/types/responses"
 
-	"github.com/lidofinance/dc4bc/client"
+	"github.com/lidofinance/dc4bc/client/api"
+	"github.com/lidofinance/dc4bc/client/types"
 	"github.com/lidofinance/dc4bc/fsm/types/requests"
+	"github.com/lidofinance/dc4bc/transport"
 	"github.com/spf13/cobra"
 )
 
@@ -31,13 +33,23 @@ const (
 	flagFramesDelay   = "frames_delay"
 	flagChunkSize     = "chunk_size"
 	flagQRCodesFolder = "qr_codes_folder"
+	flagBundlesFolder = "bundles_folder"
+	flagFrom          = "from"
+	flagJSON          = "json"
+	flagTo            = "to"
+	flagFile          = "file"
+	flagOutput        = "output"
+	flagPubKey        = "pubkey"
 )
 
+const watchReconnectDelay = 5 * time.Second
+
 func init() {
 	rootCmd.PersistentFlags().String(flagListenAddr, "localhost:8080", "Listen Address")
 	rootCmd.PersistentFlags().Int(flagFramesDelay, 10, "Delay times between frames in 100ths of a second")
 	rootCmd.PersistentFlags().Int(flagChunkSize, 256, "QR-code's chunk size")
 	rootCmd.PersistentFlags().String(flagQRCodesFolder, "/tmp", "Folder to save QR codes")
+	rootCmd.PersistentFlags().String(flagBundlesFolder, "transport", "Folder (e.g. on a removable drive) to exchange operation bundles with the airgapped machine")
 }
 
 var rootCmd = &cobra.Command{
@@ -48,8 +60,8 @@ var rootCmd = &cobra.Command{
 func main() {
 	rootCmd.AddCommand(
 		getOperationsCommand(),
-		getOperationQRPathCommand(),
-		readOperationFromCameraCommand(),
+		writeOperationCommand(),
+		readOperationResultCommand(),
 		startDKGCommand(),
 		proposeSignMessageCommand(),
 		getUsernameCommand(),
@@ -57,52 +69,33 @@ func main() {
 		getHashOfStartDKGCommand(),
 		getSignaturesCommand(),
 		getSignatureCommand(),
+		listKeysCommand(),
 		saveOffsetCommand(),
 		getOffsetCommand(),
 		getFSMStatusCommand(),
 		getFSMListCommand(),
 		getSignatureDataCommand(),
+		writeBundlesCommand(),
+		readBundlesCommand(),
+		watchCommand(),
+		auditCommand(),
+		approveOperationCommand(),
 	)
 	if err := rootCmd.Execute(); err != nil {
 		log.Fatalf("Failed to execute root command: %v", err)
 	}
 }
 
-func getOperationsRequest(host string) (*OperationsResponse, error) {
-	resp, err := http.Get(fmt.Sprintf("http://%s/getOperations", host))
-	if err != nil {
-		return nil, fmt.Errorf("failed to get operations: %w", err)
-	}
-	defer resp.Body.Close()
-	responseBody, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read body: %w", err)
-	}
-
-	var response OperationsResponse
-	if err = json.Unmarshal(responseBody, &response); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %v", err)
-	}
-	return &response, nil
-}
-
 func getOperationsCommand() *cobra.Command {
 	return &cobra.Command{
 		Use:   "get_operations",
 		Short: "returns all operations that should be processed on the airgapped machine",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			listenAddr, err := cmd.Flags().GetString(flagListenAddr)
-			if err != nil {
-				return fmt.Errorf("failed to read configuration: %v", err)
-			}
-			operations, err := getOperationsRequest(listenAddr)
+			operations, err := nodeClient.AllOperations(context.Background())
 			if err != nil {
 				return fmt.Errorf("failed to get operations: %w", err)
 			}
-			if operations.ErrorMessage != "" {
-				return fmt.Errorf("failed to get operations: %s", operations.ErrorMessage)
-			}
-			for _, operation := range operations.Result {
+			for _, operation := range operations {
 				fmt.Printf("DKG round ID: %s\n", operation.DKGIdentifier)
 				fmt.Printf("Operation ID: %s\n", operation.ID)
 				fmt.Printf("Description: %s\n", getShortOperationDescription(operation.Type))
@@ -122,6 +115,14 @@ func getOperationsCommand() *cobra.Command {
 					fmt.Printf("Hash of the data to sign - %s\n", hex.EncodeToString(msgHash[:]))
 					fmt.Printf("Signing ID: %s\n", payload.SigningId)
 				}
+				if operation.Policy != nil {
+					fmt.Printf("Signing policy: %s (rule %s): %s\n", operation.Policy.Action, operation.Policy.Rule, operation.Policy.Reason)
+					if operation.Policy.AwaitsApproval() {
+						fmt.Println("Awaits approval, run approve_operation with an approver token")
+					} else if operation.Policy.ApprovedBy != "" {
+						fmt.Printf("Approved by %s at %s\n", operation.Policy.ApprovedBy, operation.Policy.ApprovedAt.Format(time.RFC3339))
+					}
+				}
 				fmt.Println("-----------------------------------------------------")
 			}
 			return nil
@@ -129,71 +130,73 @@ func getOperationsCommand() *cobra.Command {
 	}
 }
 
-func getSignaturesRequest(host string, dkgID string) (*SignaturesResponse, error) {
-	resp, err := http.Get(fmt.Sprintf("http://%s/getSignatures?dkgID=%s", host, dkgID))
-	if err != nil {
-		return nil, fmt.Errorf("failed to get signatures: %w", err)
-	}
-	defer resp.Body.Close()
-	responseBody, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read body: %w", err)
-	}
-
-	var response SignaturesResponse
-	if err = json.Unmarshal(responseBody, &response); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %v", err)
-	}
-	return &response, nil
-}
-
 func getSignaturesCommand() *cobra.Command {
 	return &cobra.Command{
 		Use:   "get_signatures [dkgID]",
 		Args:  cobra.ExactArgs(1),
-		Short: "returns all signatures for the given DKG round that were reconstructed on the airgapped machine",
+		Short: "returns all signatures for the given DKG round that were reconstructed by participants and verified by the node",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			listenAddr, err := cmd.Flags().GetString(flagListenAddr)
-			if err != nil {
-				return fmt.Errorf("failed to read configuration: %v", err)
-			}
-			signatures, err := getSignaturesRequest(listenAddr, args[0])
+			signings, err := nodeClient.AllSignatures(context.Background(), args[0])
 			if err != nil {
 				return fmt.Errorf("failed to get signatures: %w", err)
 			}
-			if signatures.ErrorMessage != "" {
-				return fmt.Errorf("failed to get signatures: %s", signatures.ErrorMessage)
-			}
-			for sigID, signature := range signatures.Result {
-				fmt.Printf("Signing ID: %s\n", sigID)
-				for _, participantSig := range signature {
+			for _, signing := range signings {
+				fmt.Printf("Signing ID: %s\n", signing.SigningID)
+				// signatures are verified by the node before they are saved, so participants who reconstructed
+				// the same signature confirm each other
+				participantsBySignature := make(map[string][]string)
+				var reconstructedSignatures []string
+				for _, participantSig := range signing.Signatures {
+					if len(participantSig.Signature) == 0 {
+						continue
+					}
+					encodedSignature := base64.StdEncoding.EncodeToString(participantSig.Signature)
+					if _, ok := participantsBySignature[encodedSignature]; !ok {
+						reconstructedSignatures = append(reconstructedSignatures, encodedSignature)
+					}
+					participantsBySignature[encodedSignature] = append(participantsBySignature[encodedSignature], participantSig.Username)
 					fmt.Printf("\tDKG round ID: %s\n", participantSig.DKGRoundID)
 					fmt.Printf("\tParticipant: %s\n", participantSig.Username)
-					fmt.Printf("\tReconstructed signature for the data: %s\n", base64.StdEncoding.EncodeToString(participantSig.Signature))
+					fmt.Printf("\tReconstructed signature for the data: %s\n", encodedSignature)
 					fmt.Println()
 				}
+				for _, encodedSignature := range reconstructedSignatures {
+					participants := participantsBySignature[encodedSignature]
+					fmt.Printf("\tValid signature %s was reconstructed by %d participant(s): %s\n", encodedSignature,
+						len(participants), strings.Join(participants, ", "))
+				}
+				fmt.Println()
 			}
 			return nil
 		},
 	}
 }
 
-func getSignatureRequest(host string, dkgID, dataHash string) (*SignatureResponse, error) {
-	resp, err := http.Get(fmt.Sprintf("http://%s/getSignatureByID?dkgID=%s&id=%s", host, dkgID, dataHash))
-	if err != nil {
-		return nil, fmt.Errorf("failed to get signatures: %w", err)
-	}
-	defer resp.Body.Close()
-	responseBody, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read body: %w", err)
-	}
-
-	var response SignatureResponse
-	if err = json.Unmarshal(responseBody, &response); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %v", err)
+func listKeysCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list_keys",
+		Short: "returns public keys of finished DKG rounds",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			keys, err := nodeClient.AllKeys(context.Background())
+			if err != nil {
+				return fmt.Errorf("failed to get keys: %w", err)
+			}
+			for _, key := range keys {
+				fmt.Printf("DKG round ID: %s\n", key.DKGRoundID)
+				fmt.Printf("\tCreated at: %s\n", key.CreatedAt.Format(time.RFC3339))
+				fmt.Printf("\tMaster public key: %s\n", base64.StdEncoding.EncodeToString(key.MasterPubKey))
+				fmt.Printf("\tMaster public key (prysm): %s\n", key.PrysmPubKey)
+				fmt.Printf("\tThreshold: %d/%d\n", key.Threshold, len(key.Participants))
+				fmt.Println("\tParticipants:")
+				for _, participant := range key.Participants {
+					fmt.Printf("\t\t%s (index %d): %s\n", participant.Username, participant.Index,
+						base64.StdEncoding.EncodeToString(participant.PubShare))
+				}
+				fmt.Println("-----------------------------------------------------")
+			}
+			return nil
+		},
 	}
-	return &response, nil
 }
 
 func getSignatureCommand() *cobra.Command {
@@ -202,18 +205,11 @@ func getSignatureCommand() *cobra.Command {
 		Args:  cobra.ExactArgs(2),
 		Short: "returns a list of reconstructed signatures of the signed data broadcasted by users",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			listenAddr, err := cmd.Flags().GetString(flagListenAddr)
-			if err != nil {
-				return fmt.Errorf("failed to read configuration: %v", err)
-			}
-			signatures, err := getSignatureRequest(listenAddr, args[0], args[1])
+			signing, err := nodeClient.GetSignatures(context.Background(), args[0], args[1])
 			if err != nil {
 				return fmt.Errorf("failed to get signatures: %w", err)
 			}
-			if signatures.ErrorMessage != "" {
-				return fmt.Errorf("failed to get signatures: %s", signatures.ErrorMessage)
-			}
-			for _, participantSig := range signatures.Result {
+			for _, participantSig := range signing.Signatures {
 				fmt.Printf("\tParticipant: %s\n", participantSig.Username)
 				fmt.Printf("\tReconstructed signature for the data: %s\n", base64.StdEncoding.EncodeToString(participantSig.Signature))
 				fmt.Println()
@@ -229,67 +225,38 @@ func getSignatureDataCommand() *cobra.Command {
 		Args:  cobra.ExactArgs(2),
 		Short: "returns a data which was signed",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			listenAddr, err := cmd.Flags().GetString(flagListenAddr)
-			if err != nil {
-				return fmt.Errorf("failed to read configuration: %v", err)
-			}
-			signatures, err := getSignatureRequest(listenAddr, args[0], args[1])
+			signing, err := nodeClient.GetSignatures(context.Background(), args[0], args[1])
 			if err != nil {
 				return fmt.Errorf("failed to get signatures: %w", err)
 			}
-			if signatures.ErrorMessage != "" {
-				return fmt.Errorf("failed to get signatures: %s", signatures.ErrorMessage)
-			}
-			if len(signatures.Result) > 0 {
-				fmt.Println(string(signatures.Result[0].SrcPayload))
+			if len(signing.Signatures) > 0 {
+				fmt.Println(string(signing.Signatures[0].SrcPayload))
 			}
 			return nil
 		},
 	}
 }
 
-func getOperationRequest(host string, operationID string) (*OperationResponse, error) {
-	resp, err := http.Get(fmt.Sprintf("http://%s/getOperation?operationID=%s", host, operationID))
-	if err != nil {
-		return nil, fmt.Errorf("failed to get operation: %w", err)
-	}
-	defer resp.Body.Close()
-	responseBody, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read body: %w", err)
-	}
-
-	var response OperationResponse
-	if err = json.Unmarshal(responseBody, &response); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %v", err)
-	}
-	return &response, nil
-}
-
-func getOperationQRPathCommand() *cobra.Command {
+func writeOperationCommand() *cobra.Command {
 	return &cobra.Command{
 		Use:   "write_operation [operationID]",
 		Args:  cobra.ExactArgs(1),
-		Short: "returns path to a JSON file which contains the operation",
+		Short: "writes the operation as a bundle to the file operation-id.json to be processed on the airgapped machine",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			listenAddr, err := cmd.Flags().GetString(flagListenAddr)
-			if err != nil {
-				return fmt.Errorf("failed to read configuration: %v", err)
-			}
-
 			operationID := args[0]
-			operation, err := getOperationRequest(listenAddr, operationID)
+			operation, err := nodeClient.GetOperation(context.Background(), operationID)
 			if err != nil {
-				return fmt.Errorf("failed to get operations: %w", err)
+				return fmt.Errorf("failed to get operation: %w", err)
 			}
-			if operation.ErrorMessage != "" {
-				return fmt.Errorf("failed to get operations: %s", operation.ErrorMessage)
+			operationJSON, err := json.Marshal(operation)
+			if err != nil {
+				return fmt.Errorf("failed to marshal operation: %w", err)
 			}
 
-			outFileName := fmt.Sprintf("%s.json", args[0])
-			err = ioutil.WriteFile(outFileName, operation.Result, 0400)
-			if err != nil {
-				return fmt.Errorf("failed to write result to %s: %w", outFileName, err)
+			outFileName := fmt.Sprintf("%s.json", operationID)
+			tr := transport.NewFileTransport("", outFileName)
+			if err = tr.Send(transport.NewBundle(transport.OperationBundle, operation.ID, operationJSON)); err != nil {
+				return fmt.Errorf("failed to write bundle to %s: %w", outFileName, err)
 			}
 
 			fmt.Printf("wrote operation to %s\n", outFileName)
@@ -299,42 +266,16 @@ func getOperationQRPathCommand() *cobra.Command {
 	}
 }
 
-func rawGetRequest(url string) (*client.Response, error) {
-	resp, err := http.Get(url)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get operations for node %w", err)
-	}
-	defer resp.Body.Close()
-	responseBody, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read body %w", err)
-	}
-
-	var response client.Response
-	if err = json.Unmarshal(responseBody, &response); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %v", err)
-	}
-	return &response, nil
-}
-
 func getPubKeyCommand() *cobra.Command {
 	return &cobra.Command{
 		Use:   "get_pubkey",
 		Short: "returns client's pubkey",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			listenAddr, err := cmd.Flags().GetString(flagListenAddr)
-			if err != nil {
-				return fmt.Errorf("failed to read configuration: %v", err)
-			}
-
-			resp, err := rawGetRequest(fmt.Sprintf("http://%s//getPubKey", listenAddr))
+			node, err := nodeClient.GetNode(context.Background())
 			if err != nil {
 				return fmt.Errorf("failed to get client's pubkey: %w", err)
 			}
-			if resp.ErrorMessage != "" {
-				return fmt.Errorf("failed to get client's pubkey: %v", resp.ErrorMessage)
-			}
-			fmt.Println(resp.Result.(string))
+			fmt.Println(base64.StdEncoding.EncodeToString(node.PubKey))
 			return nil
 		},
 	}
@@ -346,28 +287,30 @@ func saveOffsetCommand() *cobra.Command {
 		Short: "saves a new offset for a storage",
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			listenAddr, err := cmd.Flags().GetString(flagListenAddr)
-			if err != nil {
-				return fmt.Errorf("failed to read configuration: %v", err)
-			}
-
 			offset, err := strconv.ParseUint(args[0], 10, 64)
 			if err != nil {
 				return fmt.Errorf("failed to parse uint: %w", err)
 			}
-			req := map[string]uint64{"offset": offset}
-			data, err := json.Marshal(req)
-			if err != nil {
-				return fmt.Errorf("failed to create request: %w", err)
-			}
-			resp, err := rawPostRequest(fmt.Sprintf("http://%s/saveOffset", listenAddr), "application/json", data)
-			if err != nil {
+			if err = nodeClient.SaveOffset(context.Background(), offset); err != nil {
 				return fmt.Errorf("failed to save offset: %w", err)
 			}
-			if resp.ErrorMessage != "" {
-				return fmt.Errorf("failed to save offset: %v", resp.ErrorMessage)
+			fmt.Println("ok")
+			return nil
+		},
+	}
+}
+
+func approveOperationCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "approve_operation [operationID]",
+		Args:  cobra.ExactArgs(1),
+		Short: "approves an operation which requires a second approver by the signing policy of the node, the approval is recorded under the name of the API token",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			operation, err := nodeClient.ApproveOperation(context.Background(), args[0])
+			if err != nil {
+				return fmt.Errorf("failed to approve operation: %w", err)
 			}
-			fmt.Println(resp.Result.(string))
+			fmt.Printf("operation %s is approved by %s\n", operation.ID, operation.Policy.ApprovedBy)
 			return nil
 		},
 	}
@@ -378,19 +321,11 @@ func getOffsetCommand() *cobra.Command {
 		Use:   "get_offset",
 		Short: "returns a current offset for the storage",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			listenAddr, err := cmd.Flags().GetString(flagListenAddr)
-			if err != nil {
-				return fmt.Errorf("failed to read configuration: %v", err)
-			}
-
-			resp, err := rawGetRequest(fmt.Sprintf("http://%s//getOffset", listenAddr))
+			offset, err := nodeClient.GetOffset(context.Background())
 			if err != nil {
 				return fmt.Errorf("failed to get offset: %w", err)
 			}
-			if resp.ErrorMessage != "" {
-				return fmt.Errorf("failed to get offset: %v", resp.ErrorMessage)
-			}
-			fmt.Println(uint64(resp.Result.(float64)))
+			fmt.Println(offset)
 			return nil
 		},
 	}
@@ -401,84 +336,124 @@ func getUsernameCommand() *cobra.Command {
 		Use:   "get_username",
 		Short: "returns client's username",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			listenAddr, err := cmd.Flags().GetString(flagListenAddr)
-			if err != nil {
-				return fmt.Errorf("failed to read configuration: %v", err)
-			}
-
-			resp, err := rawGetRequest(fmt.Sprintf("http://%s//getUsername", listenAddr))
+			node, err := nodeClient.GetNode(context.Background())
 			if err != nil {
 				return fmt.Errorf("failed to get client's username: %w", err)
 			}
-			if resp.ErrorMessage != "" {
-				return fmt.Errorf("failed to get client's username: %v", resp.ErrorMessage)
-			}
-			fmt.Println(resp.Result.(string))
+			fmt.Println(node.Username)
 			return nil
 		},
 	}
 }
 
-func rawPostRequest(url string, contentType string, data []byte) (*client.Response, error) {
-	resp, err := http.Post(url,
-		contentType, bytes.NewReader(data))
-	if err != nil {
-		return nil, fmt.Errorf("failed to send HTTP request: %w", err)
-	}
-	defer resp.Body.Close()
-	responseBody, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read body %w", err)
-	}
-
-	var response client.Response
-	if err = json.Unmarshal(responseBody, &response); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %v", err)
+func readOperationResultCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "read_op [operation-id]",
+		Args:  cobra.ExactArgs(1),
+		Short: "reads the file operation-id_res.json which should contain a result bundle of the operation",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opID := args[0]
+			return handleResultBundles(transport.NewFileTransport(opID+"_res.json", ""))
+		},
 	}
-	return &response, nil
 }
 
-func readOperationFromCameraCommand() *cobra.Command {
+func writeBundlesCommand() *cobra.Command {
 	return &cobra.Command{
-		Use:   "read_op [operation-id]",
-		Args:  cobra.ExactArgs(1),
-		Short: "reads the file operation-id_res.json which should contain a processed operation",
+		Use:   "write_bundles",
+		Short: "writes all pending operations as bundles to the bundles folder to be processed on the airgapped machine",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			listenAddr, err := cmd.Flags().GetString(flagListenAddr)
+			bundlesFolder, err := cmd.Flags().GetString(flagBundlesFolder)
 			if err != nil {
 				return fmt.Errorf("failed to read configuration: %v", err)
 			}
 
-			opID := args[0]
-			d, err := ioutil.ReadFile(opID + "_res.json")
+			tr, err := transport.NewHotNodeDirTransport(bundlesFolder)
 			if err != nil {
-				return fmt.Errorf("failed to read response: %w", err)
+				return fmt.Errorf("failed to init transport: %w", err)
 			}
 
-			resp, err := rawPostRequest(fmt.Sprintf("http://%s/handleProcessedOperationJSON", listenAddr),
-				"application/json", d)
+			operations, err := nodeClient.AllOperations(context.Background())
 			if err != nil {
-				return fmt.Errorf("failed to handle processed operation: %w", err)
+				return fmt.Errorf("failed to get operations: %w", err)
 			}
-			if resp.ErrorMessage != "" {
-				return fmt.Errorf("failed to handle processed operation: %v", resp.ErrorMessage)
+			for _, operation := range operations {
+				// the node rejects results of operations which are not approved yet
+				if operation.Policy.AwaitsApproval() {
+					fmt.Printf("skipped operation %s: awaits approval\n", operation.ID)
+					continue
+				}
+				operationJSON, err := json.Marshal(operation)
+				if err != nil {
+					return fmt.Errorf("failed to marshal operation %s: %w", operation.ID, err)
+				}
+				if err = tr.Send(transport.NewBundle(transport.OperationBundle, operation.ID, operationJSON)); err != nil {
+					return fmt.Errorf("failed to write bundle for operation %s: %w", operation.ID, err)
+				}
+				fmt.Printf("wrote operation %s\n", operation.ID)
 			}
 			return nil
 		},
 	}
 }
 
-func startDKGCommand() *cobra.Command {
+func readBundlesCommand() *cobra.Command {
 	return &cobra.Command{
-		Use:   "start_dkg [proposing_file]",
-		Args:  cobra.ExactArgs(1),
-		Short: "sends a propose message to start a DKG process",
+		Use:   "read_bundles",
+		Short: "reads all result bundles from the bundles folder and passes processed operations to the node",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			listenAddr, err := cmd.Flags().GetString(flagListenAddr)
+			bundlesFolder, err := cmd.Flags().GetString(flagBundlesFolder)
 			if err != nil {
 				return fmt.Errorf("failed to read configuration: %v", err)
 			}
 
+			tr, err := transport.NewHotNodeDirTransport(bundlesFolder)
+			if err != nil {
+				return fmt.Errorf("failed to init transport: %w", err)
+			}
+			return handleResultBundles(tr)
+		},
+	}
+}
+
+// handleResultBundles passes processed operations from the result bundles of the transport to the node
+func handleResultBundles(tr transport.Transport) error {
+	bundles, err := tr.Receive()
+	if err != nil {
+		return fmt.Errorf("failed to read bundles: %w", err)
+	}
+	for _, bundle := range bundles {
+		var operation types.Operation
+		if bundle.Kind != transport.ResultBundle {
+			err = fmt.Errorf("unexpected bundle kind %s", bundle.Kind)
+		} else if err = json.Unmarshal(bundle.Payload, &operation); err != nil {
+			err = fmt.Errorf("failed to unmarshal processed operation: %w", err)
+		} else if err = nodeClient.HandleOperationResult(context.Background(), &operation); err != nil &&
+			!api.IsInvalidRequest(err) && !api.IsNotFound(err) {
+			return fmt.Errorf("failed to handle processed operation %s: %w", bundle.ID, err)
+		}
+		// a result which the node refused is rejected, so it does not block the results behind it
+		if err != nil {
+			fmt.Printf("rejected bundle %s: %v\n", bundle.ID, err)
+			if err = tr.Reject(bundle, err); err != nil {
+				return fmt.Errorf("failed to reject bundle %s: %w", bundle.ID, err)
+			}
+			continue
+		}
+		if err = tr.Ack(bundle); err != nil {
+			return fmt.Errorf("failed to ack bundle %s: %w", bundle.ID, err)
+		}
+		fmt.Printf("processed operation %s\n", bundle.ID)
+	}
+	return nil
+}
+
+func startDKGCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "start_dkg [proposing_file]",
+		Args:  cobra.ExactArgs(1),
+		Short: "sends a propose message to start a DKG process",
+		RunE: func(cmd *cobra.Command, args []string) error {
 			dkgProposeFileData, err := ioutil.ReadFile(args[0])
 			if err != nil {
 				return fmt.Errorf("failed to read file: %w", err)
@@ -498,14 +473,11 @@ func startDKGCommand() *cobra.Command {
 			if err != nil {
 				return fmt.Errorf("failed to marshal SignatureProposalParticipantsListRequest: %v", err)
 			}
-			resp, err := rawPostRequest(fmt.Sprintf("http://%s/startDKG", listenAddr),
-				"application/json", messageDataBz)
+			dkgRoundID, err := nodeClient.StartRound(context.Background(), messageDataBz)
 			if err != nil {
 				return fmt.Errorf("failed to make HTTP request to start DKG: %w", err)
 			}
-			if resp.ErrorMessage != "" {
-				return fmt.Errorf("failed to make HTTP request to start DKG: %v", resp.ErrorMessage)
-			}
+			fmt.Printf("DKG round ID: %s\n", dkgRoundID)
 			return nil
 		},
 	}
@@ -558,13 +530,7 @@ func proposeSignMessageCommand() *cobra.Command {
 		Args:  cobra.ExactArgs(2),
 		Short: "sends a propose message to sign the data in the file",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			listenAddr, err := cmd.Flags().GetString(flagListenAddr)
-			if err != nil {
-				return fmt.Errorf("failed to read configuration: %v", err)
-			}
-
-			dkgID, err := hex.DecodeString(args[0])
-			if err != nil {
+			if _, err := hex.DecodeString(args[0]); err != nil {
 				return fmt.Errorf("failed to decode dkgID: %w", err)
 			}
 
@@ -573,62 +539,26 @@ func proposeSignMessageCommand() *cobra.Command {
 				return fmt.Errorf("failed to read the file")
 			}
 
-			messageDataBz, err := json.Marshal(map[string][]byte{"data": data,
-				"dkgID": dkgID})
-			if err != nil {
-				return fmt.Errorf("failed to marshal SigningProposalStartRequest: %v", err)
-			}
-
-			resp, err := rawPostRequest(fmt.Sprintf("http://%s/proposeSignMessage", listenAddr),
-				"application/json", messageDataBz)
+			signingID, err := nodeClient.ProposeSigning(context.Background(), args[0], data)
 			if err != nil {
 				return fmt.Errorf("failed to make HTTP request to propose message to sign: %w", err)
 			}
-			if resp.ErrorMessage != "" {
-				return fmt.Errorf("failed to make HTTP request to propose message to sign: %v", resp.ErrorMessage)
-			}
+			fmt.Printf("Signing ID: %s\n", signingID)
 			return nil
 		},
 	}
 }
 
-func getFSMDumpRequest(host string, dkgID string) (*FSMDumpResponse, error) {
-	resp, err := http.Get(fmt.Sprintf("http://%s/getFSMDump?dkgID=%s", host, dkgID))
-	if err != nil {
-		return nil, fmt.Errorf("failed to get FSM dump: %w", err)
-	}
-	defer resp.Body.Close()
-	responseBody, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read body: %w", err)
-	}
-
-	var response FSMDumpResponse
-	if err = json.Unmarshal(responseBody, &response); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %v", err)
-	}
-	return &response, nil
-}
-
 func getFSMStatusCommand() *cobra.Command {
 	return &cobra.Command{
 		Use:   "show_fsm_status [dkg_id]",
 		Args:  cobra.ExactArgs(1),
 		Short: "shows the current status of FSM",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			listenAddr, err := cmd.Flags().GetString(flagListenAddr)
-			if err != nil {
-				return fmt.Errorf("failed to read configuration: %v", err)
-			}
-
-			fsmDumpResponse, err := getFSMDumpRequest(listenAddr, args[0])
+			dump, err := nodeClient.GetRound(context.Background(), args[0])
 			if err != nil {
 				return fmt.Errorf("failed to get FSM dump: %w", err)
 			}
-			if fsmDumpResponse.ErrorMessage != "" {
-				return fmt.Errorf("failed to get FSM dump: %v", fsmDumpResponse.ErrorMessage)
-			}
-			dump := fsmDumpResponse.Result
 
 			fmt.Printf("FSM current status is %s\n", dump.State)
 
@@ -657,7 +587,7 @@ func getFSMStatusCommand() *cobra.Command {
 				if strings.Contains(p.GetStatus().String(), "Await") {
 					waiting = append(waiting, p.GetUsername())
 				}
-				if strings.Contains(p.GetStatus().String(), "Error") {
+				if strings.Contains(p.GetStatus().String(), "Error") || strings.Contains(p.GetStatus().String(), "Invalid") {
 					failed = append(failed, p.GetUsername())
 				}
 				if strings.Contains(p.GetStatus().String(), "Confirmed") {
@@ -672,7 +602,17 @@ func getFSMStatusCommand() *cobra.Command {
 				fmt.Printf("Received a data from: %s\n", strings.Join(confirmed, ", "))
 			}
 			if len(failed) > 0 {
-				fmt.Printf("Participants who got some error during a process: %s\n", strings.Join(waiting, ", "))
+				fmt.Printf("Participants who got some error during a process: %s\n", strings.Join(failed, ", "))
+			}
+			for _, p := range quorum {
+				protocolError := p.GetError()
+				if protocolError == nil {
+					continue
+				}
+				fmt.Printf("Error from %s: [%s] %s\n", p.GetUsername(), protocolError.Code, protocolError.Message)
+				if protocolError.Participant != "" {
+					fmt.Printf("  caused by participant: %s\n", protocolError.Participant)
+				}
 			}
 
 			return nil
@@ -685,23 +625,215 @@ func getFSMListCommand() *cobra.Command {
 		Use:   "get_fsm_list",
 		Short: "returns a list of all FSMs served by the client",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			listenAddr, err := cmd.Flags().GetString(flagListenAddr)
+			rounds, err := nodeClient.AllRounds(context.Background())
+			if err != nil {
+				return fmt.Errorf("failed to make HTTP request to get FSM list: %w", err)
+			}
+			for _, round := range rounds {
+				fmt.Printf("DKG ID: %s - FSM state: %s\n", round.ID, round.State)
+			}
+			return nil
+		},
+	}
+}
+
+func formatEvent(event api.Event) string {
+	description := string(event.Type)
+	switch event.Type {
+	case api.EventMessageProcessed:
+		description = fmt.Sprintf("processed message %s from %s at offset %d", event.MessageEvent, event.Sender,
+			event.MessageOffset)
+	case api.EventStateChanged:
+		description = fmt.Sprintf("FSM state changed from %s to %s", event.PreviousState, event.State)
+	case api.EventTimeout:
+		description = fmt.Sprintf("canceled by timeout in state %s", event.State)
+	case api.EventRoundCompleted:
+		description = "the master public key is collected"
+	case api.EventRoundFailed:
+		description = fmt.Sprintf("failed in state %s", event.State)
+	case api.EventOperationCreated:
+		description = fmt.Sprintf("new operation %s: %s", event.OperationID,
+			getShortOperationDescription(types.OperationType(event.OperationType)))
+	case api.EventOperationHandled:
+		description = fmt.Sprintf("operation %s was handled", event.OperationID)
+	case api.EventSignatureReconstructed:
+		description = fmt.Sprintf("signature %s was reconstructed by %s", event.SigningID, event.Sender)
+	case api.EventSignatureReconstructionFailed:
+		description = fmt.Sprintf("%s failed to reconstruct signature %s", event.Sender, event.SigningID)
+	}
+	return fmt.Sprintf("[%d] %s DKG round %s: %s", event.Offset, event.CreatedAt.Format(time.RFC3339),
+		event.DKGRoundID, description)
+}
+
+func watchCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "watch",
+		Short: "prints events of the node as they happen",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			fromOffset, err := cmd.Flags().GetUint64(flagFrom)
 			if err != nil {
 				return fmt.Errorf("failed to read configuration: %v", err)
 			}
+			printJSON, err := cmd.Flags().GetBool(flagJSON)
+			if err != nil {
+				return fmt.Errorf("failed to read configuration: %v", err)
+			}
+
+			handler := func(event api.Event) error {
+				fromOffset = event.Offset
+				if !printJSON {
+					fmt.Println(formatEvent(event))
+					return nil
+				}
+				eventJSON, err := json.Marshal(event)
+				if err != nil {
+					return fmt.Errorf("failed to marshal event: %w", err)
+				}
+				fmt.Println(string(eventJSON))
+				return nil
+			}
+			// the stream is resumed after the last received event when the connection is lost
+			for {
+				err := nodeClient.Watch(context.Background(), fromOffset, handler)
+				if _, ok := err.(*api.Error); ok {
+					return fmt.Errorf("failed to watch events: %w", err)
+				}
+				log.Printf("event stream is interrupted (%v), reconnecting in %s", err, watchReconnectDelay)
+				time.Sleep(watchReconnectDelay)
+			}
+		},
+	}
+	cmd.Flags().Uint64(flagFrom, 0, "Offset of the last seen event to resume the stream after, new events only if not set")
+	cmd.Flags().Bool(flagJSON, false, "Print events as JSON")
+	return cmd
+}
 
-			resp, err := rawGetRequest(fmt.Sprintf("http://%s/getFSMList", listenAddr))
+func auditCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "audit",
+		Short: "checks and exports the audit journal of the node",
+	}
+	cmd.AddCommand(auditVerifyCommand(), auditExportCommand())
+	return cmd
+}
+
+func auditVerifyCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "verify",
+		Short: "checks hashes and signatures of the audit journal of the node or of an exported journal",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			file, err := cmd.Flags().GetString(flagFile)
 			if err != nil {
-				return fmt.Errorf("failed to make HTTP request to get FSM list: %w", err)
+				return fmt.Errorf("failed to read configuration: %v", err)
 			}
-			if resp.ErrorMessage != "" {
-				return fmt.Errorf("failed to make HTTP request to get FSM list: %v", resp.ErrorMessage)
+			pubKeyBase64, err := cmd.Flags().GetString(flagPubKey)
+			if err != nil {
+				return fmt.Errorf("failed to read configuration: %v", err)
 			}
-			fsms := resp.Result.(map[string]interface{})
-			for dkgID, state := range fsms {
-				fmt.Printf("DKG ID: %s - FSM state: %s\n", dkgID, state.(string))
+
+			// the key reported by the node itself proves nothing about a journal the node could have rewritten
+			if pubKeyBase64 == "" {
+				return fmt.Errorf("--%s is required, take the communication public key of the node from "+
+					"a trusted source, e.g. the key registered for the DKG rounds", flagPubKey)
+			}
+			pubKey, err := base64.StdEncoding.DecodeString(pubKeyBase64)
+			if err != nil {
+				return fmt.Errorf("failed to decode public key: %w", err)
+			}
+
+			var records []api.AuditRecord
+			if file != "" {
+				if records, err = readAuditRecords(file); err != nil {
+					return err
+				}
+			} else if records, err = nodeClient.AuditRecords(context.Background(), 0, 0); err != nil {
+				return fmt.Errorf("failed to get audit journal: %w", err)
 			}
+			if len(records) == 0 {
+				fmt.Println("The audit journal is empty")
+				return nil
+			}
+
+			if err = api.VerifyAuditRecords(records, pubKey); err != nil {
+				return fmt.Errorf("the audit journal is corrupted: %w", err)
+			}
+			last := records[len(records)-1]
+			fmt.Printf("Records %d-%d of the audit journal are verified\n", records[0].Index, last.Index)
+			fmt.Printf("Hash of the last record: %s, keep it to detect removed records on the next check\n",
+				base64.StdEncoding.EncodeToString(last.Hash))
 			return nil
 		},
 	}
+	cmd.Flags().String(flagFile, "", "Path to an exported journal to verify instead of the journal of the node")
+	cmd.Flags().String(flagPubKey, "", "Communication public key of the node (base64)")
+	return cmd
+}
+
+func auditExportCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "writes records of the audit journal of the node as JSON lines",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			from, err := cmd.Flags().GetUint64(flagFrom)
+			if err != nil {
+				return fmt.Errorf("failed to read configuration: %v", err)
+			}
+			to, err := cmd.Flags().GetUint64(flagTo)
+			if err != nil {
+				return fmt.Errorf("failed to read configuration: %v", err)
+			}
+			output, err := cmd.Flags().GetString(flagOutput)
+			if err != nil {
+				return fmt.Errorf("failed to read configuration: %v", err)
+			}
+
+			records, err := nodeClient.AuditRecords(context.Background(), from, to)
+			if err != nil {
+				return fmt.Errorf("failed to get audit journal: %w", err)
+			}
+			var buf bytes.Buffer
+			for _, record := range records {
+				recordJSON, err := json.Marshal(record)
+				if err != nil {
+					return fmt.Errorf("failed to marshal audit record: %w", err)
+				}
+				buf.Write(recordJSON)
+				buf.WriteByte('\n')
+			}
+
+			if output == "" {
+				fmt.Print(buf.String())
+				return nil
+			}
+			if err = ioutil.WriteFile(output, buf.Bytes(), 0600); err != nil {
+				return fmt.Errorf("failed to write audit journal: %w", err)
+			}
+			fmt.Printf("%d records of the audit journal are written to %s\n", len(records), output)
+			return nil
+		},
+	}
+	cmd.Flags().Uint64(flagFrom, 0, "Index of the first record to export")
+	cmd.Flags().Uint64(flagTo, 0, "Index of the record to stop the export before, the journal is exported till the end if not set")
+	cmd.Flags().String(flagOutput, "", "Path to the file to write records to, records are printed if not set")
+	return cmd
+}
+
+// readAuditRecords reads a journal exported by the audit export command
+func readAuditRecords(filename string) ([]api.AuditRecord, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit journal: %w", err)
+	}
+	var records []api.AuditRecord
+	for i, line := range bytes.Split(data, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var record api.AuditRecord
+		if err = json.Unmarshal(line, &record); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal audit record at line %d: %w", i+1, err)
+		}
+		records = append(records, record)
+	}
+	return records, nil
 }
@@ -2,7 +2,12 @@ package main
 
 import (
 	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"os"
 	"os/signal"
@@ -10,6 +15,7 @@ import (
 	"syscall"
 
 	"github.com/lidofinance/dc4bc/client"
+	"github.com/lidofinance/dc4bc/client/api"
 	"github.com/lidofinance/dc4bc/storage"
 
 	"github.com/spf13/cobra"
@@ -29,6 +35,23 @@ const (
 	flagFramesDelay              = "frames_delay"
 	flagChunkSize                = "chunk_size"
 	flagConfig                   = "config"
+	flagAirgappedPubKey          = "airgapped_pubkey"
+	flagAirgappedReconstruction  = "airgapped_reconstruction"
+	flagTLSCert                  = "tls_cert"
+	flagTLSKey                   = "tls_key"
+	flagTLSClientCA              = "tls_client_ca"
+	flagAPITokensFile            = "api_tokens_file"
+	flagWebhookURLs              = "webhook_urls"
+	flagWebhookSecret            = "webhook_secret"
+	flagWebhookEvents            = "webhook_events"
+	flagWebhookMaxRetries        = "webhook_max_retries"
+	flagLogLevel                 = "log_level"
+	flagLogFormat                = "log_format"
+	flagLogFile                  = "log_file"
+	flagAuditJournal             = "audit_journal"
+	flagSigningPolicy            = "signing_policy"
+	flagParticipants             = "participants"
+	flagMigrateStateTo           = "migrate_state_to"
 )
 
 var (
@@ -50,6 +73,23 @@ func init() {
 	rootCmd.PersistentFlags().Int(flagFramesDelay, 10, "Delay times between frames in 100ths of a second")
 	rootCmd.PersistentFlags().Int(flagChunkSize, 256, "QR-code's chunk size")
 	rootCmd.PersistentFlags().StringVar(&cfgFile, flagConfig, "", "path to your config file")
+	rootCmd.PersistentFlags().String(flagAirgappedPubKey, "", "Identity public key of the airgapped machine (base64)")
+	rootCmd.PersistentFlags().Bool(flagAirgappedReconstruction, false, "Reconstruct threshold signatures on the airgapped machine instead of the client")
+	rootCmd.PersistentFlags().String(flagTLSCert, "", "Path to the TLS certificate of the HTTP API, enables HTTPS")
+	rootCmd.PersistentFlags().String(flagTLSKey, "", "Path to the TLS private key of the HTTP API")
+	rootCmd.PersistentFlags().String(flagTLSClientCA, "", "Path to the CA of client certificates, enables mTLS")
+	rootCmd.PersistentFlags().String(flagAPITokensFile, "", "Path to the file with bearer tokens of the HTTP API, one \"<scope> <token>\" per line, scopes: read, operator, approver")
+	rootCmd.PersistentFlags().StringSlice(flagWebhookURLs, nil, "URLs to POST events to, comma separated")
+	rootCmd.PersistentFlags().String(flagWebhookSecret, "", "Secret of HMAC-SHA256 signatures of webhooks")
+	rootCmd.PersistentFlags().StringSlice(flagWebhookEvents, nil, "Events sent to webhooks, comma separated (default operation_created,round_completed,round_failed,signature_reconstructed)")
+	rootCmd.PersistentFlags().Int(flagWebhookMaxRetries, 5, "Maximal number of retries of a failed webhook")
+	rootCmd.PersistentFlags().String(flagLogLevel, "info", "Log level: debug, info, warn or error")
+	rootCmd.PersistentFlags().String(flagLogFormat, client.LogFormatJSON, "Log format: json or text")
+	rootCmd.PersistentFlags().String(flagLogFile, "", "Path to the log file, the log is written to stdout if not set")
+	rootCmd.PersistentFlags().String(flagAuditJournal, "./dc4bc_audit_journal", "Path to the audit journal of the node, empty path disables the journal")
+	rootCmd.PersistentFlags().String(flagSigningPolicy, "", "Path to the JSON file of the policy applied to signing requests of other participants")
+	rootCmd.PersistentFlags().String(flagParticipants, "", "Path to the JSON file of participants hosted by the node, it replaces the username, airgapped_pubkey, api_tokens_file, audit_journal and signing_policy flags")
+	rootCmd.PersistentFlags().String(flagMigrateStateTo, "", "Username of the hosted participant the state of a single-participant node is moved to on start")
 
 	exitIfError(viper.BindPFlag(flagUserName, rootCmd.PersistentFlags().Lookup(flagUserName)))
 	exitIfError(viper.BindPFlag(flagListenAddr, rootCmd.PersistentFlags().Lookup(flagListenAddr)))
@@ -62,6 +102,23 @@ func init() {
 	exitIfError(viper.BindPFlag(flagStoreDBDSN, rootCmd.PersistentFlags().Lookup(flagStoreDBDSN)))
 	exitIfError(viper.BindPFlag(flagFramesDelay, rootCmd.PersistentFlags().Lookup(flagFramesDelay)))
 	exitIfError(viper.BindPFlag(flagChunkSize, rootCmd.PersistentFlags().Lookup(flagChunkSize)))
+	exitIfError(viper.BindPFlag(flagAirgappedPubKey, rootCmd.PersistentFlags().Lookup(flagAirgappedPubKey)))
+	exitIfError(viper.BindPFlag(flagAirgappedReconstruction, rootCmd.PersistentFlags().Lookup(flagAirgappedReconstruction)))
+	exitIfError(viper.BindPFlag(flagTLSCert, rootCmd.PersistentFlags().Lookup(flagTLSCert)))
+	exitIfError(viper.BindPFlag(flagTLSKey, rootCmd.PersistentFlags().Lookup(flagTLSKey)))
+	exitIfError(viper.BindPFlag(flagTLSClientCA, rootCmd.PersistentFlags().Lookup(flagTLSClientCA)))
+	exitIfError(viper.BindPFlag(flagAPITokensFile, rootCmd.PersistentFlags().Lookup(flagAPITokensFile)))
+	exitIfError(viper.BindPFlag(flagWebhookURLs, rootCmd.PersistentFlags().Lookup(flagWebhookURLs)))
+	exitIfError(viper.BindPFlag(flagWebhookSecret, rootCmd.PersistentFlags().Lookup(flagWebhookSecret)))
+	exitIfError(viper.BindPFlag(flagWebhookEvents, rootCmd.PersistentFlags().Lookup(flagWebhookEvents)))
+	exitIfError(viper.BindPFlag(flagWebhookMaxRetries, rootCmd.PersistentFlags().Lookup(flagWebhookMaxRetries)))
+	exitIfError(viper.BindPFlag(flagLogLevel, rootCmd.PersistentFlags().Lookup(flagLogLevel)))
+	exitIfError(viper.BindPFlag(flagLogFormat, rootCmd.PersistentFlags().Lookup(flagLogFormat)))
+	exitIfError(viper.BindPFlag(flagLogFile, rootCmd.PersistentFlags().Lookup(flagLogFile)))
+	exitIfError(viper.BindPFlag(flagAuditJournal, rootCmd.PersistentFlags().Lookup(flagAuditJournal)))
+	exitIfError(viper.BindPFlag(flagSigningPolicy, rootCmd.PersistentFlags().Lookup(flagSigningPolicy)))
+	exitIfError(viper.BindPFlag(flagParticipants, rootCmd.PersistentFlags().Lookup(flagParticipants)))
+	exitIfError(viper.BindPFlag(flagMigrateStateTo, rootCmd.PersistentFlags().Lookup(flagMigrateStateTo)))
 	exitIfError(viper.BindPFlag(flagUserName, rootCmd.PersistentFlags().Lookup(flagUserName)))
 }
 
@@ -113,6 +170,111 @@ func parseKafkaAuthCredentials(creds string) (*storage.KafkaAuthCredentials, err
 	}, nil
 }
 
+// participantConfig configures a participant hosted by the node. The node hosts a single participant
+// configured with flags unless the participants file is set.
+type participantConfig struct {
+	Username        string `json:"username"`
+	AirgappedPubKey string `json:"airgapped_pubkey"`
+	APITokensFile   string `json:"api_tokens_file"`
+	AuditJournal    string `json:"audit_journal"`
+	SigningPolicy   string `json:"signing_policy"`
+}
+
+func loadParticipants() ([]participantConfig, error) {
+	participantsFile := viper.GetString(flagParticipants)
+	if participantsFile == "" {
+		return []participantConfig{{
+			Username:        viper.GetString(flagUserName),
+			AirgappedPubKey: viper.GetString(flagAirgappedPubKey),
+			APITokensFile:   viper.GetString(flagAPITokensFile),
+			AuditJournal:    viper.GetString(flagAuditJournal),
+			SigningPolicy:   viper.GetString(flagSigningPolicy),
+		}}, nil
+	}
+
+	data, err := ioutil.ReadFile(participantsFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read participants file: %w", err)
+	}
+	var participants []participantConfig
+	if err = json.Unmarshal(data, &participants); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal participants file: %w", err)
+	}
+	if len(participants) == 0 {
+		return nil, errors.New("participants file is empty")
+	}
+	return participants, nil
+}
+
+// newParticipantClient creates the client of a participant hosted by the node
+func newParticipantClient(
+	ctx context.Context,
+	cfg participantConfig,
+	state client.State,
+	stg storage.Storage,
+	keyStore client.KeyStore,
+	loggerConfig client.LoggerConfig,
+	httpAuth client.HTTPAuthConfig,
+) (client.Client, error) {
+	var (
+		airgappedPubKey ed25519.PublicKey
+		err             error
+	)
+	if cfg.AirgappedPubKey != "" {
+		if airgappedPubKey, err = base64.StdEncoding.DecodeString(cfg.AirgappedPubKey); err != nil {
+			return nil, fmt.Errorf("failed to decode airgapped public key: %w", err)
+		}
+	} else {
+		log.Printf("Airgapped public key of %s is not set, processed operations will be rejected", cfg.Username)
+	}
+
+	cli, err := client.NewClient(ctx, cfg.Username, state, stg, keyStore, airgappedPubKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to init client: %w", err)
+	}
+	cli.SetAirgappedReconstruction(viper.GetBool(flagAirgappedReconstruction))
+
+	if err = cli.SetLogger(loggerConfig); err != nil {
+		return nil, fmt.Errorf("failed to set logger: %w", err)
+	}
+
+	if cfg.APITokensFile != "" {
+		if httpAuth.Tokens, err = client.LoadAPITokens(cfg.APITokensFile); err != nil {
+			return nil, fmt.Errorf("failed to load API tokens: %w", err)
+		}
+	} else {
+		log.Printf("API tokens of %s are not set, the HTTP API is available without authentication", cfg.Username)
+	}
+	cli.SetHTTPAuth(httpAuth)
+
+	if cfg.AuditJournal != "" {
+		if err = cli.SetAuditJournal(cfg.AuditJournal); err != nil {
+			return nil, fmt.Errorf("failed to open audit journal: %w", err)
+		}
+	}
+
+	if cfg.SigningPolicy != "" {
+		signingPolicy, err := client.LoadSigningPolicy(cfg.SigningPolicy)
+		if err != nil {
+			return nil, err
+		}
+		cli.SetSigningPolicy(signingPolicy)
+	}
+
+	webhooks := client.WebhookConfig{
+		URLs:       viper.GetStringSlice(flagWebhookURLs),
+		Secret:     viper.GetString(flagWebhookSecret),
+		MaxRetries: viper.GetInt(flagWebhookMaxRetries),
+	}
+	for _, eventType := range viper.GetStringSlice(flagWebhookEvents) {
+		webhooks.Events = append(webhooks.Events, api.EventType(eventType))
+	}
+	if err = cli.SetWebhooks(webhooks); err != nil {
+		return nil, fmt.Errorf("failed to set webhooks: %w", err)
+	}
+	return cli, nil
+}
+
 func startClientCommand() *cobra.Command {
 	return &cobra.Command{
 		Use:   "start",
@@ -121,10 +283,43 @@ func startClientCommand() *cobra.Command {
 			ctx := context.Background()
 			ctx, cancel := context.WithCancel(ctx)
 
-			stateDBDSN := viper.GetString(flagStateDBDSN)
-			state, err := client.NewLevelDBState(stateDBDSN)
+			participants, err := loadParticipants()
 			if err != nil {
-				return fmt.Errorf("failed to init state client: %w", err)
+				return err
+			}
+			usernames := make([]string, 0, len(participants))
+			for _, participant := range participants {
+				usernames = append(usernames, participant.Username)
+			}
+
+			stateDBDSN := viper.GetString(flagStateDBDSN)
+			var states []client.State
+			if viper.GetString(flagParticipants) == "" {
+				state, err := client.NewLevelDBState(stateDBDSN)
+				if err != nil {
+					return fmt.Errorf("failed to init state client: %w", err)
+				}
+				states = append(states, state)
+			} else {
+				if migrateTo := viper.GetString(flagMigrateStateTo); migrateTo != "" {
+					hosted := false
+					for _, username := range usernames {
+						hosted = hosted || username == migrateTo
+					}
+					if !hosted {
+						return fmt.Errorf("participant %s is not hosted by the node", migrateTo)
+					}
+					if err = client.MigrateLevelDBState(stateDBDSN, migrateTo); err != nil {
+						return fmt.Errorf("failed to migrate state to %s: %w", migrateTo, err)
+					}
+				}
+				if states, err = client.NewLevelDBStates(stateDBDSN, usernames); err != nil {
+					if errors.Is(err, client.ErrLegacyState) {
+						return fmt.Errorf("failed to init state client: %w, run the node with --%s <username> "+
+							"once to move the state to the participant", err, flagMigrateStateTo)
+					}
+					return fmt.Errorf("failed to init state client: %w", err)
+				}
 			}
 
 			kafkaTrustStorePath := viper.GetString(flagKafkaTrustStorePath)
@@ -152,16 +347,42 @@ func startClientCommand() *cobra.Command {
 				return fmt.Errorf("failed to init storage client: %w", err)
 			}
 
-			username := viper.GetString(flagUserName)
 			keyStoreDBDSN := viper.GetString(flagStoreDBDSN)
-			keyStore, err := client.NewLevelDBKeyStore(username, keyStoreDBDSN)
+			keyStore, err := client.NewLevelDBKeyStore(usernames[0], keyStoreDBDSN)
 			if err != nil {
 				return fmt.Errorf("failed to init key store: %w", err)
 			}
 
-			cli, err := client.NewClient(ctx, username, state, stg, keyStore)
+			logLevel, err := client.ParseLogLevel(viper.GetString(flagLogLevel))
 			if err != nil {
-				return fmt.Errorf("failed to init client: %w", err)
+				return err
+			}
+			loggerConfig := client.LoggerConfig{
+				Level:  logLevel,
+				Format: viper.GetString(flagLogFormat),
+			}
+			if logFile := viper.GetString(flagLogFile); logFile != "" {
+				f, err := os.OpenFile(logFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+				if err != nil {
+					return fmt.Errorf("failed to open log file: %w", err)
+				}
+				defer f.Close()
+				loggerConfig.Output = f
+			}
+
+			httpAuth := client.HTTPAuthConfig{
+				TLSCertFile:  viper.GetString(flagTLSCert),
+				TLSKeyFile:   viper.GetString(flagTLSKey),
+				ClientCAFile: viper.GetString(flagTLSClientCA),
+			}
+
+			clients := make([]client.Client, 0, len(participants))
+			for i, participant := range participants {
+				cli, err := newParticipantClient(ctx, participant, states[i], stg, keyStore, loggerConfig, httpAuth)
+				if err != nil {
+					return fmt.Errorf("failed to init participant %s: %w", participant.Username, err)
+				}
+				clients = append(clients, cli)
 			}
 
 			sigs := make(chan os.Signal, 1)
@@ -178,22 +399,59 @@ func startClientCommand() *cobra.Command {
 
 			listenAddress := viper.GetString(flagListenAddr)
 
+			if viper.GetString(flagParticipants) != "" {
+				return startMultiClient(ctx, stg, clients, loggerConfig, httpAuth, listenAddress)
+			}
+
+			cli := clients[0]
 			go func() {
 				if err := cli.StartHTTPServer(listenAddress); err != nil {
 					log.Fatalf("HTTP server error: %v", err)
 				}
 			}()
-			cli.GetLogger().Log("Client started to poll messages from append-only log")
-			cli.GetLogger().Log("Waiting for messages from append-only log...")
+			cli.GetLogger().Info("Client started to poll messages from append-only log")
+			cli.GetLogger().Info("Waiting for messages from append-only log...")
 			if err = cli.Poll(); err != nil {
 				return fmt.Errorf("error while handling operations: %w", err)
 			}
-			cli.GetLogger().Log("polling is stopped")
+			cli.GetLogger().Info("polling is stopped")
 			return nil
 		},
 	}
 }
 
+// startMultiClient serves the participants hosted by the node with one HTTP server and one subscription
+// to the append-only log
+func startMultiClient(
+	ctx context.Context,
+	stg storage.Storage,
+	clients []client.Client,
+	loggerConfig client.LoggerConfig,
+	httpAuth client.HTTPAuthConfig,
+	listenAddress string,
+) error {
+	node, err := client.NewMultiClient(ctx, stg, clients...)
+	if err != nil {
+		return fmt.Errorf("failed to init node: %w", err)
+	}
+	if err = node.SetLogger(loggerConfig); err != nil {
+		return fmt.Errorf("failed to set logger: %w", err)
+	}
+	node.SetHTTPAuth(httpAuth)
+
+	go func() {
+		if err := node.StartHTTPServer(listenAddress); err != nil {
+			log.Fatalf("HTTP server error: %v", err)
+		}
+	}()
+	node.Logger.Info("Node started to poll messages from append-only log for %d participants", len(clients))
+	if err = node.Poll(); err != nil {
+		return fmt.Errorf("error while handling operations: %w", err)
+	}
+	node.Logger.Info("polling is stopped")
+	return nil
+}
+
 var rootCmd = &cobra.Command{
 	Use:   "dc4bc_d",
 	Short: "dc4bc client daemon implementation",
@@ -1,6 +1,7 @@
 package dkg
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"math"
@@ -8,9 +9,11 @@ import (
 	"sync"
 
 	"github.com/corestario/kyber"
+	"github.com/corestario/kyber/pairing"
 	"github.com/corestario/kyber/share"
 	dkg "github.com/corestario/kyber/share/dkg/pedersen"
 	vss "github.com/corestario/kyber/share/vss/pedersen"
+	"github.com/corestario/kyber/sign/tbls"
 	"github.com/google/go-cmp/cmp"
 	"lukechampine.com/frand"
 )
@@ -32,6 +35,12 @@ type DKG struct {
 
 	N         int
 	Threshold int
+
+	// seed and the flags below are kept to be able to restore the instance from a saved state
+	seed               []byte
+	dealsGenerated     bool
+	dealsProcessed     bool
+	responsesProcessed bool
 }
 
 func Init(suite vss.Suite, pubKey kyber.Point, secKey kyber.Scalar) *DKG {
@@ -85,6 +94,10 @@ func (d *DKG) GetPubKeyByParticipant(participant string) (kyber.Point, error) {
 	return pk, nil
 }
 
+func (d *DKG) GetIndexByParticipant(participant string) (int, error) {
+	return d.pubKeys.GetIndexByParticipant(participant)
+}
+
 func (d *DKG) GetParticipantByIndex(index int) string {
 	return d.pubKeys.GetParticipantByIndex(index)
 }
@@ -136,6 +149,7 @@ func (d *DKG) InitDKGInstance(seed []byte) (err error) {
 	if err != nil {
 		return err
 	}
+	d.seed = seed
 	return nil
 }
 
@@ -155,6 +169,7 @@ func (d *DKG) GetDeals() (map[int]*dkg.Deal, error) {
 	if err != nil {
 		return nil, err
 	}
+	d.dealsGenerated = true
 	return deals, nil
 }
 
@@ -190,6 +205,7 @@ func (d *DKG) ProcessDeals() ([]*dkg.Response, error) {
 		}
 		responses = append(responses, resp)
 	}
+	d.dealsProcessed = true
 	return responses, nil
 }
 
@@ -221,6 +237,7 @@ func (d *DKG) ProcessResponses() error {
 		return fmt.Errorf("praticipant %v is not certified", d.ParticipantID)
 	}
 
+	d.responsesProcessed = true
 	return nil
 }
 
@@ -288,3 +305,61 @@ func (d *DKG) GetBLSKeyring() (*BLSKeyring, error) {
 		Share:   distKeyShare.PriShare(),
 	}, nil
 }
+
+// MarshalCommits encodes commits as a JSON list of marshaled points
+func MarshalCommits(commits []kyber.Point) ([]byte, error) {
+	commitsBz := make([][]byte, 0, len(commits))
+	for _, commit := range commits {
+		commitBz, err := commit.MarshalBinary()
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal commit: %w", err)
+		}
+		commitsBz = append(commitsBz, commitBz)
+	}
+	return json.Marshal(commitsBz)
+}
+
+// UnmarshalCommits decodes commits broadcasted by a participant, they are encoded as a JSON list of marshaled points
+func UnmarshalCommits(suite vss.Suite, data []byte) ([]kyber.Point, error) {
+	var commitsBz [][]byte
+	if err := json.Unmarshal(data, &commitsBz); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal commits: %w", err)
+	}
+	commits := make([]kyber.Point, 0, len(commitsBz))
+	for _, commitBz := range commitsBz {
+		commit := suite.Point()
+		if err := commit.UnmarshalBinary(commitBz); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal commit: %w", err)
+		}
+		commits = append(commits, commit)
+	}
+	return commits, nil
+}
+
+// PubPolyFromCommits returns the public polynomial of a DKG round, which is the sum of commits of all participants
+func PubPolyFromCommits(suite vss.Suite, commits [][]kyber.Point) (*share.PubPoly, error) {
+	if len(commits) == 0 {
+		return nil, errors.New("no commits")
+	}
+
+	var err error
+	pubPoly := share.NewPubPoly(suite, nil, commits[0])
+	for _, participantCommits := range commits[1:] {
+		if pubPoly, err = pubPoly.Add(share.NewPubPoly(suite, nil, participantCommits)); err != nil {
+			return nil, fmt.Errorf("failed to add commits: %w", err)
+		}
+	}
+	return pubPoly, nil
+}
+
+// VerifyPartialSign checks that the partial signature is made by the share with the given index
+func VerifyPartialSign(suite pairing.Suite, pubPoly *share.PubPoly, index int, msg, partialSign []byte) error {
+	signIndex, err := tbls.SigShare(partialSign).Index()
+	if err != nil {
+		return fmt.Errorf("failed to get partial signature index: %w", err)
+	}
+	if signIndex != index {
+		return fmt.Errorf("partial signature index %d does not match the share index %d", signIndex, index)
+	}
+	return tbls.Verify(suite, pubPoly, msg, partialSign)
+}
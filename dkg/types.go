@@ -4,9 +4,11 @@ import (
 	"bytes"
 	"encoding/gob"
 	"encoding/json"
+	"errors"
 	"fmt"
 
 	"github.com/corestario/kyber/pairing"
+	dkg "github.com/corestario/kyber/share/dkg/pedersen"
 	vss "github.com/corestario/kyber/share/vss/pedersen"
 
 	"github.com/corestario/kyber"
@@ -52,6 +54,15 @@ func (s PKStore) GetPKByParticipant(p string) (kyber.Point, error) {
 	return nil, fmt.Errorf("participant %s does not exist", p)
 }
 
+func (s PKStore) GetIndexByParticipant(p string) (int, error) {
+	for idx, val := range s {
+		if val.Participant == p {
+			return idx, nil
+		}
+	}
+	return -1, fmt.Errorf("participant %s does not exist", p)
+}
+
 func (s PKStore) GetPKByIndex(index int) kyber.Point {
 	if index < 0 || index > len(s) {
 		return nil
@@ -109,6 +120,22 @@ type BLSKeyring struct {
 	Share   *share.PriShare
 }
 
+// PubShare returns the public key share of the keyring, which is the public polynomial evaluated at the share index
+func (b *BLSKeyring) PubShare() *share.PubShare {
+	return b.PubPoly.Eval(b.Share.I)
+}
+
+// VerifyShare checks that the private share matches the public polynomial at the share index
+func (b *BLSKeyring) VerifyShare() error {
+	// base is nil for the standard base point, Mul handles it the same way
+	base, _ := b.PubPoly.Info()
+	pubShare := b.PubShare().V
+	if !pubShare.Equal(pubShare.Clone().Mul(b.Share.V, base)) {
+		return errors.New("share does not match the public polynomial")
+	}
+	return nil
+}
+
 // blsKeyringJSON used to encode/decodee the BLSKeyring structure into JSON cause of the private fields inside BLSKeyring
 type blsKeyringJSON struct {
 	Commitments [][]byte `json:"commitments"`
@@ -175,3 +202,179 @@ func LoadBLSKeyringFromBytes(suite vss.Suite, data []byte) (*BLSKeyring, error)
 		Share:   priShare,
 	}, nil
 }
+
+// dkgStateJSON is a serializable snapshot of a DKG round. The underlying DistKeyGenerator can not be
+// serialized, but it is deterministic for the same seed, so it is recreated from the seed of the machine
+// and the stored messages. The seed is not stored, Seed is only read from states saved by earlier versions.
+type dkgStateJSON struct {
+	N                  int                        `json:"n"`
+	Threshold          int                        `json:"threshold"`
+	ParticipantID      int                        `json:"participant_id"`
+	PubKeys            []pk2ParticipantJSON       `json:"pub_keys"`
+	Initialized        bool                       `json:"initialized"`
+	Seed               []byte                     `json:"seed,omitempty"`
+	Commits            map[string][][]byte        `json:"commits"`
+	DealsGenerated     bool                       `json:"deals_generated"`
+	Deals              map[string]*dkg.Deal       `json:"deals"`
+	DealsProcessed     bool                       `json:"deals_processed"`
+	Responses          map[string][]*dkg.Response `json:"responses"`
+	ResponsesProcessed bool                       `json:"responses_processed"`
+}
+
+type pk2ParticipantJSON struct {
+	ParticipantID int    `json:"participant_id"`
+	Participant   string `json:"participant"`
+	PK            []byte `json:"pk"`
+}
+
+// MarshalState encodes the current state of the DKG round
+func (d *DKG) MarshalState() ([]byte, error) {
+	d.Lock()
+	defer d.Unlock()
+
+	state := dkgStateJSON{
+		N:                  d.N,
+		Threshold:          d.Threshold,
+		ParticipantID:      d.ParticipantID,
+		Initialized:        d.seed != nil,
+		Commits:            make(map[string][][]byte, len(d.commits)),
+		DealsGenerated:     d.dealsGenerated,
+		Deals:              d.deals,
+		DealsProcessed:     d.dealsProcessed,
+		Responses:          make(map[string][]*dkg.Response),
+		ResponsesProcessed: d.responsesProcessed,
+	}
+
+	var err error
+	if state.PubKeys, err = d.marshalPubKeys(); err != nil {
+		return nil, err
+	}
+
+	for participant, commits := range d.commits {
+		commitsBz := make([][]byte, 0, len(commits))
+		for _, commit := range commits {
+			commitBz, err := commit.MarshalBinary()
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal commit: %w", err)
+			}
+			commitsBz = append(commitsBz, commitBz)
+		}
+		state.Commits[participant] = commitsBz
+	}
+
+	if d.responses != nil {
+		for participant, responses := range d.responses.addrToData {
+			for _, response := range responses {
+				state.Responses[participant] = append(state.Responses[participant], response.(*dkg.Response))
+			}
+		}
+	}
+
+	return json.Marshal(state)
+}
+
+// MarshalParticipants encodes the participants of the DKG round without the secrets of the round. An instance
+// restored from it can not continue the round, but it is enough to sign with the keyring of the finished round.
+func (d *DKG) MarshalParticipants() ([]byte, error) {
+	d.Lock()
+	defer d.Unlock()
+
+	pubKeys, err := d.marshalPubKeys()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(dkgStateJSON{
+		N:             d.N,
+		Threshold:     d.Threshold,
+		ParticipantID: d.ParticipantID,
+		PubKeys:       pubKeys,
+	})
+}
+
+func (d *DKG) marshalPubKeys() ([]pk2ParticipantJSON, error) {
+	pubKeys := make([]pk2ParticipantJSON, 0, len(d.pubKeys))
+	for _, pk := range d.pubKeys {
+		pkBz, err := pk.PK.MarshalBinary()
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal pub key: %w", err)
+		}
+		pubKeys = append(pubKeys, pk2ParticipantJSON{
+			ParticipantID: pk.ParticipantID,
+			Participant:   pk.Participant,
+			PK:            pkBz,
+		})
+	}
+	return pubKeys, nil
+}
+
+// RestoreFromState creates a DKG instance from the state encoded by MarshalState,
+// the seed must be the one the instance was initialized with
+func RestoreFromState(suite vss.Suite, pubKey kyber.Point, secKey kyber.Scalar, seed, data []byte) (*DKG, error) {
+	var state dkgStateJSON
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal dkg state: %w", err)
+	}
+
+	d := Init(suite, pubKey, secKey)
+	d.N = state.N
+	d.Threshold = state.Threshold
+	d.ParticipantID = state.ParticipantID
+
+	for _, pk := range state.PubKeys {
+		point := suite.Point()
+		if err := point.UnmarshalBinary(pk.PK); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal pub key: %w", err)
+		}
+		d.StorePubKey(pk.Participant, pk.ParticipantID, point)
+	}
+
+	if state.Seed != nil {
+		seed = state.Seed
+	} else if !state.Initialized {
+		return d, nil
+	}
+	if seed == nil {
+		return nil, errors.New("seed is required to restore an initialized dkg instance")
+	}
+	if err := d.InitDKGInstance(seed); err != nil {
+		return nil, fmt.Errorf("failed to init dkg instance: %w", err)
+	}
+
+	for participant, commitsBz := range state.Commits {
+		commits := make([]kyber.Point, 0, len(commitsBz))
+		for _, commitBz := range commitsBz {
+			commit := suite.Point()
+			if err := commit.UnmarshalBinary(commitBz); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal commit: %w", err)
+			}
+			commits = append(commits, commit)
+		}
+		d.StoreCommits(participant, commits)
+	}
+
+	if state.DealsGenerated {
+		// the generator processes our own deal while generating deals for other participants
+		if _, err := d.GetDeals(); err != nil {
+			return nil, fmt.Errorf("failed to get deals: %w", err)
+		}
+	}
+	for participant, deal := range state.Deals {
+		d.StoreDeal(participant, deal)
+	}
+	if state.DealsProcessed {
+		if _, err := d.ProcessDeals(); err != nil {
+			return nil, fmt.Errorf("failed to process deals: %w", err)
+		}
+	}
+
+	for participant, responses := range state.Responses {
+		d.StoreResponses(participant, responses)
+	}
+	if state.ResponsesProcessed {
+		if err := d.ProcessResponses(); err != nil {
+			return nil, fmt.Errorf("failed to process responses: %w", err)
+		}
+	}
+
+	return d, nil
+}
@@ -3,6 +3,8 @@ package internal
 import (
 	"crypto/ed25519"
 	"time"
+
+	"github.com/lidofinance/dc4bc/fsm/types/requests"
 )
 
 type ParticipantStatus interface {
@@ -59,6 +61,10 @@ func (sigP SignatureProposalParticipant) GetUsername() string {
 	return sigP.Username
 }
 
+func (sigP SignatureProposalParticipant) GetError() *requests.ProtocolError {
+	return nil
+}
+
 func (c *SignatureConfirmation) IsExpired() bool {
 	return c.ExpiresAt.Before(c.UpdatedAt)
 }
@@ -94,7 +100,7 @@ type DKGProposalParticipant struct {
 	DkgResponse  []byte
 	DkgMasterKey []byte
 	Status       DKGParticipantStatus
-	Error        error
+	Error        *requests.ProtocolError
 	UpdatedAt    time.Time
 }
 
@@ -106,6 +112,10 @@ func (dkgP DKGProposalParticipant) GetUsername() string {
 	return dkgP.Username
 }
 
+func (dkgP DKGProposalParticipant) GetError() *requests.ProtocolError {
+	return dkgP.Error
+}
+
 type DKGProposalQuorum map[int]*DKGProposalParticipant
 
 type DKGConfirmation struct {
@@ -161,9 +171,11 @@ type SigningConfirmation struct {
 	RecoveredKey     []byte
 	SrcPayload       []byte
 	EncryptedPayload []byte
-	CreatedAt        time.Time
-	UpdatedAt        time.Time
-	ExpiresAt        time.Time
+	// PubPolyCommits are commits of the public polynomial of the DKG round, they are built for the first partial sign
+	PubPolyCommits []byte
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	ExpiresAt      time.Time
 }
 
 func (c *SigningConfirmation) IsExpired() bool {
@@ -182,6 +194,7 @@ const (
 	SigningPartialSignsConfirmed
 	SigningError
 	SigningProcess
+	SigningPartialSignInvalid
 )
 
 func (s SigningParticipantStatus) String() string {
@@ -199,6 +212,8 @@ func (s SigningParticipantStatus) String() string {
 		str = "SigningError"
 	case SigningProcess:
 		str = "SigningProcess"
+	case SigningPartialSignInvalid:
+		str = "SigningPartialSignInvalid"
 	}
 	return str
 }
@@ -207,7 +222,7 @@ type SigningProposalParticipant struct {
 	Username    string
 	Status      SigningParticipantStatus
 	PartialSign []byte
-	Error       error
+	Error       *requests.ProtocolError
 	UpdatedAt   time.Time
 }
 
@@ -218,3 +233,7 @@ func (signingP SigningProposalParticipant) GetStatus() ParticipantStatus {
 func (signingP SigningProposalParticipant) GetUsername() string {
 	return signingP.Username
 }
+
+func (signingP SigningProposalParticipant) GetError() *requests.ProtocolError {
+	return signingP.Error
+}
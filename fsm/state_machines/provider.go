@@ -1,6 +1,7 @@
 package state_machines
 
 import (
+	"bytes"
 	"crypto/ed25519"
 	"encoding/json"
 	"errors"
@@ -13,6 +14,7 @@ import (
 	"github.com/lidofinance/dc4bc/fsm/fsm_pool"
 	"github.com/lidofinance/dc4bc/fsm/state_machines/internal"
 	"github.com/lidofinance/dc4bc/fsm/state_machines/signature_proposal_fsm"
+	"github.com/lidofinance/dc4bc/fsm/types/requests"
 )
 
 // Is machine state scope dump will be locked?
@@ -30,6 +32,7 @@ type FSMInstance struct {
 type Participant interface {
 	GetStatus() internal.ParticipantStatus
 	GetUsername() string
+	GetError() *requests.ProtocolError
 }
 
 // Create new fsm with unique id
@@ -107,6 +110,31 @@ func (i *FSMInstance) SigningQuorumGetParticipant(id int) (*internal.SigningProp
 	return i.dump.Payload.SigningQuorumGet(id), nil
 }
 
+// GetMasterPubKey returns the master public key of a finished DKG round, all participants must have confirmed the same key
+func (i *FSMInstance) GetMasterPubKey() ([]byte, error) {
+	if i.dump == nil {
+		return nil, errors.New("dump not initialized")
+	}
+	if i.dump.Payload.DKGProposalPayload == nil {
+		return nil, errors.New("DKG round is not started")
+	}
+
+	var masterPubKey []byte
+	for _, participant := range i.dump.Payload.DKGProposalPayload.Quorum {
+		if len(participant.DkgMasterKey) == 0 {
+			return nil, errors.New("DKG round is not finished")
+		}
+		if masterPubKey != nil && !bytes.Equal(masterPubKey, participant.DkgMasterKey) {
+			return nil, errors.New("master public keys of participants do not match")
+		}
+		masterPubKey = participant.DkgMasterKey
+	}
+	if masterPubKey == nil {
+		return nil, errors.New("DKG round is not finished")
+	}
+	return masterPubKey, nil
+}
+
 func (i *FSMInstance) GetIDByUsername(username string) (int, error) {
 	if i.dump == nil {
 		return -1, errors.New("dump not initialized")
@@ -196,5 +224,31 @@ func (d *FSMDump) Unmarshal(data []byte) error {
 		return errors.New("dump is not initialized")
 	}
 
-	return json.Unmarshal(data, d)
+	if err := json.Unmarshal(data, d); err != nil {
+		return err
+	}
+	d.dropEmptyErrors()
+	return nil
+}
+
+// dropEmptyErrors resets participant errors decoded from empty JSON objects. Old dumps stored
+// participant errors as plain errors, which were marshaled to "{}" and carry no information
+func (d *FSMDump) dropEmptyErrors() {
+	if d.Payload == nil {
+		return
+	}
+	if d.Payload.DKGProposalPayload != nil {
+		for _, participant := range d.Payload.DKGProposalPayload.Quorum {
+			if participant != nil && participant.Error.IsEmpty() {
+				participant.Error = nil
+			}
+		}
+	}
+	if d.Payload.SigningProposalPayload != nil {
+		for _, participant := range d.Payload.SigningProposalPayload.Quorum {
+			if participant != nil && participant.Error.IsEmpty() {
+				participant.Error = nil
+			}
+		}
+	}
 }
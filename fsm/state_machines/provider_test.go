@@ -4,11 +4,16 @@ import (
 	"crypto/ed25519"
 	"crypto/rand"
 	"encoding/base64"
-	"errors"
+	"encoding/json"
 	"reflect"
+	"sort"
 	"testing"
 	"time"
 
+	"github.com/corestario/kyber/pairing"
+	"github.com/corestario/kyber/pairing/bls12381"
+	"github.com/corestario/kyber/share"
+	"github.com/corestario/kyber/sign/tbls"
 	"github.com/stretchr/testify/require"
 
 	sif "github.com/lidofinance/dc4bc/fsm/state_machines/signing_proposal_fsm"
@@ -26,14 +31,14 @@ const (
 )
 
 type testParticipantsPayload struct {
-	Username      string
-	HotPrivKey    ed25519.PrivateKey
-	HotPubKey     ed25519.PublicKey
-	DkgPubKey     []byte
-	DkgCommit     []byte
-	DkgDeal       []byte
-	DkgResponse   []byte
-	DkgPartialKey []byte
+	Username    string
+	HotPrivKey  ed25519.PrivateKey
+	HotPubKey   ed25519.PublicKey
+	DkgPubKey   []byte
+	DkgCommit   []byte
+	DkgDeal     []byte
+	DkgResponse []byte
+	DkgPriPoly  *share.PriPoly
 }
 
 var (
@@ -54,22 +59,40 @@ var (
 	testSigningId        string
 	testSigningInitiator int
 	testSigningPayload   = []byte("message to sign")
+	testSigningThreshold = 2
+
+	testSuite = bls12381.NewBLS12381Suite(nil)
 
 	testFSMDump = map[fsm.State][]byte{}
 )
 
 func init() {
 	for i := 0; i < 3; i++ {
+		// commits must be real to verify partial signs
+		priPoly := share.NewPriPoly(testSuite, testSigningThreshold, nil, testSuite.RandomStream())
+		_, commits := priPoly.Commit(nil).Info()
+		commitsBz := make([][]byte, 0, len(commits))
+		for _, commit := range commits {
+			commitBz, err := commit.MarshalBinary()
+			if err != nil {
+				panic(err)
+			}
+			commitsBz = append(commitsBz, commitBz)
+		}
+		dkgCommit, err := json.Marshal(commitsBz)
+		if err != nil {
+			panic(err)
+		}
 
 		participant := &testParticipantsPayload{
-			Username:      base64.StdEncoding.EncodeToString(genDataMock(usernameMockLen)),
-			HotPrivKey:    genDataMock(keysMockLen),
-			HotPubKey:     genDataMock(keysMockLen),
-			DkgPubKey:     genDataMock(keysMockLen),
-			DkgCommit:     genDataMock(keysMockLen),
-			DkgDeal:       genDataMock(keysMockLen),
-			DkgResponse:   genDataMock(keysMockLen),
-			DkgPartialKey: genDataMock(keysMockLen),
+			Username:    base64.StdEncoding.EncodeToString(genDataMock(usernameMockLen)),
+			HotPrivKey:  genDataMock(keysMockLen),
+			HotPubKey:   genDataMock(keysMockLen),
+			DkgPubKey:   genDataMock(keysMockLen),
+			DkgCommit:   dkgCommit,
+			DkgDeal:     genDataMock(keysMockLen),
+			DkgResponse: genDataMock(keysMockLen),
+			DkgPriPoly:  priPoly,
 		}
 		testUsernameMapParticipants[participant.Username] = participant
 	}
@@ -86,6 +109,40 @@ func TestCreate_Positive(t *testing.T) {
 	}
 }
 
+func TestFromDump_EmptyErrors(t *testing.T) {
+	dump := []byte(`{"TransactionId":"` + dkgId + `","State":"` + string(dpf.StateDkgCommitsAwaitConfirmations) + `",
+		"Payload":{"DkgId":"` + dkgId + `",
+		"DKGProposalPayload":{"Quorum":{"0":{"Username":"alice","Error":{}},"1":{"Username":"bob","Error":{"Code":"internal_error"}}}},
+		"SigningProposalPayload":{"Quorum":{"0":{"Username":"alice","Error":{}}}}}}`)
+
+	testFSMInstance, err := FromDump(dump)
+	require.NoError(t, err)
+	payload := testFSMInstance.FSMDump().Payload
+	require.Nil(t, payload.DKGProposalPayload.Quorum[0].Error)
+	require.Equal(t, requests.ErrorCodeInternal, payload.DKGProposalPayload.Quorum[1].Error.Code)
+	require.Nil(t, payload.SigningProposalPayload.Quorum[0].Error)
+}
+
+// genPartialSign makes a partial sign of the payload with the share of the participant, the share is a sum of
+// polynomials of all participants evaluated at the index of the participant
+func genPartialSign(t *testing.T, participantId int, payload []byte) []byte {
+	participantIds := make([]int, 0, len(testIdMapParticipants))
+	for id := range testIdMapParticipants {
+		participantIds = append(participantIds, id)
+	}
+	sort.Ints(participantIds)
+
+	index := sort.SearchInts(participantIds, participantId)
+	priShare := &share.PriShare{I: index, V: testSuite.Scalar().Zero()}
+	for _, participant := range testIdMapParticipants {
+		priShare.V.Add(priShare.V, participant.DkgPriPoly.Eval(index).V)
+	}
+
+	partialSign, err := tbls.Sign(testSuite.(pairing.Suite), priShare, payload)
+	require.NoError(t, err)
+	return partialSign
+}
+
 func genDataMock(len int) []byte {
 	data := make([]byte, len)
 	rand.Read(data)
@@ -171,7 +228,7 @@ func Test_SignatureProposal_EventInitProposal_Positive(t *testing.T) {
 		})
 	}
 	testParticipantsListRequest.Participants = request
-	testParticipantsListRequest.SigningThreshold = len(request)
+	testParticipantsListRequest.SigningThreshold = testSigningThreshold
 
 	fsmResponse, testFSMDump[spf.StateAwaitParticipantsConfirmations], err = testFSMInstance.Do(spf.EventInitProposal, testParticipantsListRequest)
 
@@ -439,7 +496,7 @@ func Test_DkgProposal_EventDKGCommitConfirmationReceived_Canceled_Error(t *testi
 
 	fsmResponse, testFSMDumpLocal, err := testFSMInstance.Do(dpf.EventDKGCommitConfirmationError, requests.DKGProposalConfirmationErrorRequest{
 		ParticipantId: 0,
-		Error:         errors.New("test error"),
+		Error:         requests.NewProtocolError(requests.ErrorCodeInternal, "", "test error"),
 		CreatedAt:     time.Now(),
 	})
 
@@ -451,6 +508,15 @@ func Test_DkgProposal_EventDKGCommitConfirmationReceived_Canceled_Error(t *testi
 
 	compareState(t, dpf.StateDkgCommitsAwaitCanceledByError, fsmResponse.State)
 
+	// the error must survive the dump round trip
+	dump := &FSMDump{}
+	if err = dump.Unmarshal(testFSMDumpLocal); err != nil {
+		t.Fatalf("failed to unmarshal dump: %v", err)
+	}
+	participantErr := dump.Payload.DKGProposalPayload.Quorum[0].Error
+	if participantErr == nil || participantErr.Code != requests.ErrorCodeInternal || participantErr.Message != "test error" {
+		t.Fatalf("expected error to be stored in the dump, got %+v", participantErr)
+	}
 }
 
 func Test_DkgProposal_EventDKGCommitConfirmationReceived_Canceled_Timeout(t *testing.T) {
@@ -564,7 +630,7 @@ func Test_DkgProposal_EventDKGDealConfirmationReceived_Canceled_Error(t *testing
 
 	fsmResponse, testFSMDumpLocal, err := testFSMInstance.Do(dpf.EventDKGDealConfirmationError, requests.DKGProposalConfirmationErrorRequest{
 		ParticipantId: 0,
-		Error:         errors.New("test error"),
+		Error:         requests.NewProtocolError(requests.ErrorCodeInternal, "", "test error"),
 		CreatedAt:     time.Now(),
 	})
 
@@ -683,7 +749,7 @@ func Test_DkgProposal_EventDKGResponseConfirmationError_Canceled_Error(t *testin
 
 	fsmResponse, testFSMDumpLocal, err := testFSMInstance.Do(dpf.EventDKGResponseConfirmationError, requests.DKGProposalConfirmationErrorRequest{
 		ParticipantId: 0,
-		Error:         errors.New("test error"),
+		Error:         requests.NewProtocolError(requests.ErrorCodeInternal, "", "test error"),
 		CreatedAt:     time.Now(),
 	})
 
@@ -764,6 +830,21 @@ func Test_DkgProposal_EventDKGMasterKeyConfirmationReceived_Positive(t *testing.
 	compareDumpNotZero(t, testFSMDump[dpf.StateDkgMasterKeyCollected])
 }
 
+func Test_DkgProposal_GetMasterPubKey(t *testing.T) {
+	testFSMInstance, err := FromDump(testFSMDump[dpf.StateDkgMasterKeyAwaitConfirmations])
+	require.NoError(t, err)
+	_, err = testFSMInstance.GetMasterPubKey()
+	require.Error(t, err)
+
+	testFSMInstance, err = FromDump(testFSMDump[dpf.StateDkgMasterKeyCollected])
+	require.NoError(t, err)
+	masterPubKey, err := testFSMInstance.GetMasterPubKey()
+	require.NoError(t, err)
+	for _, participant := range testFSMInstance.FSMDump().Payload.DKGProposalPayload.Quorum {
+		require.Equal(t, participant.DkgMasterKey, masterPubKey)
+	}
+}
+
 func Test_DkgProposal_EventDKGMasterKeyConfirmationError_Canceled_Error(t *testing.T) {
 	testFSMInstance, err := FromDump(testFSMDump[dpf.StateDkgMasterKeyAwaitConfirmations])
 
@@ -776,7 +857,7 @@ func Test_DkgProposal_EventDKGMasterKeyConfirmationError_Canceled_Error(t *testi
 
 	fsmResponse, testFSMDumpLocal, err := testFSMInstance.Do(dpf.EventDKGMasterKeyConfirmationError, requests.DKGProposalConfirmationErrorRequest{
 		ParticipantId: 0,
-		Error:         errors.New("test error"),
+		Error:         requests.NewProtocolError(requests.ErrorCodeInternal, "", "test error"),
 		CreatedAt:     time.Now(),
 	})
 
@@ -1062,7 +1143,7 @@ func Test_SigningProposal_EventSigningPartialKeyReceived_Positive(t *testing.T)
 
 	testFSMDumpLocal = testFSMDump[sif.StateSigningAwaitPartialSigns]
 
-	for participantId, participant := range testIdMapParticipants {
+	for participantId := range testIdMapParticipants {
 		participantCounter--
 
 		testFSMInstance, err := FromDump(testFSMDumpLocal)
@@ -1077,7 +1158,7 @@ func Test_SigningProposal_EventSigningPartialKeyReceived_Positive(t *testing.T)
 		fsmResponse, testFSMDumpLocal, err = testFSMInstance.Do(sif.EventSigningPartialSignReceived, requests.SigningProposalPartialSignRequest{
 			SigningId:     testSigningId,
 			ParticipantId: participantId,
-			PartialSign:   participant.DkgPartialKey,
+			PartialSign:   genPartialSign(t, participantId, testSigningPayload),
 			CreatedAt:     time.Now(),
 		})
 
@@ -1109,11 +1190,92 @@ func Test_SigningProposal_EventSigningPartialKeyReceived_Positive(t *testing.T)
 		t.Fatalf("expected matched {SrcPayload}")
 	}
 
+	if len(response.Participants) != participantsCount {
+		t.Fatalf("expected {%d} partial signs, got {%d}", participantsCount, len(response.Participants))
+	}
+
 	testFSMDump[sif.StateSigningPartialSignsCollected] = testFSMDumpLocal
 
 	compareDumpNotZero(t, testFSMDump[sif.StateSigningPartialSignsCollected])
 }
 
+func Test_SigningProposal_EventSigningPartialKeyReceived_InvalidExcluded(t *testing.T) {
+	var (
+		fsmResponse      *fsm.Response
+		testFSMDumpLocal = testFSMDump[sif.StateSigningAwaitPartialSigns]
+	)
+
+	participantIds := make([]int, 0, len(testIdMapParticipants))
+	for participantId := range testIdMapParticipants {
+		participantIds = append(participantIds, participantId)
+	}
+	sort.Ints(participantIds)
+	culpritId := participantIds[0]
+
+	for _, participantId := range participantIds {
+		testFSMInstance, err := FromDump(testFSMDumpLocal)
+		require.NoError(t, err)
+
+		partialSign := genPartialSign(t, participantId, testSigningPayload)
+		if participantId == culpritId {
+			// a valid partial sign of another participant must not be accepted
+			partialSign = genPartialSign(t, participantIds[1], testSigningPayload)
+		}
+		fsmResponse, testFSMDumpLocal, err = testFSMInstance.Do(sif.EventSigningPartialSignReceived, requests.SigningProposalPartialSignRequest{
+			SigningId:     testSigningId,
+			ParticipantId: participantId,
+			PartialSign:   partialSign,
+			CreatedAt:     time.Now(),
+		})
+		require.NoError(t, err)
+	}
+
+	compareState(t, sif.StateSigningPartialSignsCollected, fsmResponse.State)
+
+	response, ok := fsmResponse.Data.(responses.SigningProcessParticipantResponse)
+	require.True(t, ok)
+	require.Len(t, response.Participants, len(participantIds)-1)
+	for _, participant := range response.Participants {
+		require.NotEqual(t, culpritId, participant.ParticipantId)
+	}
+
+	testFSMInstance, err := FromDump(testFSMDumpLocal)
+	require.NoError(t, err)
+	culprit, err := testFSMInstance.SigningQuorumGetParticipant(culpritId)
+	require.NoError(t, err)
+	require.NotNil(t, culprit.Error)
+	require.Equal(t, requests.ErrorCodeVerificationFailed, culprit.Error.Code)
+	require.Equal(t, testIdMapParticipants[culpritId].Username, culprit.Error.Participant)
+}
+
+func Test_SigningProposal_EventSigningPartialKeyReceived_NotEnoughValid(t *testing.T) {
+	var (
+		fsmResponse      *fsm.Response
+		testFSMDumpLocal = testFSMDump[sif.StateSigningAwaitPartialSigns]
+		validCount       int
+	)
+
+	for participantId := range testIdMapParticipants {
+		testFSMInstance, err := FromDump(testFSMDumpLocal)
+		require.NoError(t, err)
+
+		partialSign := genDataMock(keysMockLen)
+		if validCount < testSigningThreshold-1 {
+			partialSign = genPartialSign(t, participantId, testSigningPayload)
+			validCount++
+		}
+		fsmResponse, testFSMDumpLocal, err = testFSMInstance.Do(sif.EventSigningPartialSignReceived, requests.SigningProposalPartialSignRequest{
+			SigningId:     testSigningId,
+			ParticipantId: participantId,
+			PartialSign:   partialSign,
+			CreatedAt:     time.Now(),
+		})
+		require.NoError(t, err)
+	}
+
+	compareState(t, sif.StateSigningPartialSignsAwaitCancelledByError, fsmResponse.State)
+}
+
 func Test_DkgProposal_EventSigningRestart_Positive(t *testing.T) {
 	var (
 		fsmResponse      *fsm.Response
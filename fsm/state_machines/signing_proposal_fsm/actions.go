@@ -136,6 +136,7 @@ func (m *SigningProposalFSM) actionProposalResponseByParticipant(inEvent fsm.Eve
 		signingProposalParticipant.Status = internal.SigningConfirmed
 	case EventDeclineSigningConfirmation:
 		signingProposalParticipant.Status = internal.SigningDeclined
+		signingProposalParticipant.Error = request.Error
 	default:
 		err = fmt.Errorf("unsupported event for action {inEvent} = {\"%s\"}", inEvent)
 		return
@@ -235,6 +236,17 @@ func (m *SigningProposalFSM) actionPartialSignConfirmationReceived(inEvent fsm.E
 	copy(signingProposalParticipant.PartialSign, request.PartialSign)
 	signingProposalParticipant.Status = internal.SigningPartialSignsConfirmed
 
+	// An invalid partial sign is excluded from the reconstruction and its sender is recorded as the culprit,
+	// the signing goes on if there are enough valid partial signs
+	if verifyErr := m.verifyPartialSign(request.ParticipantId, request.PartialSign); verifyErr != nil {
+		signingProposalParticipant.Status = internal.SigningPartialSignInvalid
+		signingProposalParticipant.Error = requests.NewProtocolError(
+			requests.ErrorCodeVerificationFailed,
+			signingProposalParticipant.Username,
+			fmt.Sprintf("invalid partial sign: %v", verifyErr),
+		)
+	}
+
 	signingProposalParticipant.UpdatedAt = request.CreatedAt
 	m.payload.SignatureProposalPayload.UpdatedAt = request.CreatedAt
 
@@ -257,11 +269,15 @@ func (m *SigningProposalFSM) actionValidateSigningPartialSignsAwaitConfirmations
 	}
 
 	unconfirmedParticipants := m.payload.SigningQuorumCount()
+	validPartialSigns := 0
 	for _, participant := range m.payload.SigningProposalPayload.Quorum {
 		if participant.Status == internal.SigningError {
 			isContainsError = true
 		} else if participant.Status == internal.SigningPartialSignsConfirmed {
 			unconfirmedParticipants--
+			validPartialSigns++
+		} else if participant.Status == internal.SigningPartialSignInvalid {
+			unconfirmedParticipants--
 		}
 	}
 
@@ -275,10 +291,17 @@ func (m *SigningProposalFSM) actionValidateSigningPartialSignsAwaitConfirmations
 		return
 	}
 
+	if validPartialSigns < m.signingThreshold() {
+		outEvent = eventSigningPartialSignsAwaitCancelByErrorInternal
+		return
+	}
+
 	outEvent = eventSigningPartialSignsConfirmedInternal
 
 	for _, participant := range m.payload.SigningProposalPayload.Quorum {
-		participant.Status = internal.SigningProcess
+		if participant.Status == internal.SigningPartialSignsConfirmed {
+			participant.Status = internal.SigningProcess
+		}
 	}
 
 	// Response
@@ -289,6 +312,9 @@ func (m *SigningProposalFSM) actionValidateSigningPartialSignsAwaitConfirmations
 	}
 
 	for participantId, participant := range m.payload.SigningProposalPayload.Quorum {
+		if participant.Status != internal.SigningProcess {
+			continue
+		}
 		responseEntry := &responses.SigningProcessParticipantEntry{
 			ParticipantId: participantId,
 			Username:      participant.Username,
@@ -3,6 +3,15 @@ package signing_proposal_fsm
 import (
 	"crypto/rand"
 	"encoding/base64"
+	"fmt"
+	"sort"
+
+	"github.com/corestario/kyber"
+	"github.com/corestario/kyber/pairing"
+	"github.com/corestario/kyber/pairing/bls12381"
+	"github.com/corestario/kyber/share"
+	vss "github.com/corestario/kyber/share/vss/pedersen"
+	"github.com/lidofinance/dc4bc/dkg"
 )
 
 const (
@@ -18,3 +27,70 @@ func generateSigningId() (string, error) {
 
 	return base64.URLEncoding.EncodeToString(b), err
 }
+
+// verifyPartialSign checks the partial sign of the participant against the public polynomial of the DKG round.
+// Share indexes follow the order of participant ids.
+func (m *SigningProposalFSM) verifyPartialSign(participantId int, partialSign []byte) error {
+	suite := bls12381.NewBLS12381Suite(nil)
+
+	participantIds := make([]int, 0, len(m.payload.DKGProposalPayload.Quorum))
+	for id := range m.payload.DKGProposalPayload.Quorum {
+		participantIds = append(participantIds, id)
+	}
+	sort.Ints(participantIds)
+
+	shareIndex := -1
+	for index, id := range participantIds {
+		if id == participantId {
+			shareIndex = index
+		}
+	}
+	if shareIndex < 0 {
+		return fmt.Errorf("participant %d not exist in DKG quorum", participantId)
+	}
+
+	pubPoly, err := m.signingPubPoly(suite, participantIds)
+	if err != nil {
+		return err
+	}
+	return dkg.VerifyPartialSign(suite.(pairing.Suite), pubPoly, shareIndex, m.payload.SigningProposalPayload.SrcPayload, partialSign)
+}
+
+// signingPubPoly returns the public polynomial of the DKG round. The polynomial is built from commits of
+// the participants for the first partial sign and kept in the payload for the next ones.
+func (m *SigningProposalFSM) signingPubPoly(suite vss.Suite, participantIds []int) (*share.PubPoly, error) {
+	if len(m.payload.SigningProposalPayload.PubPolyCommits) > 0 {
+		commits, err := dkg.UnmarshalCommits(suite, m.payload.SigningProposalPayload.PubPolyCommits)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal public polynomial: %w", err)
+		}
+		return share.NewPubPoly(suite, nil, commits), nil
+	}
+
+	commits := make([][]kyber.Point, 0, len(participantIds))
+	for _, id := range participantIds {
+		participantCommits, err := dkg.UnmarshalCommits(suite, m.payload.DKGProposalPayload.Quorum[id].DkgCommit)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get commits of participant %d: %w", id, err)
+		}
+		commits = append(commits, participantCommits)
+	}
+	pubPoly, err := dkg.PubPolyFromCommits(suite, commits)
+	if err != nil {
+		return nil, err
+	}
+
+	_, pubPolyCommits := pubPoly.Info()
+	if m.payload.SigningProposalPayload.PubPolyCommits, err = dkg.MarshalCommits(pubPolyCommits); err != nil {
+		return nil, fmt.Errorf("failed to marshal public polynomial: %w", err)
+	}
+	return pubPoly, nil
+}
+
+// signingThreshold returns the minimal number of partial signs to reconstruct a signature
+func (m *SigningProposalFSM) signingThreshold() int {
+	for _, participant := range m.payload.SignatureProposalPayload.Quorum {
+		return participant.Threshold
+	}
+	return 0
+}
@@ -47,6 +47,6 @@ type DKGProposalMasterKeyConfirmationRequest struct {
 //			"event_dkg_master_key_confirm_canceled_by_error"
 type DKGProposalConfirmationErrorRequest struct {
 	ParticipantId int
-	Error         error
+	Error         *ProtocolError
 	CreatedAt     time.Time
 }
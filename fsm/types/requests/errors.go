@@ -0,0 +1,47 @@
+package requests
+
+import "fmt"
+
+type ErrorCode string
+
+const (
+	// ErrorCodeInternal is used when the failure is not caused by other participants
+	ErrorCodeInternal ErrorCode = "internal_error"
+	// ErrorCodeInvalidMessage is used when a message of a participant is missing, forged or malformed
+	ErrorCodeInvalidMessage ErrorCode = "invalid_message"
+	// ErrorCodeVerificationFailed is used when deals or responses of the DKG round do not pass the verification
+	ErrorCodeVerificationFailed ErrorCode = "verification_failed"
+	// ErrorCodeMasterKeyMismatch is used when participants reconstructed different master keys
+	ErrorCodeMasterKeyMismatch ErrorCode = "master_key_mismatch"
+	// ErrorCodeDeclinedByPolicy is used when the node of the participant declines a signing by its signing policy
+	ErrorCodeDeclinedByPolicy ErrorCode = "declined_by_policy"
+)
+
+// ProtocolError is a typed error sent to other participants when an operation fails.
+// Participant is the username of the participant who caused the error, if known.
+type ProtocolError struct {
+	Code        ErrorCode
+	Message     string
+	Participant string
+}
+
+func NewProtocolError(code ErrorCode, participant, message string) *ProtocolError {
+	return &ProtocolError{
+		Code:        code,
+		Message:     message,
+		Participant: participant,
+	}
+}
+
+func (e *ProtocolError) Error() string {
+	if e.Participant != "" {
+		return fmt.Sprintf("%s: %s (participant %s)", e.Code, e.Message, e.Participant)
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+// IsEmpty reports whether the error carries no information. Errors of old FSM dumps were stored
+// as empty JSON objects and decode into such errors
+func (e *ProtocolError) IsEmpty() bool {
+	return e != nil && *e == ProtocolError{}
+}
@@ -73,6 +73,10 @@ func (r *SignatureProposalConfirmationErrorRequest) Validate() error {
 		return errors.New("{Error} cannot be a nil")
 	}
 
+	if r.Error.Code == "" {
+		return errors.New("{Error.Code} cannot be empty")
+	}
+
 	if r.CreatedAt.IsZero() {
 		return errors.New("{CreatedAt} is not set")
 	}
@@ -17,7 +17,9 @@ type SigningProposalStartRequest struct {
 type SigningProposalParticipantRequest struct {
 	SigningId     string
 	ParticipantId int
-	CreatedAt     time.Time
+	// Error is set when the participant declines the signing because of a failure
+	Error     *ProtocolError
+	CreatedAt time.Time
 }
 
 // States: "state_signing_await_partial_keys"
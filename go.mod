@@ -11,6 +11,7 @@ require (
 	github.com/juju/fslock v0.0.0-20160525022230-4d5c94c67b4b
 	github.com/makiuchi-d/gozxing v0.0.0-20190830103442-eaff64b1ceb7
 	github.com/matryer/try v0.0.0-20161228173917-9ac251b645a2 // indirect
+	github.com/prometheus/client_golang v1.7.1
 	github.com/prysmaticlabs/prysm v1.0.0-alpha.29.0.20201014075528-022b6667e5d0
 	github.com/segmentio/kafka-go v0.4.2
 	github.com/skip2/go-qrcode v0.0.0-20200617195104-da1b6568686e
@@ -18,6 +19,7 @@ require (
 	github.com/spf13/viper v1.7.1
 	github.com/stretchr/testify v1.6.1
 	github.com/syndtr/goleveldb v1.0.1-0.20200815110645-5c35d600f0ca
+	github.com/tyler-smith/go-bip39 v1.0.2
 	gocv.io/x/gocv v0.24.0
 	golang.org/x/crypto v0.0.0-20200728195943-123391ffb6de
 	gopkg.in/matryer/try.v1 v1.0.0-20150601225556-312d2599e12e
@@ -7,7 +7,9 @@ package clientMocks
 import (
 	gomock "github.com/golang/mock/gomock"
 	types "github.com/lidofinance/dc4bc/client/types"
+	fsm "github.com/lidofinance/dc4bc/fsm/fsm"
 	state_machines "github.com/lidofinance/dc4bc/fsm/state_machines"
+	storage "github.com/lidofinance/dc4bc/storage"
 	reflect "reflect"
 )
 
@@ -63,6 +65,35 @@ func (mr *MockStateMockRecorder) LoadOffset() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadOffset", reflect.TypeOf((*MockState)(nil).LoadOffset))
 }
 
+// SaveEventsOffset mocks base method
+func (m *MockState) SaveEventsOffset(arg0 uint64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveEventsOffset", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveEventsOffset indicates an expected call of SaveEventsOffset
+func (mr *MockStateMockRecorder) SaveEventsOffset(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveEventsOffset", reflect.TypeOf((*MockState)(nil).SaveEventsOffset), arg0)
+}
+
+// LoadEventsOffset mocks base method
+func (m *MockState) LoadEventsOffset() (uint64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoadEventsOffset")
+	ret0, _ := ret[0].(uint64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LoadEventsOffset indicates an expected call of LoadEventsOffset
+func (mr *MockStateMockRecorder) LoadEventsOffset() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadEventsOffset", reflect.TypeOf((*MockState)(nil).LoadEventsOffset))
+}
+
 // SaveFSM mocks base method
 func (m *MockState) SaveFSM(dkgRoundID string, dump []byte) error {
 	m.ctrl.T.Helper()
@@ -122,6 +153,20 @@ func (mr *MockStateMockRecorder) PutOperation(operation interface{}) *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PutOperation", reflect.TypeOf((*MockState)(nil).PutOperation), operation)
 }
 
+// UpdateOperation mocks base method
+func (m *MockState) UpdateOperation(operation *types.Operation) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateOperation", operation)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateOperation indicates an expected call of UpdateOperation
+func (mr *MockStateMockRecorder) UpdateOperation(operation interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateOperation", reflect.TypeOf((*MockState)(nil).UpdateOperation), operation)
+}
+
 // DeleteOperation mocks base method
 func (m *MockState) DeleteOperation(operationID string) error {
 	m.ctrl.T.Helper()
@@ -209,3 +254,105 @@ func (mr *MockStateMockRecorder) GetSignatures(dkgID interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSignatures", reflect.TypeOf((*MockState)(nil).GetSignatures), dkgID)
 }
+
+// SaveMessage mocks base method
+func (m *MockState) SaveMessage(message storage.Message) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveMessage", message)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveMessage indicates an expected call of SaveMessage
+func (mr *MockStateMockRecorder) SaveMessage(message interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveMessage", reflect.TypeOf((*MockState)(nil).SaveMessage), message)
+}
+
+// GetMessages mocks base method
+func (m *MockState) GetMessages(dkgID string, event fsm.Event) ([]storage.Message, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMessages", dkgID, event)
+	ret0, _ := ret[0].([]storage.Message)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMessages indicates an expected call of GetMessages
+func (mr *MockStateMockRecorder) GetMessages(dkgID, event interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMessages", reflect.TypeOf((*MockState)(nil).GetMessages), dkgID, event)
+}
+
+// SavePubPoly mocks base method
+func (m *MockState) SavePubPoly(dkgID string, pubPoly []byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SavePubPoly", dkgID, pubPoly)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SavePubPoly indicates an expected call of SavePubPoly
+func (mr *MockStateMockRecorder) SavePubPoly(dkgID, pubPoly interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SavePubPoly", reflect.TypeOf((*MockState)(nil).SavePubPoly), dkgID, pubPoly)
+}
+
+// LoadPubPoly mocks base method
+func (m *MockState) LoadPubPoly(dkgID string) ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoadPubPoly", dkgID)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LoadPubPoly indicates an expected call of LoadPubPoly
+func (mr *MockStateMockRecorder) LoadPubPoly(dkgID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadPubPoly", reflect.TypeOf((*MockState)(nil).LoadPubPoly), dkgID)
+}
+
+// SaveDKGKey mocks base method
+func (m *MockState) SaveDKGKey(key *types.DKGKey) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveDKGKey", key)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveDKGKey indicates an expected call of SaveDKGKey
+func (mr *MockStateMockRecorder) SaveDKGKey(key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveDKGKey", reflect.TypeOf((*MockState)(nil).SaveDKGKey), key)
+}
+
+// GetDKGKey mocks base method
+func (m *MockState) GetDKGKey(dkgID string) (*types.DKGKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDKGKey", dkgID)
+	ret0, _ := ret[0].(*types.DKGKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDKGKey indicates an expected call of GetDKGKey
+func (mr *MockStateMockRecorder) GetDKGKey(dkgID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDKGKey", reflect.TypeOf((*MockState)(nil).GetDKGKey), dkgID)
+}
+
+// GetDKGKeys mocks base method
+func (m *MockState) GetDKGKeys() (map[string]*types.DKGKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDKGKeys")
+	ret0, _ := ret[0].(map[string]*types.DKGKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDKGKeys indicates an expected call of GetDKGKeys
+func (mr *MockStateMockRecorder) GetDKGKeys() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDKGKeys", reflect.TypeOf((*MockState)(nil).GetDKGKeys))
+}
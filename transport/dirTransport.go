@@ -0,0 +1,158 @@
+package transport
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	bundleFileExt   = ".bundle.json"
+	tmpFileExt      = ".tmp"
+	processedFolder = "processed"
+	rejectedFolder  = "rejected"
+	rejectReasonExt = ".reason.txt"
+
+	operationsFolder = "operations"
+	resultsFolder    = "results"
+)
+
+var _ Transport = (*DirTransport)(nil)
+
+// DirTransport is a camera-free transport which passes bundles through a pair of folders,
+// e.g. on a removable drive. Bundles are written to the outbox folder and read from the inbox folder,
+// acknowledged bundles are moved to the "processed" subfolder of the inbox and rejected bundles are moved
+// to the "rejected" subfolder along with a file with the reason.
+type DirTransport struct {
+	inboxDir  string
+	outboxDir string
+}
+
+// NewDirTransport creates a transport which reads bundles from inboxDir and writes bundles to outboxDir
+func NewDirTransport(inboxDir, outboxDir string) (*DirTransport, error) {
+	for _, dir := range []string{
+		inboxDir,
+		outboxDir,
+		filepath.Join(inboxDir, processedFolder),
+		filepath.Join(inboxDir, rejectedFolder),
+	} {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return nil, fmt.Errorf("failed to create folder %s: %w", dir, err)
+		}
+	}
+	return &DirTransport{
+		inboxDir:  inboxDir,
+		outboxDir: outboxDir,
+	}, nil
+}
+
+// NewHotNodeDirTransport creates a transport for the hot node side: operations are written
+// to <dir>/operations and results are read from <dir>/results
+func NewHotNodeDirTransport(dir string) (*DirTransport, error) {
+	return NewDirTransport(filepath.Join(dir, resultsFolder), filepath.Join(dir, operationsFolder))
+}
+
+// NewAirgappedDirTransport creates a transport for the airgapped machine side: operations are read
+// from <dir>/operations and results are written to <dir>/results
+func NewAirgappedDirTransport(dir string) (*DirTransport, error) {
+	return NewDirTransport(filepath.Join(dir, operationsFolder), filepath.Join(dir, resultsFolder))
+}
+
+func bundleFileName(bundle *Bundle) string {
+	return fmt.Sprintf("%s_%s%s", bundle.Kind, bundle.ID, bundleFileExt)
+}
+
+// Send writes the bundle to the outbox folder. The file is written to a temporary path first
+// and then renamed, so the other side never sees a partially written bundle.
+func (t *DirTransport) Send(bundle *Bundle) error {
+	if err := validateBundleID(bundle.ID); err != nil {
+		return err
+	}
+	data, err := json.Marshal(bundle)
+	if err != nil {
+		return fmt.Errorf("failed to marshal bundle: %w", err)
+	}
+
+	path := filepath.Join(t.outboxDir, bundleFileName(bundle))
+	tmpPath := path + tmpFileExt
+	if err = ioutil.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	if err = os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to rename bundle file: %w", err)
+	}
+	return nil
+}
+
+// Receive reads all bundles from the inbox folder. Files which are not valid bundles or have a broken
+// checksum are moved to the "rejected" subfolder, since they cannot be trusted.
+func (t *DirTransport) Receive() ([]*Bundle, error) {
+	files, err := ioutil.ReadDir(t.inboxDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read inbox folder: %w", err)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].ModTime().Before(files[j].ModTime())
+	})
+
+	var bundles []*Bundle
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), bundleFileExt) {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(t.inboxDir, f.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read bundle %s: %w", f.Name(), err)
+		}
+		var bundle Bundle
+		if err = json.Unmarshal(data, &bundle); err != nil {
+			err = fmt.Errorf("failed to unmarshal bundle: %w", err)
+		} else if err = validateBundleID(bundle.ID); err == nil {
+			err = bundle.VerifyChecksum()
+		}
+		if err != nil {
+			if err = t.reject(f.Name(), err); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		bundle.fileName = f.Name()
+		bundles = append(bundles, &bundle)
+	}
+	return bundles, nil
+}
+
+// Ack moves the bundle to the "processed" subfolder of the inbox, the bundle must be returned by Receive
+func (t *DirTransport) Ack(bundle *Bundle) error {
+	if bundle.fileName == "" {
+		return fmt.Errorf("bundle %s is not received from the inbox", bundle.ID)
+	}
+	name := bundle.fileName
+	if err := os.Rename(filepath.Join(t.inboxDir, name), filepath.Join(t.inboxDir, processedFolder, name)); err != nil {
+		return fmt.Errorf("failed to move bundle to processed folder: %w", err)
+	}
+	return nil
+}
+
+// Reject moves the bundle to the "rejected" subfolder of the inbox, the bundle must be returned by Receive
+func (t *DirTransport) Reject(bundle *Bundle, reason error) error {
+	if bundle.fileName == "" {
+		return fmt.Errorf("bundle %s is not received from the inbox", bundle.ID)
+	}
+	return t.reject(bundle.fileName, reason)
+}
+
+func (t *DirTransport) reject(name string, reason error) error {
+	rejectedPath := filepath.Join(t.inboxDir, rejectedFolder, name)
+	if err := ioutil.WriteFile(rejectedPath+rejectReasonExt, []byte(reason.Error()+"\n"), 0600); err != nil {
+		return fmt.Errorf("failed to write reject reason: %w", err)
+	}
+	if err := os.Rename(filepath.Join(t.inboxDir, name), rejectedPath); err != nil {
+		return fmt.Errorf("failed to move bundle to rejected folder: %w", err)
+	}
+	return nil
+}
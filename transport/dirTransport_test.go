@@ -0,0 +1,157 @@
+package transport
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// requireBundle checks the received bundle, the name of its file is known only to the transport
+func requireBundle(t *testing.T, expected, got *Bundle) {
+	received := *got
+	received.fileName = ""
+	require.Equal(t, *expected, received)
+}
+
+func TestDirTransport_SendReceiveAck(t *testing.T) {
+	req := require.New(t)
+
+	dir, err := ioutil.TempDir("", "dc4bc_test_dir_transport")
+	req.NoError(err)
+	defer os.RemoveAll(dir)
+
+	hot, err := NewHotNodeDirTransport(dir)
+	req.NoError(err)
+	airgapped, err := NewAirgappedDirTransport(dir)
+	req.NoError(err)
+
+	operation := NewBundle(OperationBundle, "op1", []byte(`{"id":"op1"}`))
+	req.NoError(hot.Send(operation))
+
+	bundles, err := airgapped.Receive()
+	req.NoError(err)
+	req.Len(bundles, 1)
+	requireBundle(t, operation, bundles[0])
+	received := bundles[0]
+
+	// the hot node must not see its own operations
+	bundles, err = hot.Receive()
+	req.NoError(err)
+	req.Len(bundles, 0)
+
+	req.Error(airgapped.Ack(operation), "only received bundles can be acked")
+	req.NoError(airgapped.Ack(received))
+	bundles, err = airgapped.Receive()
+	req.NoError(err)
+	req.Len(bundles, 0)
+	_, err = os.Stat(filepath.Join(dir, operationsFolder, processedFolder, bundleFileName(operation)))
+	req.NoError(err)
+
+	result := NewBundle(ResultBundle, "op1", []byte(`{"id":"op1","result_msgs":[]}`))
+	req.NoError(airgapped.Send(result))
+	bundles, err = hot.Receive()
+	req.NoError(err)
+	req.Len(bundles, 1)
+	requireBundle(t, result, bundles[0])
+}
+
+func TestDirTransport_Reject(t *testing.T) {
+	req := require.New(t)
+
+	dir, err := ioutil.TempDir("", "dc4bc_test_dir_transport")
+	req.NoError(err)
+	defer os.RemoveAll(dir)
+
+	hot, err := NewHotNodeDirTransport(dir)
+	req.NoError(err)
+	airgapped, err := NewAirgappedDirTransport(dir)
+	req.NoError(err)
+
+	rejected := NewBundle(OperationBundle, "op1", []byte(`{"id":"op1"}`))
+	req.NoError(hot.Send(rejected))
+	corrupted := NewBundle(OperationBundle, "op2", []byte(`{"id":"op2"}`))
+	corrupted.Checksum[0] ^= 0xff
+	req.NoError(hot.Send(corrupted))
+	req.NoError(ioutil.WriteFile(filepath.Join(dir, operationsFolder, "garbage"+bundleFileExt), []byte("{"), 0600))
+	valid := NewBundle(OperationBundle, "op3", []byte(`{"id":"op3"}`))
+	req.NoError(hot.Send(valid))
+
+	// broken bundles are moved away and do not block valid ones
+	bundles, err := airgapped.Receive()
+	req.NoError(err)
+	req.Len(bundles, 2)
+	requireBundle(t, rejected, bundles[0])
+	requireBundle(t, valid, bundles[1])
+
+	req.NoError(airgapped.Reject(bundles[0], errors.New("invalid operation")))
+	bundles, err = airgapped.Receive()
+	req.NoError(err)
+	req.Len(bundles, 1)
+	requireBundle(t, valid, bundles[0])
+
+	rejectedDir := filepath.Join(dir, operationsFolder, rejectedFolder)
+	for _, name := range []string{bundleFileName(rejected), bundleFileName(corrupted), "garbage" + bundleFileExt} {
+		_, err = os.Stat(filepath.Join(rejectedDir, name))
+		req.NoError(err)
+	}
+	reason, err := ioutil.ReadFile(filepath.Join(rejectedDir, bundleFileName(rejected)+rejectReasonExt))
+	req.NoError(err)
+	req.Equal("invalid operation\n", string(reason))
+}
+
+func TestDirTransport_UntrustedFileNames(t *testing.T) {
+	req := require.New(t)
+
+	dir, err := ioutil.TempDir("", "dc4bc_test_dir_transport")
+	req.NoError(err)
+	defer os.RemoveAll(dir)
+
+	airgapped, err := NewAirgappedDirTransport(dir)
+	req.NoError(err)
+	inboxDir := filepath.Join(dir, operationsFolder)
+
+	// a bundle with a path in its ID is rejected by its file name and nothing is written outside the inbox
+	traversal := NewBundle(OperationBundle, "../../x", []byte(`{"id":"x"}`))
+	traversalBz, err := json.Marshal(traversal)
+	req.NoError(err)
+	req.NoError(ioutil.WriteFile(filepath.Join(inboxDir, "traversal"+bundleFileExt), traversalBz, 0600))
+	req.Error(airgapped.Send(traversal))
+
+	// a bundle whose file name does not match its contents is acked by its file name
+	renamed := NewBundle(OperationBundle, "op1", []byte(`{"id":"op1"}`))
+	renamedBz, err := json.Marshal(renamed)
+	req.NoError(err)
+	req.NoError(ioutil.WriteFile(filepath.Join(inboxDir, "renamed"+bundleFileExt), renamedBz, 0600))
+
+	bundles, err := airgapped.Receive()
+	req.NoError(err)
+	req.Len(bundles, 1)
+	requireBundle(t, renamed, bundles[0])
+	req.NoError(airgapped.Ack(bundles[0]))
+	bundles, err = airgapped.Receive()
+	req.NoError(err)
+	req.Len(bundles, 0)
+
+	_, err = os.Stat(filepath.Join(inboxDir, rejectedFolder, "traversal"+bundleFileExt))
+	req.NoError(err)
+	_, err = os.Stat(filepath.Join(inboxDir, processedFolder, "renamed"+bundleFileExt))
+	req.NoError(err)
+	files, err := ioutil.ReadDir(dir)
+	req.NoError(err)
+	req.Len(files, 2, "only the inbox and the outbox are in the transport folder")
+}
+
+func TestBundle_VerifyChecksum(t *testing.T) {
+	req := require.New(t)
+
+	bundle := NewBundle(OperationBundle, "op1", []byte("payload"))
+	req.NoError(bundle.VerifyChecksum())
+
+	bundle.Payload = []byte("corrupted")
+	req.Error(bundle.VerifyChecksum())
+}
@@ -0,0 +1,91 @@
+package transport
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+var _ Transport = (*FileTransport)(nil)
+
+// FileTransport passes a single bundle through a pair of files, e.g. one operation copied by hand or shown
+// to the other side as a QR code by external tools. Receive reads the bundle from the inbox file and Send
+// writes the bundle to the outbox file.
+type FileTransport struct {
+	inboxFile  string
+	outboxFile string
+}
+
+// NewFileTransport creates a transport which reads a bundle from inboxFile and writes a bundle to outboxFile
+func NewFileTransport(inboxFile, outboxFile string) *FileTransport {
+	return &FileTransport{
+		inboxFile:  inboxFile,
+		outboxFile: outboxFile,
+	}
+}
+
+// Send writes the bundle to the outbox file, the file is written to a temporary path first and then renamed
+func (t *FileTransport) Send(bundle *Bundle) error {
+	if t.outboxFile == "" {
+		return errors.New("outbox file is not set")
+	}
+	if err := validateBundleID(bundle.ID); err != nil {
+		return err
+	}
+	data, err := json.Marshal(bundle)
+	if err != nil {
+		return fmt.Errorf("failed to marshal bundle: %w", err)
+	}
+
+	tmpPath := t.outboxFile + tmpFileExt
+	if err = ioutil.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	if err = os.Rename(tmpPath, t.outboxFile); err != nil {
+		return fmt.Errorf("failed to rename bundle file: %w", err)
+	}
+	return nil
+}
+
+// Receive reads the bundle from the inbox file. There is nothing behind a broken bundle, so it is not rejected
+// and the error is returned instead.
+func (t *FileTransport) Receive() ([]*Bundle, error) {
+	if t.inboxFile == "" {
+		return nil, errors.New("inbox file is not set")
+	}
+	data, err := ioutil.ReadFile(t.inboxFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bundle: %w", err)
+	}
+	var bundle Bundle
+	if err = json.Unmarshal(data, &bundle); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal bundle: %w", err)
+	}
+	if err = validateBundleID(bundle.ID); err != nil {
+		return nil, err
+	}
+	if err = bundle.VerifyChecksum(); err != nil {
+		return nil, err
+	}
+	return []*Bundle{&bundle}, nil
+}
+
+// Ack does nothing, the inbox file is read again only if it is passed again
+func (t *FileTransport) Ack(*Bundle) error {
+	return nil
+}
+
+// Reject writes the reason next to the inbox file
+func (t *FileTransport) Reject(_ *Bundle, reason error) error {
+	if err := ioutil.WriteFile(t.RejectReasonFile(), []byte(reason.Error()+"\n"), 0600); err != nil {
+		return fmt.Errorf("failed to write reject reason: %w", err)
+	}
+	return nil
+}
+
+// RejectReasonFile returns the path of the file with the reason of the rejected bundle
+func (t *FileTransport) RejectReasonFile() string {
+	return t.inboxFile + rejectReasonExt
+}
@@ -0,0 +1,53 @@
+package transport
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileTransport(t *testing.T) {
+	req := require.New(t)
+
+	dir, err := ioutil.TempDir("", "dc4bc_test_file_transport")
+	req.NoError(err)
+	defer os.RemoveAll(dir)
+
+	operationFile, resultFile := filepath.Join(dir, "op1.json"), filepath.Join(dir, "op1_res.json")
+	hot := NewFileTransport(resultFile, operationFile)
+	airgapped := NewFileTransport(operationFile, resultFile)
+
+	operation := NewBundle(OperationBundle, "op1", []byte(`{"id":"op1"}`))
+	req.NoError(hot.Send(operation))
+	bundles, err := airgapped.Receive()
+	req.NoError(err)
+	req.Len(bundles, 1)
+	requireBundle(t, operation, bundles[0])
+
+	result := NewBundle(ResultBundle, "op1", []byte(`{"id":"op1","result_msgs":[]}`))
+	req.NoError(airgapped.Send(result))
+	bundles, err = hot.Receive()
+	req.NoError(err)
+	req.Len(bundles, 1)
+	requireBundle(t, result, bundles[0])
+
+	req.NoError(airgapped.Reject(operation, errors.New("invalid operation")))
+	reason, err := ioutil.ReadFile(operationFile + rejectReasonExt)
+	req.NoError(err)
+	req.Equal("invalid operation\n", string(reason))
+
+	// a broken bundle is an error, since there is nothing behind it
+	operation.Checksum[0] ^= 0xff
+	corruptedBz, err := json.Marshal(operation)
+	req.NoError(err)
+	req.NoError(ioutil.WriteFile(operationFile, corruptedBz, 0600))
+	_, err = airgapped.Receive()
+	req.Error(err)
+
+	req.Error(hot.Send(NewBundle(OperationBundle, "../op1", nil)))
+}
@@ -0,0 +1,83 @@
+package transport
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+)
+
+const (
+	bundleVersion = 1
+)
+
+type BundleKind string
+
+const (
+	// OperationBundle carries an operation from the hot node to the airgapped machine
+	OperationBundle BundleKind = "operation"
+	// ResultBundle carries a processed operation from the airgapped machine back to the hot node
+	ResultBundle BundleKind = "result"
+)
+
+// Bundle is a self-contained unit of data which is passed between the hot node and the airgapped machine.
+// Payload is a JSON-encoded client operation and Checksum is a SHA-256 of the payload. Bundles are not signed
+// themselves: operations are signed with the hot node key and results are signed with the identity key of
+// the airgapped machine, and these signatures are checked by the receiving side.
+type Bundle struct {
+	Version  int        `json:"version"`
+	Kind     BundleKind `json:"kind"`
+	ID       string     `json:"id"`
+	Payload  []byte     `json:"payload"`
+	Checksum []byte     `json:"checksum"`
+
+	// fileName is the name of the file DirTransport read the bundle from, the bundle contents are untrusted
+	// and are never used to build file paths
+	fileName string
+}
+
+// NewBundle creates a bundle and calculates a checksum of the payload
+func NewBundle(kind BundleKind, id string, payload []byte) *Bundle {
+	checksum := sha256.Sum256(payload)
+	return &Bundle{
+		Version:  bundleVersion,
+		Kind:     kind,
+		ID:       id,
+		Payload:  payload,
+		Checksum: checksum[:],
+	}
+}
+
+// validateBundleID checks that the bundle ID can be a part of a file name
+func validateBundleID(id string) error {
+	if strings.ContainsAny(id, `/\`) || strings.Contains(id, "..") {
+		return fmt.Errorf("invalid bundle ID: %s", id)
+	}
+	return nil
+}
+
+// VerifyChecksum checks that the payload was not corrupted on its way
+func (b *Bundle) VerifyChecksum() error {
+	if b.Version != bundleVersion {
+		return fmt.Errorf("unsupported bundle version: %d", b.Version)
+	}
+	checksum := sha256.Sum256(b.Payload)
+	if !bytes.Equal(checksum[:], b.Checksum) {
+		return fmt.Errorf("checksum mismatch for bundle %s", b.ID)
+	}
+	return nil
+}
+
+// Transport moves bundles between the hot node and the airgapped machine.
+// Each side sends bundles to the other side and receives bundles sent to it.
+type Transport interface {
+	// Send delivers a bundle to the other side
+	Send(bundle *Bundle) error
+	// Receive returns all pending bundles sent to us
+	Receive() ([]*Bundle, error)
+	// Ack marks a received bundle as processed, so it will not be returned by Receive again
+	Ack(bundle *Bundle) error
+	// Reject marks a received bundle which can not be processed, so it will not be returned by Receive again
+	// and does not block the bundles behind it
+	Reject(bundle *Bundle, reason error) error
+}